@@ -0,0 +1,44 @@
+package nojs
+
+import (
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// ActionField is the form field carrying the submit button used
+const ActionField = "_action"
+
+// Action is one submit button of a multi-action form
+type Action struct {
+	Value string // Returned by ctx.SubmitAction
+	Label string
+	Class string
+	// NoValidate skips browser validation, e.g. for "delete" or "cancel"
+	NoValidate bool
+	// FormAction submits to a different URL than the form's action
+	FormAction string
+}
+
+// ActionButtons creates a row of submit buttons for a multi-action form.
+// The first action is the one used when the form is submitted with Enter.
+func ActionButtons(actions ...Action) g.Node {
+	buttons := []g.Node{h.Class("form-actions")}
+	for _, action := range actions {
+		buttons = append(buttons, h.Button(
+			h.Type("submit"),
+			h.Name(ActionField),
+			h.Value(action.Value),
+			g.If(action.Class != "", h.Class(action.Class)),
+			g.If(action.NoValidate, g.Attr("formnovalidate")),
+			g.If(action.FormAction != "", g.Attr("formaction", action.FormAction)),
+			g.Text(action.Label),
+		))
+	}
+	return h.Div(buttons...)
+}
+
+// SubmitAction returns the value of the ActionButtons button used to
+// submit the form, or "" when the form was submitted another way
+func (c *Context) SubmitAction() string {
+	return c.Form(ActionField)
+}
@@ -0,0 +1,183 @@
+package nojs
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// Address is a postal address
+type Address struct {
+	Line1      string
+	Line2      string
+	City       string
+	Region     string
+	PostalCode string
+	Country    string // ISO 3166-1 alpha-2 code
+}
+
+// AddressFormat describes country specific address fields
+type AddressFormat struct {
+	Name        string
+	RegionLabel string // Empty when the country has no region field
+	Regions     []Option
+	PostalLabel string // Empty when the country has no postal codes
+	// PostalPattern validates postal codes, matched case-insensitively
+	PostalPattern string
+}
+
+// AddressFormats holds the known country formats, keyed by country code.
+// Applications may add or override entries at startup.
+var AddressFormats = map[string]AddressFormat{
+	"US": {Name: "United States", RegionLabel: "State", PostalLabel: "ZIP code", PostalPattern: `^\d{5}(-\d{4})?$`,
+		Regions: regionOptions("AL", "AK", "AZ", "AR", "CA", "CO", "CT", "DE", "DC", "FL", "GA", "HI", "ID", "IL", "IN", "IA", "KS", "KY", "LA", "ME", "MD", "MA", "MI", "MN", "MS", "MO", "MT", "NE", "NV", "NH", "NJ", "NM", "NY", "NC", "ND", "OH", "OK", "OR", "PA", "RI", "SC", "SD", "TN", "TX", "UT", "VT", "VA", "WA", "WV", "WI", "WY")},
+	"CA": {Name: "Canada", RegionLabel: "Province", PostalLabel: "Postal code", PostalPattern: `^[A-Z]\d[A-Z] ?\d[A-Z]\d$`,
+		Regions: regionOptions("AB", "BC", "MB", "NB", "NL", "NS", "NT", "NU", "ON", "PE", "QC", "SK", "YT")},
+	"GB": {Name: "United Kingdom", RegionLabel: "County", PostalLabel: "Postcode", PostalPattern: `^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`},
+	"IE": {Name: "Ireland", RegionLabel: "County", PostalLabel: "Eircode", PostalPattern: `^[A-Z]\d[\dW] ?[A-Z\d]{4}$`},
+	"DE": {Name: "Germany", PostalLabel: "Postleitzahl", PostalPattern: `^\d{5}$`},
+	"FR": {Name: "France", PostalLabel: "Code postal", PostalPattern: `^\d{5}$`},
+	"ES": {Name: "Spain", RegionLabel: "Province", PostalLabel: "Código postal", PostalPattern: `^\d{5}$`},
+	"IT": {Name: "Italy", RegionLabel: "Province", PostalLabel: "CAP", PostalPattern: `^\d{5}$`},
+	"NL": {Name: "Netherlands", PostalLabel: "Postcode", PostalPattern: `^\d{4} ?[A-Z]{2}$`},
+	"AU": {Name: "Australia", RegionLabel: "State", PostalLabel: "Postcode", PostalPattern: `^\d{4}$`,
+		Regions: regionOptions("ACT", "NSW", "NT", "QLD", "SA", "TAS", "VIC", "WA")},
+	"JP": {Name: "Japan", RegionLabel: "Prefecture", PostalLabel: "Postal code", PostalPattern: `^\d{3}-?\d{4}$`},
+	"BR": {Name: "Brazil", RegionLabel: "State", PostalLabel: "CEP", PostalPattern: `^\d{5}-?\d{3}$`},
+	"MX": {Name: "Mexico", RegionLabel: "State", PostalLabel: "Código postal", PostalPattern: `^\d{5}$`},
+	"IN": {Name: "India", RegionLabel: "State", PostalLabel: "PIN code", PostalPattern: `^\d{6}$`},
+	"HK": {Name: "Hong Kong", RegionLabel: "District"},
+}
+
+func regionOptions(codes ...string) []Option {
+	return Map(codes, func(code string) Option { return Option{Value: code, Label: code} })
+}
+
+// addressFormat returns the format for a country, with generic labels for
+// unknown countries
+func addressFormat(country string) AddressFormat {
+	if f, ok := AddressFormats[strings.ToUpper(country)]; ok {
+		return f
+	}
+	return AddressFormat{Name: country, RegionLabel: "Region", PostalLabel: "Postal code"}
+}
+
+// AddressConfig configures an AddressFieldset
+type AddressConfig struct {
+	Prefix string // Field name prefix, e.g. "shipping_"
+	Legend string
+	// RefreshAction is the GET URL re-rendering the form when the country
+	// changes, usually the current page
+	RefreshAction string
+	Errors        map[string]string // Keyed by field name without prefix
+}
+
+// AddressFieldset creates address fields whose region and postal code
+// labels follow the selected country. Changing the country and pressing
+// "Update" re-renders the form through a GET request.
+func AddressFieldset(addr Address, config AddressConfig) g.Node {
+	format := addressFormat(addr.Country)
+	p := config.Prefix
+
+	codes := make([]string, 0, len(AddressFormats))
+	for code := range AddressFormats {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return AddressFormats[codes[i]].Name < AddressFormats[codes[j]].Name })
+	countries := Map(codes, func(code string) Option { return Option{Value: code, Label: AddressFormats[code].Name} })
+
+	field := func(label, name, value string, attrs ...g.Node) g.Node {
+		return h.Div(
+			Input(label, p+name, "text", value, attrs...),
+			FieldError(config.Errors[name]),
+		)
+	}
+
+	var region g.Node
+	if format.RegionLabel != "" {
+		if len(format.Regions) > 0 {
+			region = h.Div(
+				Select(format.RegionLabel, p+"region", append([]Option{{Value: "", Label: "—"}}, format.Regions...), addr.Region),
+				FieldError(config.Errors["region"]),
+			)
+		} else {
+			region = field(format.RegionLabel, "region", addr.Region)
+		}
+	}
+
+	var postal g.Node
+	if format.PostalLabel != "" {
+		postal = field(format.PostalLabel, "postal_code", addr.PostalCode, h.AutoComplete("postal-code"))
+	}
+
+	return h.FieldSet(h.Class("address"),
+		g.If(config.Legend != "", h.Legend(g.Text(config.Legend))),
+		h.Div(h.Class("address-country"),
+			Select("Country", p+"country", countries, addr.Country, h.AutoComplete("country")),
+			g.If(config.RefreshAction != "", h.Button(
+				h.Type("submit"),
+				g.Attr("formaction", config.RefreshAction),
+				g.Attr("formmethod", "get"),
+				g.Attr("formnovalidate"),
+				h.Class("button-small"),
+				g.Text("Update"),
+			)),
+			FieldError(config.Errors["country"]),
+		),
+		field("Address", "line1", addr.Line1, h.AutoComplete("address-line1"), h.Required()),
+		field("Address line 2", "line2", addr.Line2, h.AutoComplete("address-line2")),
+		field("City", "city", addr.City, h.AutoComplete("address-level2"), h.Required()),
+		region,
+		postal,
+	)
+}
+
+// FormAddress reads an address submitted by an AddressFieldset, either
+// posted or re-rendered through the GET refresh
+func (c *Context) FormAddress(prefix string) Address {
+	return Address{
+		Line1:      strings.TrimSpace(c.Form(prefix + "line1")),
+		Line2:      strings.TrimSpace(c.Form(prefix + "line2")),
+		City:       strings.TrimSpace(c.Form(prefix + "city")),
+		Region:     strings.TrimSpace(c.Form(prefix + "region")),
+		PostalCode: strings.ToUpper(strings.TrimSpace(c.Form(prefix + "postal_code"))),
+		Country:    strings.ToUpper(strings.TrimSpace(c.Form(prefix + "country"))),
+	}
+}
+
+// ValidateAddress checks an address against its country format and
+// returns error messages keyed by field name
+func ValidateAddress(addr Address) map[string]string {
+	errs := map[string]string{}
+	format := addressFormat(addr.Country)
+
+	if addr.Country == "" {
+		errs["country"] = "Country is required"
+	}
+	if addr.Line1 == "" {
+		errs["line1"] = "Address is required"
+	}
+	if addr.City == "" {
+		errs["city"] = "City is required"
+	}
+	if len(format.Regions) > 0 {
+		valid := false
+		for _, r := range format.Regions {
+			valid = valid || r.Value == addr.Region
+		}
+		if !valid {
+			errs["region"] = format.RegionLabel + " is required"
+		}
+	}
+	if format.PostalPattern != "" {
+		if addr.PostalCode == "" {
+			errs["postal_code"] = format.PostalLabel + " is required"
+		} else if !regexp.MustCompile("(?i)" + format.PostalPattern).MatchString(addr.PostalCode) {
+			errs["postal_code"] = format.PostalLabel + " is not valid"
+		}
+	}
+	return errs
+}
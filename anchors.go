@@ -0,0 +1,51 @@
+package nojs
+
+import (
+	"fmt"
+	"net/url"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// AnchorField is the form field naming the element to scroll back to
+// after a form submission
+const AnchorField = "_anchor"
+
+// AnchorInput adds a hidden field to a form so RedirectAnchor returns the
+// browser to the element with the given id, e.g. the item just edited
+func AnchorInput(id string) g.Node {
+	return h.Input(h.Type("hidden"), h.Name(AnchorField), h.Value(id))
+}
+
+// WithAnchor returns rawURL with its fragment set to anchor, replacing
+// any existing fragment. An empty anchor leaves the URL unchanged.
+func WithAnchor(rawURL, anchor string) string {
+	if anchor == "" {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Fragment = anchor
+	return u.String()
+}
+
+// RedirectAnchor redirects to url scrolled to anchor. With an empty anchor
+// the form's AnchorField is used, so the page reloads where the user was.
+func (c *Context) RedirectAnchor(status int, url, anchor string) error {
+	if anchor == "" {
+		anchor = c.Form(AnchorField)
+	}
+	return c.Redirect(status, WithAnchor(url, anchor))
+}
+
+// AutoRefreshAnchor is AutoRefresh reloading url scrolled to anchor, e.g.
+// "msg-123" for the newest chat message, instead of the top of the page
+func AutoRefreshAnchor(seconds int, url, anchor string) g.Node {
+	return h.Meta(
+		g.Attr("http-equiv", "refresh"),
+		g.Attr("content", fmt.Sprintf("%d; url=%s", seconds, WithAnchor(url, anchor))),
+	)
+}
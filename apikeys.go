@@ -0,0 +1,341 @@
+package nojs
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// KeyAPIKey stores the *APIKey of an authenticated API request
+const KeyAPIKey = "nojs.api_key"
+
+// apiKeyPrefix starts every issued key, so leaked keys are easy to find
+// with secret scanners
+const apiKeyPrefix = "nojs_"
+
+// ErrAPIKeyNotFound is returned by APIKeyStore.Get for unknown keys
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// APIKey is an issued API key. Only a hash of the secret is stored; the
+// full key is shown once, when it is created.
+type APIKey struct {
+	ID     string // Public part of the key
+	Hash   string // SHA-256 of the secret part
+	UserID string
+	Name   string
+	// RateLimit is the number of requests allowed per window, 0 uses
+	// the middleware default
+	RateLimit int
+	Created   time.Time
+	LastUsed  time.Time
+	Requests  int64
+}
+
+// APIKeyStore persists API keys
+type APIKeyStore interface {
+	Create(key *APIKey) error
+	Get(id string) (*APIKey, error)
+	List(userID string) ([]*APIKey, error)
+	Delete(id string) error
+	// RecordUse increments the key's request counter
+	RecordUse(id string, at time.Time) error
+}
+
+// MemoryAPIKeyStore keeps API keys in memory
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]*APIKey
+}
+
+// NewMemoryAPIKeyStore creates an empty in-memory key store
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{keys: make(map[string]*APIKey)}
+}
+
+// Create stores a new key
+func (m *MemoryAPIKeyStore) Create(key *APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *key
+	m.keys[key.ID] = &stored
+	return nil
+}
+
+// Get returns a copy of the key with the given ID
+func (m *MemoryAPIKeyStore) Get(id string) (*APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key, ok := m.keys[id]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	copied := *key
+	return &copied, nil
+}
+
+// List returns the keys of a user, newest first
+func (m *MemoryAPIKeyStore) List(userID string) ([]*APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var keys []*APIKey
+	for _, key := range m.keys {
+		if key.UserID == userID {
+			copied := *key
+			keys = append(keys, &copied)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Created.After(keys[j].Created) })
+	return keys, nil
+}
+
+// Delete removes a key
+func (m *MemoryAPIKeyStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keys, id)
+	return nil
+}
+
+// RecordUse counts a request made with a key
+func (m *MemoryAPIKeyStore) RecordUse(id string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if key, ok := m.keys[id]; ok {
+		key.Requests++
+		key.LastUsed = at
+	}
+	return nil
+}
+
+// IssueAPIKey creates a key for userID and returns the full key to show
+// the user once
+func IssueAPIKey(store APIKeyStore, userID, name string) (string, *APIKey, error) {
+	id := make([]byte, 8)
+	secret := make([]byte, 24)
+	rand.Read(id)
+	rand.Read(secret)
+
+	key := &APIKey{
+		ID:      hex.EncodeToString(id),
+		Hash:    hashAPISecret(hex.EncodeToString(secret)),
+		UserID:  userID,
+		Name:    name,
+		Created: time.Now(),
+	}
+	if err := store.Create(key); err != nil {
+		return "", nil, err
+	}
+	return apiKeyPrefix + key.ID + "_" + hex.EncodeToString(secret), key, nil
+}
+
+func hashAPISecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyConfig configures the APIKeys middleware
+type APIKeyConfig struct {
+	// RateLimit is the default number of requests per key per Window
+	RateLimit int
+	Window    time.Duration
+	// Optional lets requests without a key through unauthenticated;
+	// invalid keys are still rejected
+	Optional bool
+}
+
+// DefaultAPIKeyConfig allows 60 requests per minute per key
+func DefaultAPIKeyConfig() APIKeyConfig {
+	return APIKeyConfig{RateLimit: 60, Window: time.Minute}
+}
+
+// APIKeys authenticates requests by API key, sent as
+// "Authorization: Bearer <key>" or in an X-API-Key header, and limits
+// each key to its rate. The key's user is available from ctx.UserID and
+// the key from ctx.APIKey. Responses carry X-RateLimit-* headers.
+func APIKeys(store APIKeyStore, config ...APIKeyConfig) Middleware {
+	cfg := DefaultAPIKeyConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	type window struct {
+		start time.Time
+		count int
+	}
+	var mu sync.Mutex
+	windows := make(map[string]*window)
+
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			token := ctx.Request.Header.Get("X-API-Key")
+			if auth := ctx.Request.Header.Get("Authorization"); token == "" && len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+				token = strings.TrimSpace(auth[7:])
+			}
+			if token == "" {
+				if cfg.Optional {
+					return next(ctx)
+				}
+				ctx.ResponseWriter.Header().Set("WWW-Authenticate", "Bearer")
+				return NewHTTPError(http.StatusUnauthorized, "API key required")
+			}
+
+			key, ok := lookupAPIKey(store, token)
+			if !ok {
+				ctx.ResponseWriter.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				return NewHTTPError(http.StatusUnauthorized, "Invalid API key")
+			}
+
+			limit := key.RateLimit
+			if limit == 0 {
+				limit = cfg.RateLimit
+			}
+			now := time.Now()
+			mu.Lock()
+			w := windows[key.ID]
+			if w == nil || now.Sub(w.start) >= cfg.Window {
+				w = &window{start: now}
+				windows[key.ID] = w
+				if len(windows) > 10000 {
+					for id, old := range windows {
+						if now.Sub(old.start) >= cfg.Window {
+							delete(windows, id)
+						}
+					}
+				}
+			}
+			w.count++
+			count, reset := w.count, w.start.Add(cfg.Window)
+			mu.Unlock()
+
+			header := ctx.ResponseWriter.Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(max(limit-count, 0)))
+			header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			if limit > 0 && count > limit {
+				header.Set("Retry-After", strconv.Itoa(int(time.Until(reset).Seconds())+1))
+				return NewHTTPError(http.StatusTooManyRequests, "Rate limit exceeded")
+			}
+
+			store.RecordUse(key.ID, now)
+			ctx.Set(KeyAPIKey, key)
+			ctx.Set(KeyUser, key.UserID)
+			return next(ctx)
+		}
+	}
+}
+
+// lookupAPIKey validates a full key against the store
+func lookupAPIKey(store APIKeyStore, token string) (*APIKey, bool) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(token, apiKeyPrefix), "_")
+	if !ok {
+		return nil, false
+	}
+	key, err := store.Get(id)
+	if err != nil {
+		return nil, false
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPISecret(secret)), []byte(key.Hash)) != 1 {
+		return nil, false
+	}
+	return key, true
+}
+
+// APIKey returns the key that authenticated the request, or nil
+func (c *Context) APIKey() *APIKey {
+	key, _ := ValueOf[*APIKey](c, KeyAPIKey)
+	return key
+}
+
+// AccountAPIKeys returns a handler for an account page listing the
+// user's API keys with forms to create and revoke them. A new key is
+// shown once, on the page answering the create form. It requires a
+// logged in user (see ctx.UserID).
+func AccountAPIKeys(store APIKeyStore, css ...string) Handler {
+	return func(ctx *Context) error {
+		userID := ctx.UserID()
+		if userID == "" {
+			return NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		}
+
+		var created g.Node
+		if ctx.Method() == http.MethodPost {
+			if id := ctx.Form("revoke"); id != "" {
+				if key, err := store.Get(id); err == nil && key.UserID == userID {
+					if err := store.Delete(id); err != nil {
+						return err
+					}
+					ctx.SetFlash("success", "API key revoked")
+				}
+				return ctx.Redirect(http.StatusSeeOther, ctx.Request.URL.Path)
+			}
+
+			name := strings.TrimSpace(ctx.Form("name"))
+			if name == "" {
+				name = "API key"
+			}
+			token, _, err := IssueAPIKey(store, userID, name)
+			if err != nil {
+				return err
+			}
+			created = h.Div(h.Class("alert alert-success"),
+				h.P(g.Text("Your new API key. Copy it now, it will not be shown again.")),
+				CopyField("API key", token),
+			)
+		}
+
+		keys, err := store.List(userID)
+		if err != nil {
+			return err
+		}
+		action := ctx.Request.URL.Path
+		rows := []g.Node{}
+		for _, key := range keys {
+			lastUsed := "Never"
+			if !key.LastUsed.IsZero() {
+				lastUsed = TimeSince(key.LastUsed)
+			}
+			rows = append(rows, h.Tr(
+				h.Td(g.Text(key.Name)),
+				h.Td(h.Code(g.Text(apiKeyPrefix+key.ID+"_…"))),
+				h.Td(g.Text(FormatDateTime(key.Created))),
+				h.Td(g.Text(lastUsed)),
+				h.Td(g.Text(groupDigits(strconv.FormatInt(key.Requests, 10)))),
+				h.Td(Form(FormConfig{Action: action, Class: "inline-form"},
+					h.Input(h.Type("hidden"), h.Name("revoke"), h.Value(key.ID)),
+					SubmitButton("Revoke", h.Class("button-small button-danger")),
+				)),
+			))
+		}
+
+		flash := ctx.GetFlash("success")
+		return ctx.HTML(http.StatusOK, Page{
+			Title: "API keys",
+			CSS:   css,
+			Body: h.Main(h.Class("account-api-keys"),
+				h.H1(g.Text("API keys")),
+				g.If(flash != "", Alert(flash, "success")),
+				created,
+				g.If(len(keys) == 0, h.P(g.Text("You have no API keys yet."))),
+				g.If(len(keys) > 0, h.Table(h.Class("table"),
+					h.THead(h.Tr(h.Th(g.Text("Name")), h.Th(g.Text("Key")), h.Th(g.Text("Created")), h.Th(g.Text("Last used")), h.Th(g.Text("Requests")), h.Th())),
+					h.TBody(rows...),
+				)),
+				Form(FormConfig{Action: action},
+					Input("Name", "name", "text", "", h.Placeholder("e.g. Reporting script"), h.MaxLength("100")),
+					SubmitButton("Create API key"),
+				),
+			),
+		}.Render())
+	}
+}
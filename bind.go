@@ -0,0 +1,229 @@
+package nojs
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldErrors maps form field names to error messages. Bind returns it
+// when values cannot be converted, so the form can be rendered again
+// with a FieldError next to each field.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + " " + e[name]
+	}
+	return strings.Join(parts, "; ")
+}
+
+// timeLayouts are tried for time.Time fields without a layout tag; they
+// cover the formats of date, datetime-local and time inputs
+var timeLayouts = []string{"2006-01-02T15:04", "2006-01-02T15:04:05", "2006-01-02", time.RFC3339, "15:04"}
+
+var (
+	moneyType           = reflect.TypeOf(Money{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Bind fills the struct dst points to from the query string and form body.
+// Fields are named by their `form:"name"` tag, or the field name, and
+// nested structs use dotted names ("address.city"). Supported types are
+// strings, ints, uints, floats, bools (absent checkboxes are false),
+// time.Time (optional `layout:"2006-01-02"` tag), Money (`currency:"EUR"`
+// tag), encoding.TextUnmarshaler, pointers and slices of these (checkbox
+// groups, multi-selects). Conversion failures are returned as FieldErrors.
+func (c *Context) Bind(dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("nojs: Bind needs a pointer to a struct, got %T", dst)
+	}
+
+	if strings.HasPrefix(c.Request.Header.Get("Content-Type"), "multipart/form-data") {
+		c.Request.ParseMultipartForm(multipartMemory)
+	} else {
+		c.Request.ParseForm()
+	}
+
+	errs := FieldErrors{}
+	read := func(string) {}
+	if c.server.config.DevMode {
+		read = func(name string) { c.server.recordFormRead(c.route, name) }
+	}
+	bindStruct(c.Request.Form, v.Elem(), "", errs, read)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// bindStruct binds the fields of v from values, reporting each field
+// name to read
+func bindStruct(values url.Values, v reflect.Value, prefix string, errs FieldErrors, read func(name string)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get("form")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		name = prefix + name
+
+		fv := v.Field(i)
+		ft := field.Type
+		if ft.Kind() == reflect.Struct && !isScalar(ft) {
+			bindStruct(values, fv, name+".", errs, read)
+			continue
+		}
+		read(name)
+
+		vals, present := values[name]
+		if ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8 {
+			if !present {
+				continue
+			}
+			slice := reflect.MakeSlice(ft, 0, len(vals))
+			for _, raw := range vals {
+				elem := reflect.New(ft.Elem()).Elem()
+				if err := setValue(elem, raw, field.Tag); err != nil {
+					errs[name] = err.Error()
+					break
+				}
+				slice = reflect.Append(slice, elem)
+			}
+			fv.Set(slice)
+			continue
+		}
+
+		if !present {
+			if ft.Kind() == reflect.Bool {
+				fv.SetBool(false)
+			}
+			continue
+		}
+		if err := setValue(fv, vals[0], field.Tag); err != nil {
+			errs[name] = err.Error()
+		}
+	}
+}
+
+// isScalar reports whether a struct type is bound from a single value
+func isScalar(t reflect.Type) bool {
+	return t == timeType || t == moneyType || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// setValue converts raw to the type of v
+func setValue(v reflect.Value, raw string, tag reflect.StructTag) error {
+	if v.Kind() == reflect.Ptr {
+		if raw == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		ptr := reflect.New(v.Type().Elem())
+		if err := setValue(ptr.Elem(), raw, tag); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	if v.CanAddr() && v.Type() != timeType && v.Addr().Type().Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("is invalid")
+		}
+		return nil
+	}
+
+	raw = strings.TrimSpace(raw)
+	switch v.Type() {
+	case timeType:
+		if raw == "" {
+			v.Set(reflect.Zero(timeType))
+			return nil
+		}
+		layouts := timeLayouts
+		if layout := tag.Get("layout"); layout != "" {
+			layouts = []string{layout}
+		}
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, raw); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("is not a valid date or time")
+	case moneyType:
+		if raw == "" {
+			return nil
+		}
+		m, err := ParseMoney(raw, tag.Get("currency"))
+		if err != nil {
+			return fmt.Errorf("is not a valid amount")
+		}
+		v.Set(reflect.ValueOf(m))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		switch strings.ToLower(raw) {
+		case "on", "true", "1", "yes":
+			v.SetBool(true)
+		case "", "off", "false", "0", "no":
+			v.SetBool(false)
+		default:
+			return fmt.Errorf("is not a valid yes/no value")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if raw == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("is not a valid whole number")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if raw == "" {
+			return nil
+		}
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("is not a valid whole number")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if raw == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("is not a valid number")
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("has unsupported type %s", v.Type())
+	}
+	return nil
+}
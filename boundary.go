@@ -0,0 +1,94 @@
+package nojs
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// boundaryTrips counts the failures of every Boundary call site
+var boundaryTrips sync.Map // Location to *atomic.Uint64
+
+// Boundary renders the node returned by render, or fallback when render
+// returns an error or panics, or the node fails to render, so one
+// failing widget such as a third-party data sidebar does not fail the
+// whole page. A nil fallback renders a small error card. Failures are
+// logged and counted by call site for BoundaryStats:
+//
+//	nojs.Boundary(func() (g.Node, error) {
+//		rates, err := fx.Rates(ctx.Request.Context())
+//		if err != nil {
+//			return nil, err
+//		}
+//		return ratesTable(rates), nil
+//	}, nil)
+//
+// render runs, and its node is rendered, when Boundary is called.
+func Boundary(render func() (g.Node, error), fallback g.Node) g.Node {
+	var buf bytes.Buffer
+	err := renderBoundary(render, &buf)
+	if err == nil {
+		return g.Raw(buf.String())
+	}
+
+	location := "unknown"
+	if _, file, line, ok := runtime.Caller(1); ok {
+		location = filepath.Base(filepath.Dir(file)) + "/" + filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+	counter, _ := boundaryTrips.LoadOrStore(location, new(atomic.Uint64))
+	counter.(*atomic.Uint64).Add(1)
+	log.Printf("nojs: boundary at %s: %v", location, err)
+
+	if fallback == nil {
+		fallback = h.Div(h.Class("nojs-boundary"), h.Role("alert"),
+			g.Text("This part of the page could not be loaded."))
+	}
+	return fallback
+}
+
+// renderBoundary calls render and renders its node into buf, turning
+// panics into errors
+func renderBoundary(render func() (g.Node, error), buf *bytes.Buffer) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			if p == http.ErrAbortHandler {
+				panic(p) // Aborted request, not a broken widget
+			}
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	node, err := render()
+	if err != nil || node == nil {
+		return err
+	}
+	return node.Render(buf)
+}
+
+// BoundaryStat counts the failures of one Boundary call site
+type BoundaryStat struct {
+	// Location is the call site, e.g. "app/dashboard.go:42"
+	Location string
+	Trips    uint64
+}
+
+// BoundaryStats returns the failures of every Boundary call site that
+// has failed, sorted by location
+func BoundaryStats() []BoundaryStat {
+	var stats []BoundaryStat
+	boundaryTrips.Range(func(key, value interface{}) bool {
+		stats = append(stats, BoundaryStat{Location: key.(string), Trips: value.(*atomic.Uint64).Load()})
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Location < stats[j].Location })
+	return stats
+}
@@ -0,0 +1,305 @@
+package nojs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// BrandConfig configures the generated site icons and social preview
+type BrandConfig struct {
+	// Name is the site name written on the social preview image
+	Name string
+	// Tagline is written below the name on the social preview image
+	Tagline string
+	// Initials are drawn on the icons, defaulting to the first letters
+	// of the first two words of Name
+	Initials string
+	// Background and Foreground are "#rrggbb" colors
+	Background string
+	Foreground string
+}
+
+// DefaultBrandConfig returns white initials on indigo
+func DefaultBrandConfig() BrandConfig {
+	return BrandConfig{Background: "#4f46e5", Foreground: "#ffffff"}
+}
+
+// Brand holds generated site icons and a social preview image
+type Brand struct {
+	config BrandConfig
+	paths  map[string]string // Asset name to public path
+}
+
+// brandAsset is a generated file served by Brand
+type brandAsset struct {
+	name  string
+	ctype string
+	data  []byte
+}
+
+// Brand generates a favicon (SVG, PNG and ICO), an Apple touch icon and
+// a 1200x630 social preview image from initials and colors, and serves
+// them at /favicon.svg, /favicon.png, /favicon.ico, /apple-touch-icon.png
+// and /og-image.png. Add Brand.Head to the page head to reference them,
+// so a new site has icons and link previews before any design work.
+func (s *Server) Brand(config ...BrandConfig) *Brand {
+	cfg := DefaultBrandConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+		if cfg.Background == "" {
+			cfg.Background = DefaultBrandConfig().Background
+		}
+		if cfg.Foreground == "" {
+			cfg.Foreground = DefaultBrandConfig().Foreground
+		}
+	}
+	if cfg.Initials == "" {
+		cfg.Initials = brandInitials(cfg.Name)
+	}
+	bg, fg := parseHexColor(cfg.Background), parseHexColor(cfg.Foreground)
+
+	favicon := iconPNG(32, cfg.Initials, bg, fg)
+	assets := []brandAsset{
+		{"favicon.svg", "image/svg+xml", iconSVG(cfg.Initials, cfg.Background, cfg.Foreground)},
+		{"favicon.png", "image/png", favicon},
+		{"favicon.ico", "image/x-icon", pngICO(favicon, 32)},
+		{"apple-touch-icon.png", "image/png", iconPNG(180, cfg.Initials, bg, fg)},
+		{"og-image.png", "image/png", previewPNG(cfg, bg, fg)},
+	}
+
+	b := &Brand{config: cfg, paths: make(map[string]string)}
+	for _, asset := range assets {
+		asset := asset
+		sum := sha256.Sum256(asset.data)
+		etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+		b.paths[asset.name] = s.URL("/" + asset.name)
+		s.GET("/"+asset.name, func(ctx *Context) error {
+			ctx.ResponseWriter.Header().Set("ETag", etag)
+			ctx.ResponseWriter.Header().Set("Cache-Control", "public, max-age=86400")
+			if ifNoneMatch(ctx.Request, etag) {
+				ctx.ResponseWriter.WriteHeader(http.StatusNotModified)
+				ctx.written = true
+				return nil
+			}
+			ctx.ResponseWriter.Header().Set("Content-Type", asset.ctype)
+			ctx.ResponseWriter.WriteHeader(http.StatusOK)
+			ctx.written = true
+			_, err := ctx.ResponseWriter.Write(asset.data)
+			return err
+		})
+	}
+	return b
+}
+
+// Head returns the icon links and Open Graph tags for the page head.
+// The preview image URL is absolute, as social networks require.
+func (b *Brand) Head(ctx *Context) g.Node {
+	return g.Group([]g.Node{
+		h.Link(h.Rel("icon"), h.Href(b.paths["favicon.svg"]), h.Type("image/svg+xml")),
+		h.Link(h.Rel("icon"), h.Href(b.paths["favicon.png"]), h.Type("image/png"), g.Attr("sizes", "32x32")),
+		h.Link(h.Rel("apple-touch-icon"), h.Href(b.paths["apple-touch-icon.png"])),
+		h.Meta(h.Name("theme-color"), h.Content(b.config.Background)),
+		g.If(b.config.Name != "", h.Meta(g.Attr("property", "og:site_name"), h.Content(b.config.Name))),
+		h.Meta(g.Attr("property", "og:image"), h.Content(ctx.Scheme()+"://"+ctx.Request.Host+b.paths["og-image.png"])),
+		h.Meta(g.Attr("property", "og:image:width"), h.Content("1200")),
+		h.Meta(g.Attr("property", "og:image:height"), h.Content("630")),
+		h.Meta(h.Name("twitter:card"), h.Content("summary_large_image")),
+	})
+}
+
+// brandInitials takes the first letters of the first two words of name
+func brandInitials(name string) string {
+	var initials []rune
+	for _, word := range strings.Fields(name) {
+		r := []rune(word)[0]
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			initials = append(initials, unicode.ToUpper(r))
+		}
+		if len(initials) == 2 {
+			break
+		}
+	}
+	if len(initials) == 0 {
+		return "N"
+	}
+	return string(initials)
+}
+
+// parseHexColor parses "#rrggbb", returning black when invalid
+func parseHexColor(s string) color.RGBA {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32)
+	if err != nil || len(strings.TrimPrefix(s, "#")) != 6 {
+		return color.RGBA{A: 255}
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}
+}
+
+// iconSVG draws the initials on a rounded square
+func iconSVG(initials, bg, fg string) []byte {
+	size := 64
+	if len([]rune(initials)) > 1 {
+		size = 44
+	}
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100">`+
+		`<rect width="100" height="100" rx="20" fill="%s"/>`+
+		`<text x="50" y="50" dy=".35em" text-anchor="middle" font-family="system-ui, sans-serif" font-weight="700" font-size="%d" fill="%s">%s</text>`+
+		`</svg>`, html.EscapeString(bg), size, html.EscapeString(fg), html.EscapeString(initials)))
+}
+
+// iconPNG draws the initials centered on a square of the given size
+func iconPNG(size int, initials string, bg, fg color.RGBA) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{bg}, image.Point{}, draw.Src)
+	scale := glyphScale(initials, size*3/4, size*3/5)
+	drawText(img, initials, scale, size/2, size/2, fg)
+	return encodePNG(img)
+}
+
+// previewPNG draws the site name and tagline on a 1200x630 image
+func previewPNG(cfg BrandConfig, bg, fg color.RGBA) []byte {
+	const width, height = 1200, 630
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{bg}, image.Point{}, draw.Src)
+
+	name := cfg.Name
+	if name == "" {
+		name = cfg.Initials
+	}
+	nameScale := glyphScale(name, width-160, 200)
+	if nameScale > 16 {
+		nameScale = 16
+	}
+	taglineScale := glyphScale(cfg.Tagline, width-160, 60)
+	if taglineScale > 6 {
+		taglineScale = 6
+	}
+
+	if cfg.Tagline == "" {
+		drawText(img, name, nameScale, width/2, height/2, fg)
+	} else {
+		nameHeight, taglineHeight, gap := glyphHeight*nameScale, glyphHeight*taglineScale, 48
+		top := (height - nameHeight - gap - taglineHeight) / 2
+		drawText(img, name, nameScale, width/2, top+nameHeight/2, fg)
+		drawText(img, cfg.Tagline, taglineScale, width/2, top+nameHeight+gap+taglineHeight/2, fg)
+	}
+	return encodePNG(img)
+}
+
+func encodePNG(img image.Image) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// pngICO wraps a PNG in an ICO container, which browsers accept for
+// /favicon.ico
+func pngICO(data []byte, size int) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, 1}) // Reserved, icon type, image count
+	buf.Write([]byte{byte(size), byte(size), 0, 0})            // Width, height, palette size, reserved
+	binary.Write(&buf, binary.LittleEndian, []uint16{1, 32})   // Color planes, bits per pixel
+	binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(data)), 22})
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// Glyphs of the built-in 5x7 bitmap font; lowercase letters are drawn as
+// capitals and unknown characters as spaces
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+)
+
+var glyphs = map[rune][glyphHeight]string{
+	'A': {".###.", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'B': {"####.", "#...#", "#...#", "####.", "#...#", "#...#", "####."},
+	'C': {".###.", "#...#", "#....", "#....", "#....", "#...#", ".###."},
+	'D': {"####.", "#...#", "#...#", "#...#", "#...#", "#...#", "####."},
+	'E': {"#####", "#....", "#....", "####.", "#....", "#....", "#####"},
+	'F': {"#####", "#....", "#....", "####.", "#....", "#....", "#...."},
+	'G': {".###.", "#...#", "#....", "#.###", "#...#", "#...#", ".####"},
+	'H': {"#...#", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'I': {".###.", "..#..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'J': {"..###", "...#.", "...#.", "...#.", "...#.", "#..#.", ".##.."},
+	'K': {"#...#", "#..#.", "#.#..", "##...", "#.#..", "#..#.", "#...#"},
+	'L': {"#....", "#....", "#....", "#....", "#....", "#....", "#####"},
+	'M': {"#...#", "##.##", "#.#.#", "#.#.#", "#...#", "#...#", "#...#"},
+	'N': {"#...#", "#...#", "##..#", "#.#.#", "#..##", "#...#", "#...#"},
+	'O': {".###.", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'P': {"####.", "#...#", "#...#", "####.", "#....", "#....", "#...."},
+	'Q': {".###.", "#...#", "#...#", "#...#", "#.#.#", "#..#.", ".##.#"},
+	'R': {"####.", "#...#", "#...#", "####.", "#.#..", "#..#.", "#...#"},
+	'S': {".####", "#....", "#....", ".###.", "....#", "....#", "####."},
+	'T': {"#####", "..#..", "..#..", "..#..", "..#..", "..#..", "..#.."},
+	'U': {"#...#", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'V': {"#...#", "#...#", "#...#", "#...#", "#...#", ".#.#.", "..#.."},
+	'W': {"#...#", "#...#", "#...#", "#.#.#", "#.#.#", "#.#.#", ".#.#."},
+	'X': {"#...#", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", "#...#"},
+	'Y': {"#...#", "#...#", ".#.#.", "..#..", "..#..", "..#..", "..#.."},
+	'Z': {"#####", "....#", "...#.", "..#..", ".#...", "#....", "#####"},
+	'0': {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1': {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2': {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3': {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4': {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5': {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6': {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7': {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8': {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9': {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+	'-': {".....", ".....", ".....", "#####", ".....", ".....", "....."},
+	'.': {".....", ".....", ".....", ".....", ".....", ".##..", ".##.."},
+	'!': {"..#..", "..#..", "..#..", "..#..", "..#..", ".....", "..#.."},
+	'&': {".##..", "#..#.", "#.#..", ".#...", "#.#.#", "#..#.", ".##.#"},
+}
+
+// glyphScale returns the largest scale at which text fits in the box
+func glyphScale(text string, maxWidth, maxHeight int) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 1
+	}
+	scale := maxWidth / (n*(glyphWidth+1) - 1)
+	if s := maxHeight / glyphHeight; s < scale {
+		scale = s
+	}
+	if scale < 1 {
+		scale = 1
+	}
+	return scale
+}
+
+// drawText draws text with the bitmap font, centered on (cx, cy)
+func drawText(img *image.RGBA, text string, scale, cx, cy int, c color.RGBA) {
+	runes := []rune(strings.ToUpper(text))
+	x := cx - (len(runes)*(glyphWidth+1)-1)*scale/2
+	y := cy - glyphHeight*scale/2
+	for _, r := range runes {
+		if glyph, ok := glyphs[r]; ok {
+			for row, line := range glyph {
+				for col, pixel := range line {
+					if pixel == '#' {
+						rect := image.Rect(x+col*scale, y+row*scale, x+(col+1)*scale, y+(row+1)*scale)
+						draw.Draw(img, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+					}
+				}
+			}
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
@@ -0,0 +1,93 @@
+package nojs
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// BufferingTestPath is the route used by the proxy-buffering self-test
+const BufferingTestPath = "/_nojs/buffering-test"
+
+// BufferingDocsURL explains how to disable response buffering in common
+// reverse proxies; it is included in the startup warning
+var BufferingDocsURL = "https://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_buffering"
+
+// bufferingTestDelay is the pause between the two chunks of the test route
+const bufferingTestDelay = time.Second
+
+// bufferingTestHandler writes two chunks separated by bufferingTestDelay
+func bufferingTestHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	fmt.Fprintln(w, "first")
+	flusher.Flush()
+
+	select {
+	case <-time.After(bufferingTestDelay):
+	case <-r.Context().Done():
+		return
+	}
+
+	fmt.Fprintln(w, "second")
+	flusher.Flush()
+}
+
+// CheckBuffering fetches the buffering test route through baseURL and
+// reports whether the first chunk was held back until the response ended.
+// The server must have been created with BufferingCheck enabled.
+func CheckBuffering(baseURL string) (buffered bool, err error) {
+	client := &http.Client{Timeout: 10 * bufferingTestDelay}
+
+	start := time.Now()
+	resp, err := client.Get(strings.TrimSuffix(baseURL, "/") + BufferingTestPath)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("buffering test returned %s", resp.Status)
+	}
+
+	if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+		return false, err
+	}
+
+	// An unbuffered first chunk arrives well before the delay elapses
+	return time.Since(start) >= bufferingTestDelay*9/10, nil
+}
+
+// startBufferingCheck runs the self-test in the background once the
+// listener has had a moment to come up
+func (s *Server) startBufferingCheck() {
+	if !s.config.BufferingCheck || s.config.BaseURL == "" {
+		return
+	}
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+
+		buffered, err := CheckBuffering(s.config.BaseURL)
+		if err != nil {
+			log.Printf("nojs: buffering check against %s failed: %v", s.config.BaseURL, err)
+			return
+		}
+		if buffered {
+			log.Printf("nojs: WARNING streaming responses through %s are buffered by a proxy; "+
+				"live updates will not reach browsers until each response ends. "+
+				"Disable proxy buffering for this site, see %s", s.config.BaseURL, BufferingDocsURL)
+		}
+	}()
+}
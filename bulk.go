@@ -0,0 +1,66 @@
+package nojs
+
+import (
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// SelectionField is the checkbox name used by BulkList rows
+const SelectionField = "ids"
+
+// BulkItem is one selectable row of a BulkList
+type BulkItem struct {
+	ID      string
+	Content g.Node
+}
+
+// BulkListConfig configures a BulkList
+type BulkListConfig struct {
+	Action  string // Form action receiving the batch submission
+	Items   []BulkItem
+	Actions []Action // Batch actions, read with ctx.SubmitAction
+	// Selected pre-checks rows, e.g. after a "select all" link or when
+	// re-rendering a failed submission
+	Selected []string
+	// SelectAllURL, when set, renders "Select all" and "Clear" links;
+	// handlers typically pass ctx.SelectedIDs or all IDs as Selected
+	SelectAllURL string
+	ClearURL     string
+}
+
+// BulkList creates a list whose rows can be selected with checkboxes and
+// acted upon with a toolbar of batch submit buttons
+func BulkList(config BulkListConfig) g.Node {
+	rows := []g.Node{h.Class("bulk-list")}
+	for _, item := range config.Items {
+		id := "select-" + item.ID
+		rows = append(rows, h.Li(h.Class("bulk-item"),
+			h.Input(
+				h.Type("checkbox"),
+				h.Name(SelectionField),
+				h.Value(item.ID),
+				h.ID(id),
+				g.If(Contains(config.Selected, item.ID), h.Checked()),
+			),
+			h.Label(h.For(id), item.Content),
+		))
+	}
+
+	var selection g.Node
+	if config.SelectAllURL != "" {
+		selection = h.Div(h.Class("bulk-selection"),
+			h.A(h.Href(config.SelectAllURL), g.Text("Select all")),
+			g.If(config.ClearURL != "", h.A(h.Href(config.ClearURL), g.Text("Clear"))),
+		)
+	}
+
+	return Form(FormConfig{Action: config.Action, Class: "bulk-form"},
+		h.Div(h.Class("bulk-toolbar"), selection, ActionButtons(config.Actions...)),
+		h.Ul(rows...),
+	)
+}
+
+// SelectedIDs returns the IDs checked in a BulkList submission
+func (c *Context) SelectedIDs() []string {
+	return c.FormValues(SelectionField)
+}
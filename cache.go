@@ -0,0 +1,98 @@
+package nojs
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keyPurge stores the surrogate keys queued with ctx.Purge
+const keyPurge = "nojs.purge"
+
+// CachePublic lets browsers and shared caches such as CDNs keep the
+// response for maxAge, then serve it stale for up to swr while they
+// revalidate in the background. Use it for pages that look the same to
+// every visitor.
+func (c *Context) CachePublic(maxAge, swr time.Duration) {
+	value := "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+	if swr > 0 {
+		value += ", stale-while-revalidate=" + strconv.Itoa(int(swr.Seconds()))
+	}
+	c.ResponseWriter.Header().Set("Cache-Control", value)
+}
+
+// CachePrivate keeps the response out of shared caches and makes the
+// browser revalidate it before reuse, for pages showing the user's own
+// data
+func (c *Context) CachePrivate() {
+	c.ResponseWriter.Header().Set("Cache-Control", "private, no-cache")
+}
+
+// NoStore forbids any cache from keeping the response, for sensitive
+// pages such as account settings
+func (c *Context) NoStore() {
+	c.ResponseWriter.Header().Set("Cache-Control", "no-store")
+}
+
+// SurrogateKeys tags a cacheable response with keys naming the resources
+// it shows, e.g. "todos" and "todo-42", so a CDN can purge every page
+// showing a resource when it changes. Keys are sent in the Surrogate-Key
+// header, which the CDN strips before the response reaches browsers.
+func (c *Context) SurrogateKeys(keys ...string) {
+	header := c.ResponseWriter.Header()
+	existing := strings.Fields(header.Get("Surrogate-Key"))
+	for _, key := range keys {
+		if !Contains(existing, key) {
+			existing = append(existing, key)
+		}
+	}
+	header.Set("Surrogate-Key", strings.Join(existing, " "))
+}
+
+// Purger evicts cached responses tagged with surrogate keys from a CDN
+type Purger interface {
+	Purge(ctx context.Context, keys []string) error
+}
+
+// PurgeFunc adapts a function to the Purger interface
+type PurgeFunc func(ctx context.Context, keys []string) error
+
+// Purge implements Purger
+func (f PurgeFunc) Purge(ctx context.Context, keys []string) error {
+	return f(ctx, keys)
+}
+
+// Purge queues surrogate keys for purging once the handler returns
+// without error, typically from a POST handler after saving:
+//
+//	ctx.Purge("todos", "todo-"+id)
+//	return ctx.Redirect(http.StatusSeeOther, "/todos")
+//
+// Keys are purged through ServerConfig.Purger before the request ends,
+// so the page the user is redirected to is already fresh. Without a
+// Purger, Purge does nothing.
+func (c *Context) Purge(keys ...string) {
+	queued, _ := ValueOf[[]string](c, keyPurge)
+	for _, key := range keys {
+		if !Contains(queued, key) {
+			queued = append(queued, key)
+		}
+	}
+	c.Set(keyPurge, queued)
+}
+
+// purgeQueued purges the keys queued with Purge, logging failures since
+// the response has already been sent
+func (c *Context) purgeQueued() {
+	keys, _ := ValueOf[[]string](c, keyPurge)
+	purger := c.server.root().config.Purger
+	if len(keys) == 0 || purger == nil {
+		return
+	}
+	c.Set(keyPurge, []string(nil))
+	if err := purger.Purge(c.Request.Context(), keys); err != nil {
+		log.Printf("nojs: purging %s: %v", strings.Join(keys, " "), err)
+	}
+}
@@ -0,0 +1,86 @@
+package nojs
+
+import (
+	"fmt"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// CarouselConfig configures a carousel
+type CarouselConfig struct {
+	ID string // Prefix for slide IDs, defaults to "carousel"
+	// Current is the zero-based slide shown first when auto-advancing
+	Current int
+	// AutoAdvance reloads the page every AutoAdvance seconds on the next
+	// slide; zero disables it
+	AutoAdvance int
+	// Param is the query parameter carrying the current slide for
+	// auto-advance, defaults to "slide"
+	Param string
+}
+
+// Carousel creates a slideshow using CSS scroll-snap, with previous/next
+// anchor links between slides. Include CarouselStyles() in the page head.
+func Carousel(items []g.Node, config ...CarouselConfig) g.Node {
+	cfg := CarouselConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.ID == "" {
+		cfg.ID = "carousel"
+	}
+	if cfg.Param == "" {
+		cfg.Param = "slide"
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	slideID := func(i int) string {
+		return fmt.Sprintf("%s-%d", cfg.ID, (i+len(items))%len(items))
+	}
+
+	slides := []g.Node{h.Class("carousel-track")}
+	dots := []g.Node{h.Class("carousel-dots")}
+	for i, item := range items {
+		slides = append(slides, h.Div(h.ID(slideID(i)), h.Class("carousel-slide"),
+			item,
+			h.A(h.Href("#"+slideID(i-1)), h.Class("carousel-prev"), h.Aria("label", "Previous slide"), g.Text("‹")),
+			h.A(h.Href("#"+slideID(i+1)), h.Class("carousel-next"), h.Aria("label", "Next slide"), g.Text("›")),
+		))
+		dots = append(dots, h.A(h.Href("#"+slideID(i)), h.Aria("label", fmt.Sprintf("Slide %d", i+1)),
+			g.If(i == cfg.Current, h.Class("active")),
+		))
+	}
+
+	var refresh g.Node
+	if cfg.AutoAdvance > 0 {
+		next := (cfg.Current + 1) % len(items)
+		refresh = h.Meta(
+			g.Attr("http-equiv", "refresh"),
+			g.Attr("content", fmt.Sprintf("%d;url=?%s=%d#%s", cfg.AutoAdvance, cfg.Param, next, slideID(next))),
+		)
+	}
+
+	return h.Div(h.ID(cfg.ID), h.Class("carousel"),
+		refresh,
+		h.Div(slides...),
+		h.Nav(dots...),
+	)
+}
+
+// CarouselStyles returns the CSS required by Carousel
+func CarouselStyles() g.Node {
+	return Style(`
+.carousel { position: relative; }
+.carousel-track { display: flex; overflow-x: auto; scroll-snap-type: x mandatory; scroll-behavior: smooth; }
+.carousel-slide { position: relative; flex: 0 0 100%; scroll-snap-align: start; }
+.carousel-prev, .carousel-next { position: absolute; top: 50%; transform: translateY(-50%); padding: 0.5rem 0.75rem; background: rgba(0,0,0,0.4); color: #fff; text-decoration: none; font-size: 1.5rem; }
+.carousel-prev { left: 0.5rem; }
+.carousel-next { right: 0.5rem; }
+.carousel-dots { display: flex; justify-content: center; gap: 0.5rem; padding: 0.5rem; }
+.carousel-dots a { width: 0.75rem; height: 0.75rem; border-radius: 50%; background: #ccc; }
+.carousel-dots a.active, .carousel-dots a:focus { background: #333; }
+`)
+}
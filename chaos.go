@@ -0,0 +1,147 @@
+package nojs
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ChaosRule describes the faults injected into a route's requests
+type ChaosRule struct {
+	// Latency delays every request, plus a random extra of up to Jitter
+	Latency time.Duration
+	Jitter  time.Duration
+	// ErrorRate is the fraction of requests, from 0 to 1, that fail
+	// with ErrorStatus (503 by default) instead of reaching the handler
+	ErrorRate   float64
+	ErrorStatus int
+	// DropRate is the fraction of requests whose connection is cut
+	// after a random time of up to DropAfter (10s by default), without
+	// ending the response. It is meant for streams, to check that pages
+	// recover once the browser gives up on them.
+	DropRate  float64
+	DropAfter time.Duration
+}
+
+// ChaosConfig configures the Chaos middleware
+type ChaosConfig struct {
+	// Default applies to routes missing from Routes
+	Default ChaosRule
+	// Routes maps route patterns, as returned by ctx.Route, to rules
+	Routes map[string]ChaosRule
+}
+
+// Chaos injects latency, errors and dropped connections so the no-JS
+// fallbacks of an app (static handlers, refresh flows, reconnecting
+// streams) can be checked by hand. It only acts when ServerConfig.DevMode
+// is set, so it is safe to leave registered.
+//
+//	server.Use(nojs.Chaos(nojs.ChaosConfig{
+//		Default: nojs.ChaosRule{Latency: 300 * time.Millisecond, Jitter: time.Second},
+//		Routes: map[string]nojs.ChaosRule{
+//			"/live": {DropRate: 0.5, DropAfter: 5 * time.Second},
+//		},
+//	}))
+func Chaos(config ChaosConfig) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			if !ctx.server.root().config.DevMode {
+				return next(ctx)
+			}
+			rule, ok := config.Routes[ctx.Route()]
+			if !ok {
+				rule = config.Default
+			}
+
+			if delay := rule.Latency + randomDuration(rule.Jitter); delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-ctx.Request.Context().Done():
+					timer.Stop()
+					return ctx.Request.Context().Err()
+				}
+			}
+
+			if rule.ErrorRate > 0 && rand.Float64() < rule.ErrorRate {
+				status := rule.ErrorStatus
+				if status == 0 {
+					status = http.StatusServiceUnavailable
+				}
+				return NewHTTPError(status, "Chaos: injected error")
+			}
+
+			if rule.DropRate > 0 && rand.Float64() < rule.DropRate {
+				return chaosDrop(ctx, next, rule.DropAfter)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// chaosDrop runs next, cutting the connection after a random time of up
+// to after. The request context is cancelled and writes fail from then
+// on, so stream loops end; once the handler returns, the connection is
+// aborted with http.ErrAbortHandler.
+func chaosDrop(ctx *Context, next Handler, after time.Duration) error {
+	if after <= 0 {
+		after = 10 * time.Second
+	}
+	reqCtx, cancel := context.WithCancel(ctx.Request.Context())
+	defer cancel()
+
+	w := &chaosWriter{ResponseWriter: ctx.ResponseWriter}
+	r := ctx.Request
+	ctx.ResponseWriter, ctx.Request = w, r.WithContext(reqCtx)
+	timer := time.AfterFunc(randomDuration(after), func() {
+		w.dropped.Store(true)
+		cancel()
+	})
+
+	err := next(ctx)
+	timer.Stop()
+	ctx.ResponseWriter, ctx.Request = w.ResponseWriter, r
+	if w.dropped.Load() {
+		panic(http.ErrAbortHandler)
+	}
+	return err
+}
+
+// errChaosDropped is returned by writes after a chaos drop
+var errChaosDropped = errors.New("nojs: connection dropped by Chaos")
+
+// chaosWriter fails writes once the connection is dropped
+type chaosWriter struct {
+	http.ResponseWriter
+	dropped atomic.Bool
+}
+
+func (w *chaosWriter) Write(b []byte) (int, error) {
+	if w.dropped.Load() {
+		return 0, errChaosDropped
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher for streams
+func (w *chaosWriter) Flush() {
+	if !w.dropped.Load() {
+		http.NewResponseController(w.ResponseWriter).Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *chaosWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// randomDuration returns a random duration in [0, max)
+func randomDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
@@ -0,0 +1,59 @@
+// Command nojs-replay re-executes requests recorded by nojs.Recorder
+// against a dev server and reports responses that changed:
+//
+//	nojs-replay -dir recordings -target http://localhost:8080
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/jairo/mavis/nojs"
+)
+
+func main() {
+	dir := flag.String("dir", "recordings", "directory of recordings")
+	target := flag.String("target", "http://localhost:8080", "base URL of the server to replay against")
+	cookie := flag.String("cookie", "", "Cookie header to send instead of the redacted one")
+	verbose := flag.Bool("v", false, "also list requests that matched")
+	flag.Parse()
+
+	var cfg nojs.ReplayConfig
+	if *cookie != "" {
+		cfg.Header = http.Header{"Cookie": {*cookie}}
+	}
+	results, err := nojs.Replay(*dir, *target, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.OK() {
+			if *verbose {
+				fmt.Printf("ok    %s %s\n", r.Method, r.URL)
+			}
+			continue
+		}
+		failed++
+		var problems []string
+		if r.Err != nil {
+			problems = append(problems, r.Err.Error())
+		}
+		if r.GotStatus != r.WantStatus && r.Err == nil {
+			problems = append(problems, fmt.Sprintf("status %d, recorded %d", r.GotStatus, r.WantStatus))
+		}
+		if r.BodyChanged {
+			problems = append(problems, "body changed")
+		}
+		fmt.Printf("FAIL  %s %s (%s): %s\n", r.Method, r.URL, r.File, strings.Join(problems, "; "))
+	}
+	fmt.Printf("%d replayed, %d changed\n", len(results), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
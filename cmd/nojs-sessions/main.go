@@ -0,0 +1,78 @@
+// Command nojs-sessions maintains sessions kept by a nojs.FileSessionBackend.
+// Secrets are read from NOJS_SESSION_SECRETS, comma separated, newest
+// first. To rotate the session secret once every instance encrypts with
+// the new one, re-encrypt the stored sessions, then drop the old secret:
+//
+//	NOJS_SESSION_SECRETS=new,old nojs-sessions -dir sessions -reencrypt
+//
+// Without -reencrypt it lists how many sessions each payload version has,
+// to tell when the Up migrations of old versions are no longer needed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jairo/mavis/nojs"
+)
+
+func main() {
+	dir := flag.String("dir", "sessions", "directory of the session files")
+	reencrypt := flag.Bool("reencrypt", false, "encrypt every session again under the first secret")
+	flag.Parse()
+
+	var secrets []string
+	for _, secret := range strings.Split(os.Getenv("NOJS_SESSION_SECRETS"), ",") {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	if len(secrets) == 0 {
+		fmt.Fprintln(os.Stderr, "NOJS_SESSION_SECRETS is not set")
+		os.Exit(2)
+	}
+	codec := &nojs.SessionCodec{Secrets: secrets}
+	backend := nojs.NewFileSessionBackend(*dir)
+
+	if *reencrypt {
+		done, failed, err := nojs.ReencryptSessions(backend, codec)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%d re-encrypted, %d not decryptable\n", done, failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	versions := make(map[int]int)
+	failed := 0
+	err := backend.Each(func(id string, payload []byte) bool {
+		if v, err := codec.PayloadVersion(payload); err == nil {
+			versions[v]++
+		} else {
+			failed++
+		}
+		return true
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	keys := make([]int, 0, len(versions))
+	for v := range versions {
+		keys = append(keys, v)
+	}
+	sort.Ints(keys)
+	for _, v := range keys {
+		fmt.Printf("version %d: %d sessions\n", v, versions[v])
+	}
+	if failed > 0 {
+		fmt.Printf("%d not decryptable with these secrets\n", failed)
+	}
+}
@@ -0,0 +1,84 @@
+// Command nojs-wirebench compares the cost of sending hub events between
+// processes as JSON and with nojs.EventCodec. For every payload size it
+// measures encoding once on the publisher and decoding on every
+// receiving instance, with all CPUs busy:
+//
+//	nojs-wirebench -receivers 8
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jairo/mavis/nojs"
+)
+
+// wireFormat encodes and decodes events
+type wireFormat struct {
+	name   string
+	encode func(nojs.Event) ([]byte, error)
+	decode func([]byte) (nojs.Event, error)
+}
+
+func main() {
+	receivers := flag.Int("receivers", 4, "instances decoding every published event")
+	flag.Parse()
+
+	var codec nojs.EventCodec
+	formats := []wireFormat{
+		{"json", func(e nojs.Event) ([]byte, error) { return json.Marshal(e) }, func(b []byte) (nojs.Event, error) {
+			var e nojs.Event
+			return e, json.Unmarshal(b, &e)
+		}},
+		{"binary", codec.Encode, codec.Decode},
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "payload\tformat\tencoded bytes\tencode\tdecode\tper event\tallocs\t")
+	for _, size := range []int{200, 4 << 10, 64 << 10} {
+		event := nojs.Event{Room: "board:42", Type: "card", Data: []byte(sampleHTML(size)), Time: time.Now()}
+		for _, f := range formats {
+			encoded, err := f.encode(event)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			enc := testing.Benchmark(func(b *testing.B) {
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						f.encode(event)
+					}
+				})
+			})
+			dec := testing.Benchmark(func(b *testing.B) {
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						f.decode(encoded)
+					}
+				})
+			})
+			perEvent := time.Duration(enc.NsPerOp() + int64(*receivers)*dec.NsPerOp())
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\t%d\t\n", size, f.name, len(encoded),
+				time.Duration(enc.NsPerOp()), time.Duration(dec.NsPerOp()), perEvent,
+				enc.AllocsPerOp()+int64(*receivers)*dec.AllocsPerOp())
+		}
+	}
+	w.Flush()
+}
+
+// sampleHTML returns rendered-looking HTML of about size bytes
+func sampleHTML(size int) string {
+	var b strings.Builder
+	for i := 0; b.Len() < size; i++ {
+		fmt.Fprintf(&b, `<li class="card" id="card-%d"><h3>Task %d</h3><p>Move the release checklist to the new board</p></li>`, i, i)
+	}
+	return b.String()[:size]
+}
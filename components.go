@@ -15,12 +15,14 @@ type Page struct {
 	Description string
 	CSS         []string
 	Body        g.Node
-	Scripts     []g.Node // For progressive enhancement only
+	Scripts     []g.Node // For progressive enhancement only, see EnhancementScript
+	Head        []g.Node // Extra head elements, e.g. JSONLD or Article
 }
 
-// Render renders a complete HTML page
+// Render renders a complete HTML page. ctx.HTML sends preload Link
+// headers for its CSS.
 func (p Page) Render(nodes ...g.Node) g.Node {
-	return c.HTML5(
+	return pageNode{page: p, nodes: nodes, Node: c.HTML5(
 		c.HTML5Props{
 			Title:       p.Title,
 			Description: p.Description,
@@ -30,10 +32,11 @@ func (p Page) Render(nodes ...g.Node) g.Node {
 				g.Map(p.CSS, func(css string) g.Node {
 					return h.Link(h.Rel("stylesheet"), h.Href(css))
 				}),
+				g.Group(p.Head),
 			},
 			Body: append([]g.Node{p.Body}, append(nodes, p.Scripts...)...),
 		},
-	)
+	)}
 }
 
 // Layout represents a reusable page layout
@@ -43,19 +46,29 @@ type Layout struct {
 	Header      g.Node
 	Navigation  g.Node
 	Footer      g.Node
+	// TitlePrefix is put before titles set with ctx.Title by ctx.Render,
+	// e.g. "My App | "
+	TitlePrefix string
+	Head        []g.Node
 }
 
 // Wrap wraps content in the layout
 func (l Layout) Wrap(content g.Node) g.Node {
+	return l.page(l.Title, content)
+}
+
+// page renders content in the layout with the given title
+func (l Layout) page(title string, content g.Node) g.Node {
 	return Page{
-		Title: l.Title,
+		Title: title,
 		CSS:   l.CSS,
-		Body: h.Body(
+		Head:  l.Head,
+		Body: g.Group([]g.Node{
 			g.If(l.Header != nil, l.Header),
 			g.If(l.Navigation != nil, l.Navigation),
 			h.Main(content),
 			g.If(l.Footer != nil, l.Footer),
-		),
+		}),
 	}.Render()
 }
 
@@ -67,9 +80,11 @@ type FormConfig struct {
 	Method   string
 	Class    string
 	Redirect string // For post-submit redirect
+	AntiBot  *AntiBot // Adds honeypot and time-trap fields
 }
 
-// Form creates a form with proper no-JS handling
+// Form creates a form with proper no-JS handling. With the CSRF
+// middleware, ctx.HTML adds the token field to POST forms.
 func Form(config FormConfig, children ...g.Node) g.Node {
 	method := config.Method
 	if method == "" {
@@ -93,6 +108,11 @@ func Form(config FormConfig, children ...g.Node) g.Node {
 		nodes[1] = h.Method("POST")
 	}
 
+	// Add anti-bot fields if configured
+	if config.AntiBot != nil {
+		children = append([]g.Node{config.AntiBot.Fields()}, children...)
+	}
+
 	// Add redirect field if specified
 	if config.Redirect != "" {
 		children = append([]g.Node{
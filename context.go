@@ -1,9 +1,15 @@
 package nojs
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
@@ -18,6 +24,13 @@ type Context struct {
 	server         *Server
 	params         map[string]string
 	written        bool
+	stream         *StreamWriter
+	streamCounted  bool
+	nonce          string
+	snapshot       *snapshotState
+	route          string
+	values         *contextValues
+	response       *statusRecorder
 }
 
 // Handler is a function that handles HTTP requests
@@ -34,6 +47,52 @@ func (c *Context) Param(name string) string {
 	return c.params[name]
 }
 
+// Route returns the pattern of the route handling the request, e.g.
+// "/todos/" for /todos/42
+func (c *Context) Route() string {
+	return c.route
+}
+
+// RequestID returns the ID of the current request, taken from the
+// X-Request-ID header when it is a plausible ID or generated otherwise.
+// Sent IDs end up in logs and HTML comments, so only up to 64 letters,
+// digits, dots, underscores and hyphens are accepted.
+func (c *Context) RequestID() string {
+	if id := c.GetString(KeyRequestID); id != "" {
+		return id
+	}
+
+	id := c.Request.Header.Get("X-Request-ID")
+	if !validRequestID(id) {
+		b := make([]byte, 8)
+		rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+	c.Set(KeyRequestID, id)
+	return id
+}
+
+// validRequestID reports whether id matches [A-Za-z0-9._-]{1,64}
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch b := id[i]; {
+		case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9', b == '.', b == '_', b == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// URL returns the public path of a path inside the server handling the
+// request, which differs from path when the server is mounted
+func (c *Context) URL(path string) string {
+	return c.server.URL(path)
+}
+
 // Query returns a query parameter by name
 func (c *Context) Query(name string) string {
 	return c.Request.URL.Query().Get(name)
@@ -46,6 +105,9 @@ func (c *Context) QueryValues(name string) []string {
 
 // Form returns a form value by name
 func (c *Context) Form(name string) string {
+	if c.server.config.DevMode {
+		c.server.recordFormRead(c.route, name)
+	}
 	if c.Request.Method == "POST" || c.Request.Method == "PUT" {
 		c.Request.ParseForm()
 	}
@@ -68,12 +130,124 @@ func (c *Context) JSON(status int, data interface{}) error {
 	return json.NewEncoder(c.ResponseWriter).Encode(data)
 }
 
-// HTML renders an HTML response using gomponents
+// XML sends an XML response with the XML declaration
+func (c *Context) XML(status int, data interface{}) error {
+	c.ResponseWriter.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	c.ResponseWriter.WriteHeader(status)
+	c.written = true
+	if _, err := io.WriteString(c.ResponseWriter, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(c.ResponseWriter).Encode(data)
+}
+
+// HTML renders an HTML response using gomponents. The page is rendered
+// once: head tags are added to pages built with Page as nodes, and CSRF
+// fields and Turbo frame selection are applied to the rendered bytes in a
+// single pass.
 func (c *Context) HTML(status int, node g.Node) error {
-	c.ResponseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.preloadPage(node)
+	header := c.ResponseWriter.Header()
+	header.Set("Content-Type", "text/html; charset=utf-8")
+
+	head := c.headTags()
+	if page, ok := node.(pageNode); ok && len(head) > 0 {
+		node, head = page.withHead(head), nil
+	}
+	frame := ""
+	if c.server.config.TurboMode {
+		header.Add("Vary", "Turbo-Frame")
+		frame = c.TurboFrameID()
+	}
+	csrf := c.CSRFToken() != ""
+
+	if len(head) == 0 && !csrf && frame == "" {
+		c.ResponseWriter.WriteHeader(status)
+		c.written = true
+		return node.Render(c.ResponseWriter)
+	}
+
+	var buf bytes.Buffer
+	if err := node.Render(&buf); err != nil {
+		return err
+	}
+	body := buf.Bytes()
+	if len(head) > 0 {
+		body = insertHead(body, head)
+	}
+	if csrf {
+		body = c.csrfForms(body)
+	}
+	if frame != "" {
+		// The full page is sent when the frame is not found so Turbo can
+		// report the missing content
+		if f := extractTurboFrame(body, frame); f != nil {
+			body = f
+		}
+	}
+
 	c.ResponseWriter.WriteHeader(status)
 	c.written = true
-	return node.Render(c.ResponseWriter)
+	_, err := c.ResponseWriter.Write(body)
+	return err
+}
+
+// headTags returns the tags ctx.HTML adds to the page head: the dev
+// reload refresh and the SEO directives
+func (c *Context) headTags() []g.Node {
+	var tags []g.Node
+	if c.server.devReload != nil && c.route != DevReloadPath {
+		tags = append(tags, c.devReloadTag())
+	}
+	if seo, ok := c.applySEO(); ok {
+		tags = append(tags, c.seoTags(seo)...)
+	}
+	return tags
+}
+
+// insertHead inserts tags before the end of the head of a page not built
+// with Page. The first </head> is the real one, as the head comes before
+// any content.
+func insertHead(page []byte, tags []g.Node) []byte {
+	i := bytes.Index(page, []byte("</head>"))
+	if i < 0 {
+		return page
+	}
+	var b bytes.Buffer
+	b.Grow(len(page) + 256)
+	b.Write(page[:i])
+	g.Group(tags).Render(&b)
+	b.Write(page[i:])
+	return b.Bytes()
+}
+
+// keyTitle stores the page title set with ctx.Title
+const keyTitle = "nojs.title"
+
+// Title sets the title of the page rendered with ctx.Render, after the
+// layout's TitlePrefix
+func (c *Context) Title(title string) {
+	c.Set(keyTitle, title)
+}
+
+// Render sends content wrapped in the server's Layout (see
+// ServerConfig.Layout, inherited by mounted servers) as an HTML page:
+//
+//	ctx.Title("Todos")
+//	return ctx.Render(http.StatusOK, todoList)
+func (c *Context) Render(status int, content g.Node) error {
+	var layout Layout
+	for s := c.server; s != nil; s = s.parent {
+		if s.config.Layout != nil {
+			layout = *s.config.Layout
+			break
+		}
+	}
+	title := layout.Title
+	if t := c.GetString(keyTitle); t != "" {
+		title = layout.TitlePrefix + t
+	}
+	return c.HTML(status, layout.page(title, content))
 }
 
 // Text sends a plain text response
@@ -92,6 +266,22 @@ func (c *Context) Redirect(status int, url string) error {
 	return nil
 }
 
+// RedirectBack redirects with 303 See Other to the page the request came
+// from, as the last step of Post/Redirect/Get. The Referer is only
+// followed when it has the same origin as the request, so a forged header
+// cannot send users to another site; fallback is used otherwise. Flashes
+// set before the call are shown on the page redirected to:
+//
+//	ctx.Flash(nojs.FlashSuccess, "Saved")
+//	return ctx.RedirectBack("/todos")
+func (c *Context) RedirectBack(fallback string) error {
+	back, ok := sameOriginReferer(c.Request, c.Scheme())
+	if !ok {
+		back = fallback
+	}
+	return c.Redirect(http.StatusSeeOther, back)
+}
+
 // Stream enables HTTP streaming for real-time updates
 func (c *Context) Stream() (*StreamWriter, error) {
 	if !c.server.config.StreamingEnabled {
@@ -107,45 +297,35 @@ func (c *Context) Stream() (*StreamWriter, error) {
 	c.ResponseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
 	c.ResponseWriter.Header().Set("Cache-Control", "no-cache")
 	c.ResponseWriter.Header().Set("X-Content-Type-Options", "nosniff")
+	c.ResponseWriter.Header().Set("X-Accel-Buffering", "no") // nginx
+
+	if !startStream(c.ResponseWriter) {
+		return nil, WrapHTTPError(http.StatusServiceUnavailable, "Service Unavailable", http.ErrHandlerTimeout)
+	}
+
+	// Streams outlive ServerConfig.WriteTimeout; lift the deadline
+	http.NewResponseController(c.ResponseWriter).SetWriteDeadline(time.Time{})
 
-	return &StreamWriter{
+	sw := &StreamWriter{
 		writer:  c.ResponseWriter,
 		flusher: flusher,
 		context: c,
-	}, nil
-}
-
-// SetFlash sets a flash message (stored in cookie)
-func (c *Context) SetFlash(name, value string) {
-	http.SetCookie(c.ResponseWriter, &http.Cookie{
-		Name:     "flash_" + name,
-		Value:    url.QueryEscape(value),
-		Path:     "/",
-		MaxAge:   60, // 60 seconds
-		HttpOnly: true,
-		SameSite: http.SameSiteStrictMode,
-	})
-}
-
-// GetFlash gets and removes a flash message
-func (c *Context) GetFlash(name string) string {
-	cookie, err := c.Request.Cookie("flash_" + name)
-	if err != nil {
-		return ""
 	}
+	if !c.streamCounted {
+		c.streamCounted = true
+		root := c.server.root()
+		root.streams.Add(1)
+		root.openStreams.Add(1)
+		c.response.streamBytes = &root.streamBytes
+	}
+	c.stream = sw
 
-	// Clear the flash message
-	http.SetCookie(c.ResponseWriter, &http.Cookie{
-		Name:     "flash_" + name,
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-		SameSite: http.SameSiteStrictMode,
-	})
+	if c.server.config.StreamDebug {
+		sw.debug = true
+		log.Printf("stream start req=%s %s %s", c.RequestID(), c.Request.Method, c.Request.URL.Path)
+	}
 
-	value, _ := url.QueryUnescape(cookie.Value)
-	return value
+	return sw, nil
 }
 
 // IsHTMX returns true if the request is from HTMX (for progressive enhancement)
@@ -153,11 +333,12 @@ func (c *Context) IsHTMX() bool {
 	return c.Request.Header.Get("HX-Request") == "true"
 }
 
-// IsJSON returns true if the request expects JSON response
+// IsJSON returns true if the request sends JSON or prefers a JSON
+// response over HTML
 func (c *Context) IsJSON() bool {
-	accept := c.Request.Header.Get("Accept")
 	contentType := c.Request.Header.Get("Content-Type")
-	return strings.Contains(accept, "application/json") || strings.Contains(contentType, "application/json")
+	return strings.HasPrefix(contentType, "application/json") ||
+		c.Accepts("text/html", "application/json") == "application/json"
 }
 
 // Method returns the HTTP method
@@ -176,18 +357,52 @@ type StreamWriter struct {
 	writer  http.ResponseWriter
 	flusher http.Flusher
 	context *Context
+
+	// Whether StartHTML and EndHTML were called, to end documents of
+	// failed streams
+	started bool
+	ended   bool
+
+	// Debug markers, see ServerConfig.StreamDebug
+	debug      bool
+	seq        int
+	bytes      int64
+	lastMarker time.Time
 }
 
 // Write writes data to the stream and flushes immediately
 func (sw *StreamWriter) Write(data []byte) (int, error) {
 	n, err := sw.writer.Write(data)
+	sw.bytes += int64(n)
 	sw.flusher.Flush()
 	return n, err
 }
 
+// boundary is called between complete writes, where a debug marker
+// cannot end up inside a tag or attribute
+func (sw *StreamWriter) boundary() {
+	if sw.debug && time.Since(sw.lastMarker) >= sw.context.server.config.StreamDebugInterval {
+		sw.writeMarker()
+		sw.flusher.Flush()
+	}
+}
+
+// writeMarker embeds the request ID and next sequence number as an HTML
+// comment and logs the same values
+func (sw *StreamWriter) writeMarker() {
+	sw.seq++
+	sw.lastMarker = time.Now()
+	id := sw.context.RequestID()
+	fmt.Fprintf(sw.writer, "<!-- nojs req=%s seq=%d -->\n", id, sw.seq)
+	log.Printf("stream req=%s seq=%d bytes=%d", id, sw.seq, sw.bytes)
+}
+
 // WriteString writes a string to the stream
 func (sw *StreamWriter) WriteString(s string) error {
 	_, err := sw.Write([]byte(s))
+	if err == nil {
+		sw.boundary()
+	}
 	return err
 }
 
@@ -202,6 +417,29 @@ func (sw *StreamWriter) WriteNode(nodes ...g.Node) error {
 		if err := node.Render(sw); err != nil {
 			return err
 		}
+		sw.boundary()
+	}
+	return nil
+}
+
+// Done returns a channel closed when the server shuts down. Streaming
+// loops should select on it and return after calling Close.
+func (sw *StreamWriter) Done() <-chan struct{} {
+	return sw.context.server.root().shutdown
+}
+
+// Close writes the configured shutdown message and ends the document
+func (sw *StreamWriter) Close() error {
+	if err := sw.writeShutdownMessage(); err != nil {
+		return err
+	}
+	return sw.EndHTML()
+}
+
+// writeShutdownMessage writes ServerConfig.StreamShutdownMessage, if any
+func (sw *StreamWriter) writeShutdownMessage() error {
+	if msg := sw.context.server.root().config.StreamShutdownMessage; msg != "" {
+		return sw.WriteString(msg)
 	}
 	return nil
 }
@@ -231,14 +469,47 @@ func (sw *StreamWriter) StartHTML(title string, headNodes ...g.Node) error {
 </head>
 <body>
 `
+	sw.started = true
 	return sw.WriteString(html)
 }
 
 // EndHTML writes the end of an HTML document
 func (sw *StreamWriter) EndHTML() error {
+	sw.ended = true
 	return sw.WriteString("</body>\n</html>\n")
 }
 
+// fail writes the error trailer to a stream whose handler failed, and
+// ends the document if the handler started it, so the client is not
+// left with a page that looks like it is still loading
+func (sw *StreamWriter) fail(err error) {
+	if sw.context.Request.Context().Err() != nil {
+		return // Client gone
+	}
+	trailer := streamErrorTrailer
+	for s := sw.context.server; s != nil; s = s.parent {
+		if s.config.StreamErrorTrailer != nil {
+			trailer = s.config.StreamErrorTrailer
+			break
+		}
+	}
+	if node := trailer(sw.context, err); node != nil {
+		sw.WriteNode(node)
+	}
+	if sw.started && !sw.ended {
+		sw.EndHTML()
+	}
+}
+
+// streamErrorTrailer is the default ServerConfig.StreamErrorTrailer
+func streamErrorTrailer(ctx *Context, err error) g.Node {
+	return h.Div(h.Role("alert"), h.Class("nojs-stream-error"),
+		h.Style("margin: 1rem 0; padding: 0.75rem 1rem; border: 1px solid #b91c1c; background: #fef2f2; color: #7f1d1d; font-family: system-ui, sans-serif"),
+		g.Text("Something went wrong and this page stopped updating. "),
+		g.If(ctx.Request.Method == http.MethodGet, h.A(h.Href(ctx.Request.RequestURI), g.Text("Reload the page"))),
+	)
+}
+
 // StreamPage starts streaming an HTML page with the given configuration
 func (sw *StreamWriter) StreamPage(title string, css []string) error {
 	headNodes := []g.Node{}
@@ -264,6 +535,8 @@ func (sw *StreamWriter) Sleep(duration time.Duration) {
 			sw.KeepAlive()
 		case <-sw.context.Request.Context().Done():
 			return
+		case <-sw.Done():
+			return
 		}
 	}
 }
\ No newline at end of file
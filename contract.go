@@ -0,0 +1,162 @@
+package nojs
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	g "maragu.dev/gomponents"
+)
+
+// FormIssue is a mismatch between a rendered form and the route it
+// submits to, found by CheckForms
+type FormIssue struct {
+	Action  string
+	Method  string
+	Field   string
+	Problem string
+}
+
+func (i FormIssue) String() string {
+	if i.Field != "" {
+		return fmt.Sprintf("%s %s: field %q %s", i.Method, i.Action, i.Field, i.Problem)
+	}
+	return fmt.Sprintf("%s %s: %s", i.Method, i.Action, i.Problem)
+}
+
+// formReads records the form fields each route read through ctx.Form
+// while DevMode is on
+type formReads struct {
+	mu     sync.Mutex
+	fields map[string]map[string]bool
+}
+
+func (s *Server) recordFormRead(route, name string) {
+	s.formReads.mu.Lock()
+	defer s.formReads.mu.Unlock()
+	if s.formReads.fields == nil {
+		s.formReads.fields = make(map[string]map[string]bool)
+	}
+	if s.formReads.fields[route] == nil {
+		s.formReads.fields[route] = make(map[string]bool)
+	}
+	s.formReads.fields[route][name] = true
+}
+
+// frameworkFields are form fields read by nojs itself
+var frameworkFields = map[string]bool{
+	"_method": true, ActionField: true, AnchorField: true, FocusField: true,
+	HoneypotField: true, TimeTrapField: true, CSRFField: true,
+}
+
+var (
+	formPattern  = regexp.MustCompile(`(?s)<form\b([^>]*)>(.*?)</form>`)
+	fieldPattern = regexp.MustCompile(`<(?:input|select|textarea|button)\b([^>]*)>`)
+	attrPattern  = regexp.MustCompile(`(?i)(?:^|\s)(action|method|name|value)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+))`)
+)
+
+// CheckForms renders page and cross-checks every form against the server:
+// the action must match a route, the method must be registered for it, and
+// every field must be known to the handler. Fields are known when the
+// handler read them with ctx.Form or ctx.Bind in DevMode (exercise the
+// routes first, e.g. in a test) or when they appear in the Request struct
+// of a Document'ed operation. Routes with no known fields are not field-checked.
+//
+//	if issues := server.CheckForms(page); len(issues) > 0 {
+//		t.Errorf("form contract: %v", issues)
+//	}
+func (s *Server) CheckForms(page g.Node) []FormIssue {
+	var issues []FormIssue
+	for _, form := range formPattern.FindAllStringSubmatch(renderString(page), -1) {
+		attrs := htmlAttrs(form[1])
+		action, method := attrs["action"], strings.ToUpper(attrs["method"])
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		var fields []string
+		for _, field := range fieldPattern.FindAllStringSubmatch(form[2], -1) {
+			fa := htmlAttrs(field[1])
+			if fa["name"] == "_method" {
+				method = strings.ToUpper(fa["value"])
+			}
+			if fa["name"] != "" {
+				fields = append(fields, fa["name"])
+			}
+		}
+		issues = append(issues, s.checkForm(action, method, fields)...)
+	}
+	return issues
+}
+
+// checkForm checks one form submission against the route table
+func (s *Server) checkForm(action, method string, fields []string) []FormIssue {
+	u, err := url.Parse(action)
+	if err != nil || u.IsAbs() {
+		return nil
+	}
+	req := &http.Request{Method: method, URL: u, Host: u.Host}
+	pattern := s.patternFor(req, u.Path)
+	if pattern == "" {
+		return []FormIssue{{Action: action, Method: method, Problem: "no route matches the action"}}
+	}
+
+	var issues []FormIssue
+	if handlers, ok := s.methods[pattern]; ok {
+		_, allowed := handlers[method]
+		if !allowed {
+			issues = append(issues, FormIssue{Action: action, Method: method,
+				Problem: "method not registered, route allows " + allowedMethods(handlers)})
+		}
+	}
+
+	known := map[string]bool{}
+	s.formReads.mu.Lock()
+	for name := range s.formReads.fields[pattern] {
+		known[name] = true
+	}
+	s.formReads.mu.Unlock()
+	for _, op := range s.apiDocs {
+		if op.Pattern == pattern && op.Method == method && op.Request != nil {
+			t := reflect.TypeOf(op.Request)
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			for i := 0; t.Kind() == reflect.Struct && i < t.NumField(); i++ {
+				if name := fieldName(t.Field(i)); name != "" {
+					known[name] = true
+				}
+			}
+		}
+	}
+	if len(known) == 0 {
+		return issues
+	}
+
+	for _, field := range fields {
+		if !known[field] && !frameworkFields[field] {
+			issues = append(issues, FormIssue{Action: action, Method: method, Field: field,
+				Problem: "is posted but never read by the handler"})
+		}
+	}
+	return issues
+}
+
+// htmlAttrs extracts the attributes CheckForms cares about from a tag
+func htmlAttrs(tag string) map[string]string {
+	attrs := map[string]string{}
+	for _, m := range attrPattern.FindAllStringSubmatch(tag, -1) {
+		// Only one of the double-quoted, single-quoted and unquoted
+		// value groups matched
+		name := strings.ToLower(m[1])
+		if _, seen := attrs[name]; !seen {
+			attrs[name] = html.UnescapeString(m[2] + m[3] + m[4])
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,134 @@
+package nojs
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidCookie is returned for missing, tampered or undecryptable cookies
+var ErrInvalidCookie = errors.New("invalid or missing cookie")
+
+// cookieKeys signs and encrypts cookies with ServerConfig.CookieSecrets;
+// the first secret is used for new cookies and all of them are tried
+// when reading
+type cookieKeys struct {
+	sign    [][]byte
+	sealers []*sealer
+}
+
+func newCookieKeys(secrets []string) *cookieKeys {
+	if len(secrets) == 0 {
+		// Cookies will not survive a restart, which is acceptable for flashes
+		b := make([]byte, 32)
+		rand.Read(b)
+		secrets = []string{hex.EncodeToString(b)}
+	}
+	k := &cookieKeys{}
+	for _, secret := range secrets {
+		mac := sha256.Sum256([]byte("nojs-cookie-sign:" + secret))
+		k.sign = append(k.sign, mac[:])
+		k.sealers = append(k.sealers, newSealer("nojs-cookie-encrypt:"+secret))
+	}
+	return k
+}
+
+// cookieKeys returns the keys of the outermost server, so mounted apps
+// share its secrets
+func (c *Context) cookieKeys() *cookieKeys {
+	root := c.server.root()
+	root.cookieOnce.Do(func() {
+		root.cookies = newCookieKeys(root.config.CookieSecrets)
+	})
+	return root.cookies
+}
+
+func signCookie(key []byte, name, payload string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "=" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SetSignedCookie sets cookie with its value signed, so the client can
+// read but not change it. Path defaults to "/", and HttpOnly and
+// SameSite=Lax are set unless SameSite is given.
+func (c *Context) SetSignedCookie(cookie *http.Cookie) {
+	keys := c.cookieKeys()
+	payload := base64.RawURLEncoding.EncodeToString([]byte(cookie.Value))
+	signed := *cookie
+	signed.Value = payload + "." + signCookie(keys.sign[0], cookie.Name, payload)
+	setSecureCookie(c, &signed)
+}
+
+// GetSignedCookie returns the value of a cookie set with
+// SetSignedCookie, verified against every configured secret
+func (c *Context) GetSignedCookie(name string) (string, error) {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	payload, sig, ok := strings.Cut(cookie.Value, ".")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+	for _, key := range c.cookieKeys().sign {
+		if hmac.Equal([]byte(sig), []byte(signCookie(key, name, payload))) {
+			value, err := base64.RawURLEncoding.DecodeString(payload)
+			if err != nil {
+				return "", ErrInvalidCookie
+			}
+			return string(value), nil
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+// SetEncryptedCookie sets cookie with its value encrypted and
+// authenticated, so the client can neither read nor change it
+func (c *Context) SetEncryptedCookie(cookie *http.Cookie) {
+	sealed := *cookie
+	sealed.Value = c.cookieKeys().sealers[0].seal("cookie:"+cookie.Name, []byte(cookie.Value))
+	setSecureCookie(c, &sealed)
+}
+
+// GetEncryptedCookie returns the value of a cookie set with
+// SetEncryptedCookie, decrypted with any configured secret
+func (c *Context) GetEncryptedCookie(name string) (string, error) {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, s := range c.cookieKeys().sealers {
+		if value, err := s.open("cookie:"+name, cookie.Value); err == nil {
+			return string(value), nil
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+// DeleteCookie expires a cookie set on path "/"
+func (c *Context) DeleteCookie(name string) {
+	http.SetCookie(c.ResponseWriter, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+func setSecureCookie(c *Context, cookie *http.Cookie) {
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == http.SameSiteDefaultMode {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	cookie.HttpOnly = true
+	http.SetCookie(c.ResponseWriter, cookie)
+}
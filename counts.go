@@ -0,0 +1,160 @@
+package nojs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// Total is a possibly approximate item count for pagination
+type Total struct {
+	Count int
+	// Exact is false when Count is a lower bound, i.e. "more than Count"
+	Exact bool
+}
+
+// String formats the total, e.g. "1,234" or "10,000+"
+func (t Total) String() string {
+	s := groupDigits(strconv.Itoa(t.Count))
+	if !t.Exact {
+		s += "+"
+	}
+	return s
+}
+
+// groupDigits inserts thousands separators into a decimal string
+func groupDigits(digits string) string {
+	out := []byte{}
+	for i := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out = append(out, ',')
+		}
+		out = append(out, digits[i])
+	}
+	return string(out)
+}
+
+// CountCache caches expensive totals (COUNT(*) queries) per key, e.g. per
+// filter combination, so paging through a large list costs one count per
+// TTL instead of one per page. The zero value is usable but caches
+// nothing; set TTL.
+type CountCache struct {
+	TTL time.Duration
+	// Threshold caps counting: count functions are asked for at most
+	// Threshold+1 rows and larger totals are shown as "Threshold+"
+	Threshold int
+
+	mu      sync.Mutex
+	entries map[string]countEntry
+}
+
+// maxCountEntries caps a CountCache, whose keys often come from user
+// supplied filters
+const maxCountEntries = 10000
+
+type countEntry struct {
+	total   Total
+	expires time.Time
+}
+
+// NewCountCache creates a cache keeping totals for ttl and counting at
+// most threshold items (0 for no limit)
+func NewCountCache(ttl time.Duration, threshold int) *CountCache {
+	return &CountCache{TTL: ttl, Threshold: threshold, entries: make(map[string]countEntry)}
+}
+
+// Total returns the cached total for key or calls count with the
+// limit to stop counting at (0 for no limit), e.g. a
+// "SELECT COUNT(*) FROM (SELECT 1 FROM items WHERE … LIMIT ?)" query
+func (c *CountCache) Total(ctx context.Context, key string, count func(ctx context.Context, limit int) (int, error)) (Total, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.total, nil
+	}
+
+	limit := 0
+	if c.Threshold > 0 {
+		limit = c.Threshold + 1
+	}
+	n, err := count(ctx, limit)
+	if err != nil {
+		return Total{}, err
+	}
+	total := Total{Count: n, Exact: true}
+	if c.Threshold > 0 && n > c.Threshold {
+		total = Total{Count: c.Threshold}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.entries == nil {
+		c.entries = make(map[string]countEntry)
+	}
+	if len(c.entries) >= maxCountEntries {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		// Still full: evict arbitrary entries, they are only a cache
+		for k := range c.entries {
+			if len(c.entries) < maxCountEntries {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = countEntry{total: total, expires: now.Add(c.TTL)}
+	return total, nil
+}
+
+// Invalidate drops the cached total for key, e.g. after an insert
+func (c *CountCache) Invalidate(key string) {
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
+
+// VirtualPagination renders pagination when the total may be unknown or
+// approximate. hasNext reports whether the current page was full (fetch
+// perPage+1 rows to know). An exact total gets the numbered Pagination;
+// otherwise only Previous, the current page, the total as a lower bound
+// and Next are shown.
+func VirtualPagination(currentPage, perPage int, total Total, hasNext bool, baseURL string) g.Node {
+	if total.Exact {
+		return Pagination(currentPage, (total.Count+perPage-1)/perPage, baseURL)
+	}
+
+	var items []g.Node
+	link := func(page int, label string) g.Node {
+		return h.Li(h.Class("page-item"),
+			h.A(h.Class("page-link"), h.Href(fmt.Sprintf("%s?page=%d", baseURL, page)), g.Text(label)),
+		)
+	}
+
+	if currentPage > 1 {
+		items = append(items, link(currentPage-1, "Previous"))
+	}
+	items = append(items, h.Li(h.Class("page-item active"),
+		h.Span(h.Class("page-link"), g.Textf("Page %d", currentPage)),
+	))
+	if total.Count > 0 {
+		items = append(items, h.Li(h.Class("page-item disabled"),
+			h.Span(h.Class("page-link"), g.Textf("of %s items", total)),
+		))
+	}
+	if hasNext {
+		items = append(items, link(currentPage+1, "Next"))
+	}
+
+	paginationItems := append([]g.Node{h.Class("pagination")}, items...)
+	return h.Nav(h.Ul(paginationItems...))
+}
@@ -0,0 +1,160 @@
+package nojs
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// CSRF field, header and cookie names
+const (
+	CSRFField  = "_csrf"
+	CSRFHeader = "X-CSRF-Token"
+	CSRFCookie = "nojs_csrf"
+)
+
+// CSRFConfig configures the CSRF middleware
+type CSRFConfig struct {
+	// Exempt lists path prefixes not checked, e.g. webhook endpoints
+	Exempt []string
+	// ErrorPage renders the 403 response for rejected requests
+	ErrorPage func(ctx *Context) error
+}
+
+// CSRF middleware protects unsafe requests (POST, PUT, PATCH, DELETE)
+// against cross-site request forgery. Each client gets a random token,
+// kept in its session when the session middleware runs first and in a
+// cookie otherwise. Every POST form in an HTML response from ctx.HTML,
+// whether built with Form or by hand, gets the token as a hidden field;
+// for streamed forms add ctx.CSRFField yourself. Requests without the
+// matching token get a 403 page.
+func CSRF(config ...CSRFConfig) Middleware {
+	var cfg CSRFConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.ErrorPage == nil {
+		cfg.ErrorPage = csrfErrorPage
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			token := ctx.csrfToken()
+			ctx.Set(keyCSRF, token)
+
+			switch ctx.Request.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+				return next(ctx)
+			}
+			for _, prefix := range cfg.Exempt {
+				if strings.HasPrefix(ctx.Request.URL.Path, prefix) {
+					return next(ctx)
+				}
+			}
+
+			sent := ctx.Request.Header.Get(CSRFHeader)
+			if sent == "" {
+				// Upload forms are parsed here so a body over the limit
+				// is reported as such instead of as a missing token
+				if strings.HasPrefix(ctx.Request.Header.Get("Content-Type"), "multipart/form-data") {
+					if err := ctx.Request.ParseMultipartForm(multipartMemory); err != nil {
+						var maxErr *http.MaxBytesError
+						if errors.As(err, &maxErr) {
+							return WrapHTTPError(http.StatusRequestEntityTooLarge, "Request too large", err)
+						}
+						return WrapHTTPError(http.StatusBadRequest, "Invalid form", err)
+					}
+				}
+				sent = ctx.Request.PostFormValue(CSRFField)
+			}
+			if sent == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				return cfg.ErrorPage(ctx)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// csrfToken returns the client's token, creating it on first use
+func (c *Context) csrfToken() string {
+	if session := GetSession(c); session != nil {
+		if token, ok := session.Get(CSRFField).(string); ok && token != "" {
+			return token
+		}
+		token := newSessionID()
+		session.Set(CSRFField, token)
+		return token
+	}
+
+	if cookie, err := c.Request.Cookie(CSRFCookie); err == nil && len(cookie.Value) == 64 {
+		return cookie.Value
+	}
+	token := newSessionID()
+	http.SetCookie(c.ResponseWriter, &http.Cookie{
+		Name:     CSRFCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token
+}
+
+// CSRFToken returns the token for the request, or "" when the CSRF
+// middleware is not in use
+func (c *Context) CSRFToken() string {
+	token := c.GetString(keyCSRF)
+	if token != "" && GetSession(c) != nil {
+		// Session.Regenerate replaces the token, e.g. on login
+		return c.csrfToken()
+	}
+	return token
+}
+
+// CSRFField renders the hidden token field for forms that are not sent
+// through ctx.HTML, e.g. streamed ones
+func (c *Context) CSRFField() g.Node {
+	token := c.CSRFToken()
+	if token == "" {
+		return nil
+	}
+	return h.Input(h.Type("hidden"), h.Name(CSRFField), h.Value(token))
+}
+
+// formTag matches opening form tags
+var formTag = regexp.MustCompile(`(?i)<form\b[^>]*>`)
+
+// csrfForms inserts the token field into the POST forms of a rendered
+// page, except forms submitting to other sites
+func (c *Context) csrfForms(page []byte) []byte {
+	field := []byte(renderString(c.CSRFField()))
+	return formTag.ReplaceAllFunc(page, func(tag []byte) []byte {
+		attrs := htmlAttrs(string(tag[len("<form"):]))
+		if !strings.EqualFold(attrs["method"], "post") {
+			return tag
+		}
+		action := attrs["action"]
+		if strings.Contains(action, "://") || strings.HasPrefix(action, "//") {
+			return tag
+		}
+		return append(tag[:len(tag):len(tag)], field...)
+	})
+}
+
+// csrfErrorPage is the default response for rejected requests
+func csrfErrorPage(ctx *Context) error {
+	return ctx.HTML(http.StatusForbidden, Page{
+		Title: "Form expired",
+		Body: h.Main(h.Class("error-page"),
+			h.H1(g.Text("This form has expired")),
+			h.P(g.Text("For your security the submission was rejected. Go back, reload the page and try again.")),
+			h.P(h.A(h.Href(localReferer(ctx.Request)), g.Text("Back to the form"))),
+		),
+	}.Render())
+}
@@ -0,0 +1,86 @@
+package nojs
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+)
+
+// csvFlushRows is how many rows CSVWriter buffers before flushing them
+// to the client
+const csvFlushRows = 100
+
+// CSV sends rows as a CSV response, with headers as the first row when
+// given. Set a Content-Disposition header first to make browsers
+// download it as a file. For large exports, write rows as they are read
+// with CSVWriter instead.
+func (c *Context) CSV(status int, headers []string, rows [][]string) error {
+	cw, err := c.CSVWriter(status, headers)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	return cw.Flush()
+}
+
+// CSVWriter writes CSV rows to the response as they are produced, e.g.
+// while iterating over database rows, so exports start downloading
+// immediately and never sit in memory whole
+type CSVWriter struct {
+	w    *csv.Writer
+	rc   *http.ResponseController
+	rows int
+}
+
+// CSVWriter starts a CSV response, writing headers as the first row when
+// given. Call Flush when done.
+//
+//	cw, err := ctx.CSVWriter(http.StatusOK, []string{"id", "title"})
+//	for rows.Next() {
+//		...
+//		if err := cw.Write([]string{id, title}); err != nil {
+//			return err
+//		}
+//	}
+//	return cw.Flush()
+func (c *Context) CSVWriter(status int, headers []string) (*CSVWriter, error) {
+	c.ResponseWriter.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	c.ResponseWriter.WriteHeader(status)
+	c.written = true
+	cw := &CSVWriter{w: csv.NewWriter(c.ResponseWriter), rc: http.NewResponseController(c.ResponseWriter)}
+	if len(headers) > 0 {
+		if err := cw.w.Write(headers); err != nil {
+			return nil, err
+		}
+	}
+	return cw, nil
+}
+
+// Write writes a row, sending buffered rows to the client every
+// hundred rows
+func (cw *CSVWriter) Write(row []string) error {
+	if err := cw.w.Write(row); err != nil {
+		return err
+	}
+	cw.rows++
+	if cw.rows%csvFlushRows == 0 {
+		return cw.Flush()
+	}
+	return nil
+}
+
+// Flush sends buffered rows to the client
+func (cw *CSVWriter) Flush() error {
+	cw.w.Flush()
+	if err := cw.w.Error(); err != nil {
+		return err
+	}
+	if err := cw.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package nojs
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// Query parameters carrying pagination cursors
+const (
+	CursorAfterParam  = "after"
+	CursorBeforeParam = "before"
+)
+
+// ErrInvalidCursor is returned for malformed cursors
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in a list ordered by time and ID. Unlike offsets,
+// cursors stay correct when items are added while paging, e.g. in chat
+// history.
+type Cursor struct {
+	Time time.Time
+	ID   string
+	// Backward is set when the cursor came from a "before" link, i.e. the
+	// previous page is requested
+	Backward bool
+}
+
+// IsZero reports whether the cursor is empty, i.e. the first page
+func (c Cursor) IsZero() bool {
+	return c.ID == "" && c.Time.IsZero()
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c Cursor) Encode() string {
+	payload := strconv.FormatInt(c.Time.UnixNano(), 36) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(payload))
+}
+
+// ParseCursor decodes a string made by Cursor.Encode
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(ts, 36, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{Time: time.Unix(0, nanos), ID: id}, nil
+}
+
+// Cursor returns the pagination cursor of the request from the "after" or
+// "before" parameter. Missing or malformed cursors give the zero Cursor,
+// i.e. the first page.
+func (c *Context) Cursor() Cursor {
+	if after := c.Query(CursorAfterParam); after != "" {
+		if cursor, err := ParseCursor(after); err == nil {
+			return cursor
+		}
+	}
+	if before := c.Query(CursorBeforeParam); before != "" {
+		if cursor, err := ParseCursor(before); err == nil {
+			cursor.Backward = true
+			return cursor
+		}
+	}
+	return Cursor{}
+}
+
+// CursorPagination renders Previous/Next links for cursor pagination.
+// prev is the first item shown and next the last; pass a zero Cursor to
+// omit a link at either end of the list.
+func CursorPagination(baseURL string, prev, next Cursor) g.Node {
+	if prev.IsZero() && next.IsZero() {
+		return nil
+	}
+
+	sep := "?"
+	if strings.Contains(baseURL, "?") {
+		sep = "&"
+	}
+
+	var items []g.Node
+	if !prev.IsZero() {
+		items = append(items, h.Li(h.Class("page-item"),
+			h.A(h.Class("page-link"), h.Rel("prev"), h.Href(baseURL+sep+CursorBeforeParam+"="+prev.Encode()), g.Text("Previous")),
+		))
+	}
+	if !next.IsZero() {
+		items = append(items, h.Li(h.Class("page-item"),
+			h.A(h.Class("page-link"), h.Rel("next"), h.Href(baseURL+sep+CursorAfterParam+"="+next.Encode()), g.Text("Next")),
+		))
+	}
+
+	paginationItems := append([]g.Node{h.Class("pagination cursor-pagination")}, items...)
+	return h.Nav(h.Ul(paginationItems...))
+}
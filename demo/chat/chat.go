@@ -90,10 +90,8 @@ func (c *ChatDemo) RegisterRoutes(server *nojs.Server, prefix string) {
 }
 
 func (c *ChatDemo) chatPageHandler(ctx *nojs.Context) error {
-	username := ""
-	if cookie, err := ctx.Request.Cookie("chat_username"); err == nil {
-		username = cookie.Value
-	}
+	// Signed, so nobody can pose as someone else by editing the cookie
+	username, _ := ctx.GetSignedCookie("chat_username")
 
 	page := nojs.Page{
 		Title: "Global Chat - NoJS Demo",
@@ -349,12 +347,10 @@ func (c *ChatDemo) sendMessageHandler(ctx *nojs.Context) error {
 		return ctx.Redirect(http.StatusSeeOther, c.prefix)
 	}
 	
-	sessionID := ""
-	if cookie, err := ctx.Request.Cookie("chat_session"); err == nil {
-		sessionID = cookie.Value
-	} else {
+	sessionID, err := ctx.GetSignedCookie("chat_session")
+	if err != nil {
 		sessionID = strconv.FormatInt(time.Now().UnixNano(), 36)
-		http.SetCookie(ctx.ResponseWriter, &http.Cookie{
+		ctx.SetSignedCookie(&http.Cookie{
 			Name:     "chat_session",
 			Value:    sessionID,
 			Path:     "/",
@@ -364,7 +360,7 @@ func (c *ChatDemo) sendMessageHandler(ctx *nojs.Context) error {
 		})
 	}
 	
-	http.SetCookie(ctx.ResponseWriter, &http.Cookie{
+	ctx.SetSignedCookie(&http.Cookie{
 		Name:     "chat_username",
 		Value:    username,
 		Path:     "/",
@@ -8,3 +8,9 @@ require (
     github.com/jairo/mavis/nojs v0.0.0-00010101000000-000000000000
     maragu.dev/gomponents v1.1.0
 )
+
+require (
+	golang.org/x/crypto v0.33.0 // indirect
+	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+)
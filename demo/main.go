@@ -98,10 +98,8 @@ func main() {
 
 // Main chat page with iframe for messages (no streaming needed)
 func chatPageHandler(ctx *nojs.Context) error {
-	username := ""
-	if cookie, err := ctx.Request.Cookie("chat_username"); err == nil {
-		username = cookie.Value
-	}
+	// Signed, so nobody can pose as someone else by editing the cookie
+	username, _ := ctx.GetSignedCookie("chat_username")
 
 	page := nojs.Page{
 		Title: "Global Chat - NoJS Demo",
@@ -369,13 +367,11 @@ func sendMessageHandler(ctx *nojs.Context) error {
 	}
 	
 	// Get or create user session ID from cookie
-	sessionID := ""
-	if cookie, err := ctx.Request.Cookie("chat_session"); err == nil {
-		sessionID = cookie.Value
-	} else {
+	sessionID, err := ctx.GetSignedCookie("chat_session")
+	if err != nil {
 		// Create new session ID
 		sessionID = strconv.FormatInt(time.Now().UnixNano(), 36)
-		http.SetCookie(ctx.ResponseWriter, &http.Cookie{
+		ctx.SetSignedCookie(&http.Cookie{
 			Name:     "chat_session",
 			Value:    sessionID,
 			Path:     "/",
@@ -386,7 +382,7 @@ func sendMessageHandler(ctx *nojs.Context) error {
 	}
 	
 	// Set username cookie
-	http.SetCookie(ctx.ResponseWriter, &http.Cookie{
+	ctx.SetSignedCookie(&http.Cookie{
 		Name:     "chat_username",
 		Value:    username,
 		Path:     "/",
@@ -0,0 +1,153 @@
+package nojs
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// DevReloadPath is the long-poll endpoint pages wait on in DevMode
+const DevReloadPath = "/_nojs/reload"
+
+// devReload tracks a version that changes whenever a watched file does
+// or the process restarts
+type devReload struct {
+	mu      sync.Mutex
+	boot    string
+	version string
+	changed chan struct{}
+}
+
+func newDevReload() *devReload {
+	boot := strconv.FormatInt(time.Now().UnixNano(), 36)
+	return &devReload{boot: boot, version: boot, changed: make(chan struct{})}
+}
+
+// current returns the version and a channel closed when it changes
+func (d *devReload) current() (string, <-chan struct{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.version, d.changed
+}
+
+// watch polls dirs for modified, added or removed files until stop is
+// closed. Polling avoids a file notification dependency and is cheap for
+// project-sized trees.
+func (d *devReload) watch(dirs []string, stop <-chan struct{}) {
+	last := fingerprint(dirs)
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if fp := fingerprint(dirs); fp != last {
+			last = fp
+			d.mu.Lock()
+			d.version = d.boot + "." + fp
+			close(d.changed)
+			d.changed = make(chan struct{})
+			d.mu.Unlock()
+		}
+	}
+}
+
+// fingerprint hashes the names, sizes and modification times of the files
+// under dirs, skipping hidden directories such as .git
+func fingerprint(dirs []string) string {
+	hash := fnv.New64a()
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			name := entry.Name()
+			if entry.IsDir() && path != dir && (strings.HasPrefix(name, ".") || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			if info, err := entry.Info(); err == nil && !entry.IsDir() {
+				fmt.Fprintf(hash, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+			}
+			return nil
+		})
+	}
+	return strconv.FormatUint(hash.Sum64(), 36)
+}
+
+// startDevReload starts watching ServerConfig.DevWatchDirs
+func (s *Server) startDevReload() {
+	if s.devReload == nil {
+		return
+	}
+	dirs := s.config.DevWatchDirs
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	go s.devReload.watch(dirs, s.root().shutdown)
+}
+
+// devReloadTag returns the meta refresh sending the page to the reload
+// endpoint. The browser keeps showing the page while the endpoint waits
+// and comes back to it once something changed.
+func (c *Context) devReloadTag() g.Node {
+	version, _ := c.server.devReload.current()
+	target := c.server.URL(DevReloadPath) + "?" + url.Values{
+		"v":    {version},
+		"back": {c.Request.URL.RequestURI()},
+	}.Encode()
+	return AutoRefreshAnchor(1, target, "")
+}
+
+// devReloadPolls is how many timed out polls are chained through
+// redirects to the endpoint itself, staying below the redirect limit of
+// browsers, before the page is reloaded anyway
+const devReloadPolls = 15
+
+// devReloadHandler waits until the watched files change or the server
+// restarts, then sends the browser back to the page. When the wait times
+// out it polls again through a redirect to itself, which the browser
+// follows without leaving the page.
+func (s *Server) devReloadHandler(ctx *Context) error {
+	back := ctx.Query("back")
+	if !strings.HasPrefix(back, "/") || strings.HasPrefix(back, "//") {
+		back = "/"
+	}
+
+	version, changed := s.devReload.current()
+	if ctx.Query("v") != version {
+		return ctx.Redirect(http.StatusSeeOther, back)
+	}
+
+	http.NewResponseController(ctx.ResponseWriter).SetWriteDeadline(time.Time{})
+	select {
+	case <-changed:
+	case <-time.After(time.Minute):
+		if polls, _ := strconv.Atoi(ctx.Query("n")); polls < devReloadPolls {
+			q := ctx.Request.URL.Query()
+			q.Set("n", strconv.Itoa(polls+1))
+			return ctx.Redirect(http.StatusSeeOther, s.URL(DevReloadPath)+"?"+q.Encode())
+		}
+	case <-ctx.Request.Context().Done():
+		return nil
+	case <-s.root().shutdown:
+		// Retry until the restarted server answers
+		return ctx.HTML(http.StatusServiceUnavailable, h.HTML(
+			h.Head(AutoRefreshAnchor(1, back, "")),
+			h.Body(h.P(g.Text("Reloading…"))),
+		))
+	}
+	return ctx.Redirect(http.StatusSeeOther, back)
+}
@@ -0,0 +1,117 @@
+package nojs
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	g "maragu.dev/gomponents"
+)
+
+// EarlyHints sends a 103 Early Hints response listing links, so the
+// browser starts fetching them while the handler is still building the
+// page. Each link is a URL, preloaded with the destination guessed from
+// its extension, an origin such as "https://cdn.example.com", which is
+// preconnected, or a complete Link header value starting with "<". The
+// links are also sent with the final response. It does nothing once the
+// response has started or for HTTP/1.0 clients, which do not understand
+// informational responses.
+//
+//	ctx.EarlyHints("/static/app.css", "/static/logo.svg")
+//	posts, err := slowQuery(ctx)
+func (c *Context) EarlyHints(links ...string) error {
+	if len(links) == 0 || c.HeadersSent() || !c.Request.ProtoAtLeast(1, 1) {
+		return nil
+	}
+	c.addLinks(links)
+	c.ResponseWriter.WriteHeader(http.StatusEarlyHints)
+	return nil
+}
+
+// addLinks adds a Link header for each of links not already listed
+func (c *Context) addLinks(links []string) {
+	header := c.ResponseWriter.Header()
+	existing := header.Values("Link")
+	for _, link := range links {
+		value := linkValue(link)
+		if !Contains(existing, value) {
+			header.Add("Link", value)
+			existing = append(existing, value)
+		}
+	}
+}
+
+// linkValue turns a URL into a preload or preconnect Link header value
+func linkValue(link string) string {
+	if strings.HasPrefix(link, "<") {
+		return link
+	}
+	if u, err := url.Parse(link); err == nil && u.Host != "" && strings.Trim(u.Path, "/") == "" {
+		return "<" + link + ">; rel=preconnect"
+	}
+
+	value := "<" + link + ">; rel=preload"
+	ext := strings.ToLower(path.Ext(strings.SplitN(link, "?", 2)[0]))
+	switch ext {
+	case ".css":
+		value += "; as=style"
+	case ".js", ".mjs":
+		value += "; as=script"
+	case ".woff2", ".woff", ".ttf", ".otf":
+		// Fonts are always fetched in CORS mode
+		value += "; as=font; crossorigin"
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp", ".avif", ".svg", ".ico":
+		value += "; as=image"
+	default:
+		value += "; as=fetch; crossorigin"
+	}
+	return value
+}
+
+// informational reports whether status is a 1xx response that precedes
+// the real one, such as 103 Early Hints
+func informational(status int) bool {
+	return status >= 100 && status < 200 && status != http.StatusSwitchingProtocols
+}
+
+// pageNode is a page rendered by Page.Render, remembering the Page so
+// ctx.HTML can preload its stylesheets and add to its head
+type pageNode struct {
+	g.Node
+	page  Page
+	nodes []g.Node
+}
+
+// withHead renders the page again with tags added to its head
+func (p pageNode) withHead(tags []g.Node) g.Node {
+	page := p.page
+	page.Head = append(append([]g.Node(nil), page.Head...), tags...)
+	return page.Render(p.nodes...)
+}
+
+// preloadPage adds preload Link headers for the stylesheets of a page
+// rendered by Page.Render and remembers them for the route, so that with
+// ServerConfig.EarlyHints the next request for it gets them in a 103
+// response before the handler runs
+func (c *Context) preloadPage(node g.Node) {
+	page, ok := node.(pageNode)
+	if !ok || len(page.page.CSS) == 0 {
+		return
+	}
+	c.addLinks(page.page.CSS)
+	if c.route != "" {
+		c.server.hints.Store(c.route, page.page.CSS)
+	}
+}
+
+// sendEarlyHints sends the stylesheets remembered for the route of a GET
+// request in a 103 response
+func (c *Context) sendEarlyHints() {
+	if c.Request.Method != http.MethodGet {
+		return
+	}
+	if css, ok := c.server.hints.Load(c.route); ok {
+		c.EarlyHints(css.([]string)...)
+	}
+}
@@ -1,6 +1,10 @@
 package nojs
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
 
 // HTTPError represents an HTTP error with status code
 type HTTPError struct {
@@ -37,4 +41,14 @@ func WrapHTTPError(code int, message string, err error) *HTTPError {
 		Message: message,
 		Err:     err,
 	}
-}
\ No newline at end of file
+}
+
+// errorStatus returns the status handleError sends for err: the code of
+// the HTTPError it wraps, or 500
+func errorStatus(err error) int {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
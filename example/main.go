@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 
@@ -48,13 +50,29 @@ var (
 )
 
 func main() {
-	// Initialize with some demo data
-	addTodo("Build a web app without JavaScript")
-	addTodo("Learn about HTML streaming")
-	addTodo("Master server-side rendering")
-
-	// Create server
-	server := nojs.NewServer()
+	// Create server; ctx.Render wraps pages in the layout
+	config := nojs.DefaultServerConfig()
+	// NOJS_DEV=1 reloads pages on changes; never set it in production
+	config.DevMode = os.Getenv("NOJS_DEV") == "1"
+	config.Layout = &nojs.Layout{
+		Title:       "NoJS Example",
+		TitlePrefix: "NoJS Example - ",
+		CSS:         []string{"/static/style.css"},
+	}
+	server := nojs.NewServer(config)
+
+	// Demo data
+	err := nojs.LoadFixtures(context.Background(), nojs.FixtureItems("todos", []string{
+		"Build a web app without JavaScript",
+		"Learn about HTML streaming",
+		"Master server-side rendering",
+	}, func(ctx context.Context, text string) error {
+		addTodo(text)
+		return nil
+	}))
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Add middleware
 	server.Use(nojs.Logger())
@@ -63,11 +81,11 @@ func main() {
 	// Routes
 	server.Route("/", handleIndex)
 	server.Route("/todos", handleTodos)
-	server.Route("/todos/add", handleAddTodo)
-	server.Route("/todos/toggle", handleToggleTodo)
-	server.Route("/todos/delete", handleDeleteTodo)
+	server.POST("/todos/add", handleAddTodo)
+	server.POST("/todos/toggle", handleToggleTodo)
+	server.POST("/todos/delete", handleDeleteTodo)
 	server.Route("/chat", handleChat)
-	server.Route("/chat/send", handleChatSend)
+	server.POST("/chat/send", handleChatSend)
 	server.Route("/chat/stream", handleChatStream)
 
 	// Static files
@@ -93,13 +111,7 @@ func handleIndex(ctx *nojs.Context) error {
 		),
 	)
 
-	page := nojs.Page{
-		Title: "NoJS Example",
-		CSS:   []string{"/static/style.css"},
-		Body:  content,
-	}
-
-	return ctx.HTML(200, page.Render())
+	return ctx.Render(200, content)
 }
 
 func handleTodos(ctx *nojs.Context) error {
@@ -133,20 +145,11 @@ func handleTodos(ctx *nojs.Context) error {
 		nojs.AutoRefresh(10),
 	)
 
-	page := nojs.Page{
-		Title: "Todo List - NoJS Example",
-		CSS:   []string{"/static/style.css"},
-		Body:  content,
-	}
-
-	return ctx.HTML(200, page.Render())
+	ctx.Title("Todo List")
+	return ctx.Render(200, content)
 }
 
 func handleAddTodo(ctx *nojs.Context) error {
-	if ctx.Method() != "POST" {
-		return nojs.NewHTTPError(405, "Method not allowed")
-	}
-
 	text := ctx.Form("text")
 	if text == "" {
 		ctx.SetFlash("error", "Todo text is required")
@@ -159,12 +162,7 @@ func handleAddTodo(ctx *nojs.Context) error {
 }
 
 func handleToggleTodo(ctx *nojs.Context) error {
-	if ctx.Method() != "POST" {
-		return nojs.NewHTTPError(405, "Method not allowed")
-	}
-
-	id := 0
-	fmt.Sscanf(ctx.Form("id"), "%d", &id)
+	id := ctx.FormIntOr("id", 0)
 
 	if todo, exists := todos[id]; exists {
 		todo.Completed = !todo.Completed
@@ -179,12 +177,7 @@ func handleToggleTodo(ctx *nojs.Context) error {
 }
 
 func handleDeleteTodo(ctx *nojs.Context) error {
-	if ctx.Method() != "POST" {
-		return nojs.NewHTTPError(405, "Method not allowed")
-	}
-
-	id := 0
-	fmt.Sscanf(ctx.Form("id"), "%d", &id)
+	id := ctx.FormIntOr("id", 0)
 
 	if _, exists := todos[id]; exists {
 		delete(todos, id)
@@ -198,32 +191,21 @@ func handleChat(ctx *nojs.Context) error {
 	// Check if user wants to change username
 	if ctx.Query("change") == "1" {
 		// Clear username cookie
-		http.SetCookie(ctx.ResponseWriter, &http.Cookie{
-			Name:   "chat_username",
-			Value:  "",
-			Path:   "/",
-			MaxAge: -1,
-		})
+		ctx.DeleteCookie("chat_username")
 		return ctx.Redirect(303, "/chat")
 	}
 
 	// Get username from cookie
-	username := ""
-	cookie, err := ctx.Request.Cookie("chat_username")
-	if err == nil {
-		username = cookie.Value
-	}
+	username, _ := ctx.GetSignedCookie("chat_username")
 
 	// Handle username submission
 	if ctx.Method() == "POST" && username == "" {
 		username = ctx.Form("username")
 		if username != "" {
-			http.SetCookie(ctx.ResponseWriter, &http.Cookie{
-				Name:     "chat_username",
-				Value:    username,
-				Path:     "/",
-				MaxAge:   86400, // 24 hours
-				HttpOnly: true,
+			ctx.SetSignedCookie(&http.Cookie{
+				Name:   "chat_username",
+				Value:  username,
+				MaxAge: 86400, // 24 hours
 			})
 			return ctx.Redirect(303, "/chat")
 		}
@@ -278,20 +260,11 @@ func handleChat(ctx *nojs.Context) error {
 		bodyContent,
 	)
 
-	page := nojs.Page{
-		Title: "Chat Room - NoJS Example",
-		CSS:   []string{"/static/style.css"},
-		Body:  content,
-	}
-
-	return ctx.HTML(200, page.Render())
+	ctx.Title("Chat Room")
+	return ctx.Render(200, content)
 }
 
 func handleChatSend(ctx *nojs.Context) error {
-	if ctx.Method() != "POST" {
-		return nojs.NewHTTPError(405, "Method not allowed")
-	}
-
 	username := ctx.Form("username")
 	message := ctx.Form("message")
 
@@ -303,12 +276,10 @@ func handleChatSend(ctx *nojs.Context) error {
 	chatRoom.AddMessage(username, message)
 
 	// Set username cookie
-	http.SetCookie(ctx.ResponseWriter, &http.Cookie{
-		Name:     "chat_username",
-		Value:    username,
-		Path:     "/",
-		MaxAge:   86400, // 24 hours
-		HttpOnly: true,
+	ctx.SetSignedCookie(&http.Cookie{
+		Name:   "chat_username",
+		Value:  username,
+		MaxAge: 86400, // 24 hours
 	})
 
 	return ctx.Redirect(303, "/chat")
@@ -0,0 +1,111 @@
+package nojs
+
+import (
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// FeedEvent is one entry of an activity feed
+type FeedEvent struct {
+	Type  string // Consecutive events of the same Type and Group coalesce
+	Group string // e.g. the commented post's ID
+	Actor string
+	Text  string
+	Link  string
+	Time  time.Time
+}
+
+// FeedConfig configures an ActivityFeed
+type FeedConfig struct {
+	// Summary describes a run of coalesced events, e.g.
+	// func(e FeedEvent, n int) string { return Pluralize(n, "new comment", "new comments") }.
+	// Events are not coalesced when nil.
+	Summary func(first FeedEvent, count int) string
+	// Now is the reference time for "Today"/"Yesterday", defaults to time.Now()
+	Now time.Time
+}
+
+// ActivityFeed renders events (newest first) grouped by day with
+// separators, coalescing similar consecutive events
+func ActivityFeed(events []FeedEvent, config FeedConfig) g.Node {
+	if config.Now.IsZero() {
+		config.Now = time.Now()
+	}
+
+	items := []g.Node{h.Class("feed")}
+	day := ""
+	for i := 0; i < len(events); {
+		event := events[i]
+
+		// Gather the run of similar events on the same day
+		j := i + 1
+		if config.Summary != nil {
+			for j < len(events) && events[j].Type == event.Type && events[j].Group == event.Group &&
+				feedDay(events[j].Time, config.Now) == feedDay(event.Time, config.Now) {
+				j++
+			}
+		}
+
+		if d := feedDay(event.Time, config.Now); d != day {
+			day = d
+			items = append(items, h.Li(h.Class("feed-day"), g.Attr("role", "separator"), g.Text(day)))
+		}
+
+		if j-i > 1 {
+			items = append(items, feedItem(FeedEvent{
+				Type: event.Type,
+				Text: config.Summary(event, j-i),
+				Link: event.Link,
+				Time: event.Time,
+			}, "feed-item feed-coalesced"))
+		} else {
+			items = append(items, feedItem(event, "feed-item"))
+		}
+		i = j
+	}
+
+	return h.Ul(items...)
+}
+
+// FeedItem renders a single event, e.g. for live appends
+func FeedItem(event FeedEvent) g.Node {
+	return feedItem(event, "feed-item")
+}
+
+func feedItem(event FeedEvent, class string) g.Node {
+	var text g.Node = g.Text(event.Text)
+	if event.Link != "" {
+		text = h.A(h.Href(event.Link), g.Text(event.Text))
+	}
+
+	return h.Li(h.Class(class+" feed-"+event.Type),
+		g.If(event.Actor != "", h.Strong(h.Class("feed-actor"), g.Text(event.Actor+" "))),
+		text,
+		h.Time(h.DateTime(event.Time.Format(time.RFC3339)), h.Class("feed-time"), g.Text(FormatTime(event.Time))),
+	)
+}
+
+// feedDay returns the separator label for t
+func feedDay(t, now time.Time) string {
+	y1, m1, d1 := t.Date()
+	y2, m2, d2 := now.Date()
+	if y1 == y2 && m1 == m2 && d1 == d2 {
+		return "Today"
+	}
+	y3, m3, d3 := now.AddDate(0, 0, -1).Date()
+	if y1 == y3 && m1 == m3 && d1 == d3 {
+		return "Yesterday"
+	}
+	return FormatDate(t)
+}
+
+// PublishFeedEvent renders event and publishes it to a Hub room, so
+// viewers following the room with StreamWriter.Follow see it appended
+func PublishFeedEvent(hub *Hub, room string, event FeedEvent) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	return hub.PublishNode(room, "feed."+event.Type, FeedItem(event))
+}
@@ -0,0 +1,127 @@
+package nojs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// File sends the file at path with a Content-Type from its extension (or
+// sniffed from its contents), an ETag and Last-Modified for conditional
+// requests, and Range support for resumable downloads and media seeking.
+// Unlike SendFile, path is used as given; never build it from unchecked
+// user input.
+func (c *Context) File(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fileError(err)
+	}
+	defer f.Close()
+	return c.serveFile(filepath.Base(path), f)
+}
+
+// FileFromFS sends the file name from fsys, e.g. an embed.FS, like File
+func (c *Context) FileFromFS(fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return fileError(err)
+	}
+	defer f.Close()
+	return c.serveFile(path.Base(name), f)
+}
+
+// Attachment sends the file at path like File, but asks the browser to
+// download it and save it as downloadName
+func (c *Context) Attachment(path, downloadName string) error {
+	c.ResponseWriter.Header().Set("Content-Disposition", contentDisposition("attachment", downloadName))
+	return c.File(path)
+}
+
+// serveFile sends an open file through http.ServeContent, which handles
+// Range, If-Range, If-None-Match and If-Modified-Since
+func (c *Context) serveFile(name string, f fs.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fileError(err)
+	}
+	if info.IsDir() {
+		return NewHTTPError(http.StatusNotFound, "Not Found")
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return WrapHTTPError(http.StatusInternalServerError, "Internal Server Error", err)
+		}
+		content = bytes.NewReader(data)
+	}
+
+	header := c.ResponseWriter.Header()
+	if header.Get("Content-Type") == "" {
+		if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+			header.Set("Content-Type", ctype)
+		}
+	}
+	if header.Get("ETag") == "" {
+		etag, err := fileETag(info, content)
+		if err != nil {
+			return WrapHTTPError(http.StatusInternalServerError, "Internal Server Error", err)
+		}
+		header.Set("ETag", etag)
+	}
+
+	http.ServeContent(c.ResponseWriter, c.Request, name, info.ModTime(), content)
+	c.written = true
+	return nil
+}
+
+// fileETag returns a strong ETag, so that If-Range works for resumed
+// downloads. It is built from the modification time and size, or hashed
+// from the content for files without a modification time, such as those
+// of an embed.FS, which would otherwise keep their ETag across releases.
+func fileETag(info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()), nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:8]) + `"`, nil
+}
+
+// fileError maps a file open or stat error to an HTTP error
+func fileError(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return NewHTTPError(http.StatusNotFound, "Not Found")
+	case errors.Is(err, fs.ErrPermission):
+		return NewHTTPError(http.StatusForbidden, "Forbidden")
+	}
+	return WrapHTTPError(http.StatusInternalServerError, "Internal Server Error", err)
+}
+
+// contentDisposition formats a Content-Disposition header, quoting the
+// filename and encoding non-ASCII names as RFC 5987 filename*
+func contentDisposition(disposition, filename string) string {
+	if filename == "" {
+		return disposition
+	}
+	if v := mime.FormatMediaType(disposition, map[string]string{"filename": filename}); v != "" {
+		return v
+	}
+	return disposition
+}
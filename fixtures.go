@@ -0,0 +1,66 @@
+package nojs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+)
+
+// Fixture is a named set of seed data
+type Fixture struct {
+	Name string
+	Load func(ctx context.Context) error
+}
+
+// FixtureItems creates a fixture inserting items with insert, e.g. into a
+// store the app already uses
+func FixtureItems[T any](name string, items []T, insert func(ctx context.Context, item T) error) Fixture {
+	return Fixture{Name: name, Load: func(ctx context.Context) error {
+		for i, item := range items {
+			if err := insert(ctx, item); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		return nil
+	}}
+}
+
+// FixtureFile creates a fixture from a JSON file holding an array of T,
+// e.g. from an embed.FS. YAML is not read, as that would add a dependency
+// to every app; convert such files to JSON once.
+func FixtureFile[T any](fsys fs.FS, file string, insert func(ctx context.Context, item T) error) Fixture {
+	return Fixture{Name: file, Load: func(ctx context.Context) error {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		var items []T
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		return FixtureItems(file, items, insert).Load(ctx)
+	}}
+}
+
+// LoadFixtures loads fixtures in order, e.g. to give integration tests a
+// repeatable state
+func LoadFixtures(ctx context.Context, fixtures ...Fixture) error {
+	for _, f := range fixtures {
+		if err := f.Load(ctx); err != nil {
+			return fmt.Errorf("fixture %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// Seed loads fixtures when the server starts in DevMode, so development
+// instances start with data. It does nothing in production.
+func (s *Server) Seed(fixtures ...Fixture) {
+	if !s.config.DevMode {
+		return
+	}
+	s.OnStart(func(ctx context.Context) error {
+		return LoadFixtures(ctx, fixtures...)
+	})
+}
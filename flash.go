@@ -0,0 +1,184 @@
+package nojs
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// FlashLevel is the kind of a flash message, used as its CSS modifier
+type FlashLevel string
+
+// Flash levels
+const (
+	FlashSuccess FlashLevel = "success"
+	FlashError   FlashLevel = "error"
+	FlashWarn    FlashLevel = "warn"
+	FlashInfo    FlashLevel = "info"
+)
+
+// flashKey names the session value or cookie holding pending flashes
+const flashKey = "nojs_flash"
+
+// keyFlash stores the request's *flashState
+const keyFlash = "nojs.flash"
+
+// FlashMessage is a message shown once on the next page
+type FlashMessage struct {
+	Level FlashLevel      `json:"l"`
+	Text  string          `json:"t"`
+	Data  json.RawMessage `json:"d,omitempty"`
+}
+
+// Decode unmarshals the payload of a message added with FlashData
+func (m FlashMessage) Decode(v interface{}) error {
+	return json.Unmarshal(m.Data, v)
+}
+
+// flashState holds the pending flashes of a request, both those that
+// arrived with it and those added while handling it
+type flashState struct {
+	messages []FlashMessage
+	// fromCookie is set when the request carried the flash cookie
+	fromCookie bool
+}
+
+// Flash adds a message for the next page the user sees, or for this
+// page when it is rendered with Flashes in the same request. Flashes are
+// kept in the session with SessionManager and in an encrypted cookie
+// otherwise.
+func (c *Context) Flash(level FlashLevel, text string) {
+	st := c.flashes()
+	st.messages = append(st.messages, FlashMessage{Level: level, Text: text})
+	c.saveFlashes(st)
+}
+
+// FlashData adds a message carrying data, e.g. form errors, read back
+// with FlashMessage.Decode
+func (c *Context) FlashData(level FlashLevel, text string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	st := c.flashes()
+	st.messages = append(st.messages, FlashMessage{Level: level, Text: text, Data: raw})
+	c.saveFlashes(st)
+	return nil
+}
+
+// Flashes returns and removes all pending flash messages
+func (c *Context) Flashes() []FlashMessage {
+	return c.takeFlashes(func(FlashMessage) bool { return true })
+}
+
+// takeFlashes returns and removes the pending messages matching match
+func (c *Context) takeFlashes(match func(FlashMessage) bool) []FlashMessage {
+	st := c.flashes()
+	var taken, rest []FlashMessage
+	for _, m := range st.messages {
+		if match(m) {
+			taken = append(taken, m)
+		} else {
+			rest = append(rest, m)
+		}
+	}
+	if len(taken) > 0 {
+		st.messages = rest
+		c.saveFlashes(st)
+	}
+	return taken
+}
+
+// SetFlash adds a flash message with level name
+func (c *Context) SetFlash(name, value string) {
+	c.Flash(FlashLevel(name), value)
+}
+
+// GetFlash returns and removes the flash messages with level name,
+// joined into one string
+func (c *Context) GetFlash(name string) string {
+	var texts []string
+	for _, m := range c.takeFlashes(func(m FlashMessage) bool { return m.Level == FlashLevel(name) }) {
+		texts = append(texts, m.Text)
+	}
+	return strings.Join(texts, " ")
+}
+
+// flashes loads the pending flashes once per request
+func (c *Context) flashes() *flashState {
+	if st, ok := ValueOf[*flashState](c, keyFlash); ok {
+		return st
+	}
+	st := &flashState{}
+	if session := GetSession(c); session != nil {
+		st.messages, _ = session.Get(flashKey).([]FlashMessage)
+	} else if value, err := c.GetEncryptedCookie(flashKey); err == nil {
+		st.fromCookie = true
+		json.Unmarshal([]byte(value), &st.messages)
+	} else if _, err := c.Request.Cookie(flashKey); err == nil {
+		st.fromCookie = true // Undecryptable, e.g. after a secret change
+	}
+	c.Set(keyFlash, st)
+	return st
+}
+
+// saveFlashes stores the pending flashes for the next request
+func (c *Context) saveFlashes(st *flashState) {
+	if session := GetSession(c); session != nil {
+		if len(st.messages) == 0 {
+			session.Delete(flashKey)
+		} else {
+			session.Set(flashKey, append([]FlashMessage(nil), st.messages...))
+		}
+		return
+	}
+
+	// Replace a cookie set earlier in this request
+	header := c.ResponseWriter.Header()
+	var kept []string
+	for _, v := range header.Values("Set-Cookie") {
+		if !strings.HasPrefix(v, flashKey+"=") {
+			kept = append(kept, v)
+		}
+	}
+	header.Del("Set-Cookie")
+	for _, v := range kept {
+		header.Add("Set-Cookie", v)
+	}
+
+	if len(st.messages) == 0 {
+		if st.fromCookie {
+			c.DeleteCookie(flashKey)
+		}
+		return
+	}
+	data, _ := json.Marshal(st.messages)
+	c.SetEncryptedCookie(&http.Cookie{
+		Name:     flashKey,
+		Value:    string(data),
+		MaxAge:   300,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// Flashes renders and removes all pending flash messages as alerts.
+// Errors are announced immediately to screen readers, other levels
+// politely.
+func Flashes(ctx *Context) g.Node {
+	messages := ctx.Flashes()
+	if len(messages) == 0 {
+		return nil
+	}
+	var alerts []g.Node
+	for _, m := range messages {
+		role := "status"
+		if m.Level == FlashError {
+			role = "alert"
+		}
+		alerts = append(alerts, h.Div(h.Class("alert alert-"+string(m.Level)), h.Role(role), g.Text(m.Text)))
+	}
+	return h.Div(append([]g.Node{h.Class("flashes")}, alerts...)...)
+}
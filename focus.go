@@ -0,0 +1,54 @@
+package nojs
+
+import (
+	"net/url"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// FocusField is the form field and query parameter naming the input to
+// focus when the page is rendered again
+const FocusField = "_focus"
+
+// FocusInput adds a hidden field to a form naming the input that should
+// have focus after the submission, e.g. the "new item" input so several
+// items can be added in a row. A submit button with name FocusField
+// records a different input per button.
+func FocusInput(name string) g.Node {
+	return h.Input(h.Type("hidden"), h.Name(FocusField), h.Value(name))
+}
+
+// FocusTarget returns the input to focus, from the submitted form or the
+// query string of a RedirectFocus redirect
+func (c *Context) FocusTarget() string {
+	return c.Form(FocusField)
+}
+
+// Autofocus returns an autofocus attribute for the input with the given
+// name when it is the focus target. With fallback set it also focuses the
+// input when no target was recorded, e.g. on the first visit.
+func (c *Context) Autofocus(name string, fallback ...bool) g.Node {
+	target := c.FocusTarget()
+	if target == name || (target == "" && len(fallback) > 0 && fallback[0]) {
+		return h.AutoFocus()
+	}
+	return nil
+}
+
+// RedirectFocus redirects after a form submission (Post/Redirect/Get),
+// carrying the focus target to the next page in the query string
+func (c *Context) RedirectFocus(status int, rawURL string) error {
+	target := c.FocusTarget()
+	if target == "" {
+		return c.Redirect(status, rawURL)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return c.Redirect(status, rawURL)
+	}
+	q := u.Query()
+	q.Set(FocusField, target)
+	u.RawQuery = q.Encode()
+	return c.Redirect(status, u.String())
+}
@@ -0,0 +1,212 @@
+package nojs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// FontConfig configures self-hosted web fonts
+type FontConfig struct {
+	// Families are Google Fonts family specs, e.g. "Inter:wght@400;700"
+	Families []string
+	// Subsets keeps only these character subsets, e.g. "latin" and
+	// "latin-ext"; all are kept when empty
+	Subsets []string
+	// Text subsets the fonts to exactly these characters, e.g. for a
+	// display font used only in the logo
+	Text string
+	// Display is the font-display value, "swap" by default
+	Display string
+	// Dir caches the CSS and font files. Commit it or fill it at build
+	// time to start without network access.
+	Dir string
+	// Path is the URL path the font files are served under
+	Path string
+	// CSSURL is the CSS API the fonts are fetched from
+	CSSURL string
+	Client *http.Client
+}
+
+// DefaultFontConfig returns the default font configuration
+func DefaultFontConfig() FontConfig {
+	return FontConfig{
+		Display: "swap",
+		Dir:     "fonts",
+		Path:    "/fonts/",
+		CSSURL:  "https://fonts.googleapis.com/css2",
+		Client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Fonts are web fonts served by the app itself, so pages make no
+// requests to third parties
+type Fonts struct {
+	css string
+}
+
+// fontUserAgent makes the CSS API answer with WOFF2 files
+const fontUserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36"
+
+var (
+	fontFaceRe = regexp.MustCompile(`(?:/\*\s*([\w\-\[\]]+)\s*\*/\s*)?@font-face\s*\{[^}]*\}`)
+	fontURLRe  = regexp.MustCompile(`url\((https?://[^)]+)\)`)
+)
+
+// Fonts downloads the configured font families on first use, keeps them
+// in config.Dir and serves them under config.Path with long cache
+// headers. Put Fonts.Style in the page head to use them:
+//
+//	fonts, err := server.Fonts(nojs.FontConfig{Families: []string{"Inter:wght@400;700"}, Subsets: []string{"latin"}})
+//	...
+//	nojs.Page{Head: []g.Node{fonts.Style()}, ...}
+func (s *Server) Fonts(config ...FontConfig) (*Fonts, error) {
+	cfg := DefaultFontConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+		defaults := DefaultFontConfig()
+		if cfg.Display == "" {
+			cfg.Display = defaults.Display
+		}
+		if cfg.Dir == "" {
+			cfg.Dir = defaults.Dir
+		}
+		if cfg.Path == "" {
+			cfg.Path = defaults.Path
+		}
+		if cfg.CSSURL == "" {
+			cfg.CSSURL = defaults.CSSURL
+		}
+		if cfg.Client == nil {
+			cfg.Client = defaults.Client
+		}
+	}
+	if len(cfg.Families) == 0 {
+		return nil, fmt.Errorf("nojs: no font families configured")
+	}
+
+	query := url.Values{"family": cfg.Families, "display": {cfg.Display}}
+	if cfg.Text != "" {
+		query.Set("text", cfg.Text)
+	}
+	source := cfg.CSSURL + "?" + query.Encode()
+	sum := sha256.Sum256([]byte(source + "\n" + strings.Join(cfg.Subsets, ",")))
+	cssFile := filepath.Join(cfg.Dir, "fonts-"+hex.EncodeToString(sum[:8])+".css")
+
+	css, err := os.ReadFile(cssFile)
+	if err != nil {
+		if css, err = downloadFonts(cfg, source); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(cssFile, css, 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	s.routes = append(s.routes, routeRecord{pattern: cfg.Path, name: "font files from " + cfg.Dir})
+	files := http.StripPrefix(cfg.Path, http.FileServer(http.Dir(cfg.Dir)))
+	s.mux.Handle(cfg.Path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".css") || strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		// File names are content hashes, so they never change
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		files.ServeHTTP(w, r)
+	}))
+
+	base := s.URL(cfg.Path)
+	return &Fonts{css: strings.ReplaceAll(string(css), "url("+fontPathPlaceholder, "url("+base)}, nil
+}
+
+// fontPathPlaceholder stands for FontConfig.Path in cached CSS, so the
+// cache survives a change of mount point
+const fontPathPlaceholder = "{{fonts}}/"
+
+// downloadFonts fetches the CSS at source, downloads the fonts of the
+// wanted subsets into cfg.Dir and returns the CSS pointing to them
+func downloadFonts(cfg FontConfig, source string) ([]byte, error) {
+	css, err := fetchFont(cfg.Client, source)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var out strings.Builder
+	for _, m := range fontFaceRe.FindAllStringSubmatch(string(css), -1) {
+		if len(cfg.Subsets) > 0 && m[1] != "" && !Contains(cfg.Subsets, m[1]) {
+			continue
+		}
+		face := m[0]
+		for _, u := range fontURLRe.FindAllStringSubmatch(face, -1) {
+			name, err := downloadFontFile(cfg, u[1])
+			if err != nil {
+				return nil, err
+			}
+			face = strings.ReplaceAll(face, u[0], "url("+fontPathPlaceholder+name+")")
+		}
+		if m[1] != "" {
+			out.WriteString("/* " + m[1] + " */\n")
+		}
+		out.WriteString(face[strings.Index(face, "@font-face"):] + "\n")
+	}
+	if out.Len() == 0 {
+		return nil, fmt.Errorf("nojs: no fonts found at %s", source)
+	}
+	return []byte(out.String()), nil
+}
+
+// downloadFontFile saves a font file under a name derived from its
+// content and returns that name
+func downloadFontFile(cfg FontConfig, fileURL string) (string, error) {
+	data, err := fetchFont(cfg.Client, fileURL)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	ext := path.Ext(strings.SplitN(fileURL, "?", 2)[0])
+	name := hex.EncodeToString(sum[:8]) + ext
+	return name, os.WriteFile(filepath.Join(cfg.Dir, name), data, 0o644)
+}
+
+func fetchFont(client *http.Client, u string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", fontUserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("nojs: fetching %s: %s", u, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+}
+
+// CSS returns the @font-face rules
+func (f *Fonts) CSS() string {
+	return f.css
+}
+
+// Style returns the @font-face rules as a style element for the page head
+func (f *Fonts) Style() g.Node {
+	return h.StyleEl(g.Raw(f.css))
+}
@@ -0,0 +1,201 @@
+package nojs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// FormStateCookie carries a failed submission back to the form page
+const FormStateCookie = "nojs_form"
+
+// maxFormStateSize keeps the sealed cookie value, which grows by about a
+// third over the JSON, below the 4KB browsers accept for a whole cookie;
+// values are dropped (errors are kept) when a submission is larger
+const maxFormStateSize = 3900
+
+// FormState is a failed form submission: the values entered and the
+// error for each field
+type FormState struct {
+	Values url.Values  `json:"v,omitempty"`
+	Errors FieldErrors `json:"e,omitempty"`
+}
+
+// FormFlash implements Post/Redirect/Get for invalid forms: the values and
+// errors travel to the form page in an encrypted, short-lived cookie, and
+// the form is rendered again with them
+type FormFlash struct {
+	sealer *sealer
+}
+
+// NewFormFlash creates a FormFlash encrypting with a key derived from secret
+func NewFormFlash(secret string) *FormFlash {
+	return &FormFlash{sealer: newSealer(secret)}
+}
+
+// Bind binds and validates the request into dst (see BindValid). When the
+// submission is invalid it redirects back to the form page at back (the
+// Referer when empty) and returns false; the handler should then return
+// the error, which is nil after a successful redirect.
+//
+//	var form Signup
+//	if ok, err := flash.Bind(ctx, &form, "/signup"); !ok {
+//		return err
+//	}
+func (f *FormFlash) Bind(ctx *Context, dst interface{}, back string) (bool, error) {
+	err := ctx.BindValid(dst)
+	if err == nil {
+		return true, nil
+	}
+	errs, ok := err.(FieldErrors)
+	if !ok {
+		return false, err
+	}
+	return false, f.Redirect(ctx, back, errs)
+}
+
+// Redirect stores the submitted values and errs and redirects to back (the
+// Referer when empty) with 303 See Other. Password fields are never stored.
+func (f *FormFlash) Redirect(ctx *Context, back string, errs FieldErrors) error {
+	if back == "" {
+		back = localReferer(ctx.Request)
+	}
+
+	values := url.Values{}
+	for name, vals := range ctx.Request.Form {
+		if strings.Contains(strings.ToLower(name), "password") || frameworkFields[name] {
+			continue
+		}
+		values[name] = vals
+	}
+
+	data, _ := json.Marshal(FormState{Values: values, Errors: errs})
+	sealed := f.sealer.seal(FormStateCookie, data)
+	if len(sealed) > maxFormStateSize {
+		data, _ = json.Marshal(FormState{Errors: errs})
+		sealed = f.sealer.seal(FormStateCookie, data)
+	}
+	if len(sealed) > maxFormStateSize {
+		// Too many errors to carry; the form is shown empty
+		return ctx.Redirect(http.StatusSeeOther, back)
+	}
+
+	http.SetCookie(ctx.ResponseWriter, &http.Cookie{
+		Name:     FormStateCookie,
+		Value:    sealed,
+		Path:     "/",
+		MaxAge:   60,
+		HttpOnly: true,
+		Secure:   ctx.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return ctx.Redirect(http.StatusSeeOther, back)
+}
+
+// Load returns the state stored by Redirect and clears it. It returns an
+// empty state on the first visit, so forms render the same way either way.
+func (f *FormFlash) Load(ctx *Context) *FormState {
+	state := &FormState{Values: url.Values{}, Errors: FieldErrors{}}
+	cookie, err := ctx.Request.Cookie(FormStateCookie)
+	if err != nil {
+		return state
+	}
+	http.SetCookie(ctx.ResponseWriter, &http.Cookie{Name: FormStateCookie, Path: "/", MaxAge: -1})
+
+	data, err := f.sealer.open(FormStateCookie, cookie.Value)
+	if err != nil {
+		return state
+	}
+	json.Unmarshal(data, state)
+	if state.Values == nil {
+		state.Values = url.Values{}
+	}
+	if state.Errors == nil {
+		state.Errors = FieldErrors{}
+	}
+	return state
+}
+
+// Value returns the submitted value of a field, or fallback (e.g. the
+// stored record's value) when the form was not submitted
+func (s *FormState) Value(name string, fallback ...string) string {
+	if vals, ok := s.Values[name]; ok && len(vals) > 0 {
+		return vals[0]
+	}
+	if len(fallback) > 0 {
+		return fallback[0]
+	}
+	return ""
+}
+
+// Error returns the error message of a field
+func (s *FormState) Error(name string) string {
+	return s.Errors[name]
+}
+
+// HasErrors reports whether the submission had errors
+func (s *FormState) HasErrors() bool {
+	return len(s.Errors) > 0
+}
+
+// Input renders a labeled input filled with the submitted value and its
+// error message, marking it aria-invalid when it has one
+func (s *FormState) Input(label, name, inputType string, attrs ...g.Node) g.Node {
+	message := s.Error(name)
+	value := s.Value(name)
+	if inputType == "password" {
+		value = ""
+	}
+	if message != "" {
+		attrs = append(attrs, h.Aria("invalid", "true"), h.Aria("describedby", "error-"+name))
+	}
+	return h.Div(
+		Input(label, name, inputType, value, attrs...),
+		g.If(message != "", h.P(h.ID("error-"+name), h.Class("field-error"), h.Role("alert"), g.Text(message))),
+	)
+}
+
+// Summary renders an alert listing every error, for the top of the form
+func (s *FormState) Summary() g.Node {
+	if !s.HasErrors() {
+		return nil
+	}
+	names := make([]string, 0, len(s.Errors))
+	for name := range s.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return h.Div(h.Class("alert alert-error"), h.Role("alert"),
+		h.P(g.Text("Please correct the following:")),
+		h.Ul(g.Map(names, func(name string) g.Node {
+			return h.Li(g.Text(name + " " + s.Errors[name]))
+		})),
+	)
+}
+
+// localReferer returns the Referer path when it points to the same host,
+// "/" otherwise
+func localReferer(r *http.Request) string {
+	if back, ok := sameOriginReferer(r, ""); ok {
+		return back
+	}
+	return "/"
+}
+
+// sameOriginReferer returns the path and query of the Referer when it
+// points to the request's host and, unless empty, scheme
+func sameOriginReferer(r *http.Request, scheme string) (string, bool) {
+	u, err := url.Parse(r.Referer())
+	if err != nil || u.Host != r.Host || u.Path == "" {
+		return "", false
+	}
+	if scheme != "" && u.Scheme != scheme {
+		return "", false
+	}
+	return u.RequestURI(), true
+}
@@ -0,0 +1,33 @@
+package nojs
+
+import (
+	"net/http"
+
+	g "maragu.dev/gomponents"
+)
+
+// HTMLFragment renders content as a partial response for HTMX requests
+// when ServerConfig.HTMXMode is enabled, and content wrapped in a full
+// page by wrap (e.g. Layout.Wrap) otherwise. Both kinds of request share
+// the same handler code; no-JS clients always get full pages.
+func (c *Context) HTMLFragment(status int, content g.Node, wrap func(g.Node) g.Node) error {
+	if c.server.config.HTMXMode {
+		c.ResponseWriter.Header().Add("Vary", "HX-Request")
+		if c.IsHTMX() && c.Request.Header.Get("HX-Boosted") != "true" {
+			return c.HTML(status, content)
+		}
+	}
+	return c.HTML(status, wrap(content))
+}
+
+// Fragment registers a route whose handler returns page content; it is
+// sent alone to HTMX requests and wrapped by wrap for everything else
+func (s *Server) Fragment(pattern string, wrap func(g.Node) g.Node, render func(ctx *Context) (g.Node, error)) {
+	s.Route(pattern, func(ctx *Context) error {
+		content, err := render(ctx)
+		if err != nil {
+			return err
+		}
+		return ctx.HTMLFragment(http.StatusOK, content, wrap)
+	})
+}
@@ -0,0 +1,102 @@
+package nojs
+
+import (
+	"fmt"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// GalleryImage is one image in a Gallery
+type GalleryImage struct {
+	Src     string
+	Thumb   string // Defaults to Src
+	Alt     string
+	Caption string
+}
+
+// Gallery creates a thumbnail grid whose images open fullscreen through
+// the :target technique. Include GalleryStyles() in the page head.
+func Gallery(id string, images []GalleryImage) g.Node {
+	return h.Div(h.ID(id), h.Class("gallery"),
+		gallerySection(id, images, 0, len(images)),
+	)
+}
+
+// gallerySection renders thumbnails and lightboxes for images[from:to]
+func gallerySection(id string, images []GalleryImage, from, to int) g.Node {
+	imageID := func(i int) string {
+		return fmt.Sprintf("%s-image-%d", id, i+1)
+	}
+
+	thumbs := []g.Node{h.Class("gallery-grid")}
+	boxes := []g.Node{}
+	for i := from; i < to; i++ {
+		img := images[i]
+		thumb := img.Thumb
+		if thumb == "" {
+			thumb = img.Src
+		}
+
+		thumbs = append(thumbs, h.A(h.Href("#"+imageID(i)), h.Class("gallery-thumb"),
+			h.Img(h.Src(thumb), h.Alt(img.Alt), h.Loading("lazy")),
+		))
+
+		box := []g.Node{h.ID(imageID(i)), h.Class("gallery-lightbox"),
+			h.A(h.Href("#"+id), h.Class("gallery-close"), h.Aria("label", "Close"), g.Text("×")),
+			h.Figure(
+				h.Img(h.Src(img.Src), h.Alt(img.Alt), h.Loading("lazy")),
+				g.If(img.Caption != "", h.FigCaption(g.Text(img.Caption))),
+			),
+		}
+		if i > 0 {
+			box = append(box, h.A(h.Href("#"+imageID(i-1)), h.Class("gallery-prev"), h.Aria("label", "Previous image"), g.Text("‹")))
+		}
+		if i < len(images)-1 {
+			box = append(box, h.A(h.Href("#"+imageID(i+1)), h.Class("gallery-next"), h.Aria("label", "Next image"), g.Text("›")))
+		}
+		boxes = append(boxes, h.Div(box...))
+	}
+
+	return h.Section(h.Class("gallery-section"), h.Div(thumbs...), g.Group(boxes))
+}
+
+// StreamGallery streams a large gallery in sections of sectionSize
+// images so the first thumbnails show while the rest are rendered
+func (sw *StreamWriter) StreamGallery(id string, images []GalleryImage, sectionSize int) error {
+	if sectionSize <= 0 {
+		sectionSize = 24
+	}
+
+	if err := sw.WriteString(fmt.Sprintf(`<div id="%s" class="gallery">`, SanitizeHTML(id))); err != nil {
+		return err
+	}
+	for from := 0; from < len(images); from += sectionSize {
+		to := from + sectionSize
+		if to > len(images) {
+			to = len(images)
+		}
+		if err := sw.WriteNode(gallerySection(id, images, from, to)); err != nil {
+			return err
+		}
+	}
+	return sw.WriteString("</div>")
+}
+
+// GalleryStyles returns the CSS required by Gallery
+func GalleryStyles() g.Node {
+	return Style(`
+.gallery-grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(150px, 1fr)); gap: 0.5rem; }
+.gallery-thumb img { width: 100%; aspect-ratio: 1; object-fit: cover; display: block; }
+.gallery-thumb:focus { outline: 3px solid #4a90e2; }
+.gallery-lightbox { display: none; position: fixed; inset: 0; z-index: 1000; background: rgba(0,0,0,0.9); align-items: center; justify-content: center; }
+.gallery-lightbox:target { display: flex; }
+.gallery-lightbox figure { margin: 0; text-align: center; color: #fff; }
+.gallery-lightbox figure img { max-width: 90vw; max-height: 85vh; }
+.gallery-close, .gallery-prev, .gallery-next { position: absolute; color: #fff; text-decoration: none; font-size: 2rem; padding: 0.5rem 1rem; }
+.gallery-close { top: 0.5rem; right: 0.5rem; }
+.gallery-prev { left: 0.5rem; top: 50%; }
+.gallery-next { right: 0.5rem; top: 50%; }
+.gallery-close:focus, .gallery-prev:focus, .gallery-next:focus { outline: 2px solid #fff; }
+`)
+}
@@ -3,3 +3,9 @@ module github.com/jairo/mavis/nojs
 go 1.21
 
 require maragu.dev/gomponents v1.1.0
+
+require (
+	golang.org/x/crypto v0.33.0
+	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+)
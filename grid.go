@@ -0,0 +1,246 @@
+package nojs
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// GridColumn is an editable column of a Grid
+type GridColumn struct {
+	// Field is the form name of the struct field, as used by Bind
+	Field string
+	Label string
+	// Type is the input type: text (default), number, date, checkbox…
+	Type string
+	// Options renders a select instead of an input
+	Options []Option
+	Attrs   []g.Node
+}
+
+// GridConfig configures a Grid
+type GridConfig struct {
+	// Name prefixes the input names, e.g. "rows" gives rows[0].title
+	Name      string
+	Action    string
+	Columns   []GridColumn
+	AddRow    bool
+	RemoveRow bool
+	SaveLabel string
+}
+
+// Grid actions reported by BindGrid
+const (
+	GridSave   = "save"
+	GridAdd    = "add-row"
+	GridRemove = "remove-row"
+)
+
+// GridAction is the submit button used on a Grid
+type GridAction struct {
+	Kind string
+	// Row is the index of the removed row
+	Row int
+}
+
+// Grid renders a spreadsheet-like table of inputs for a slice of structs,
+// saved with a single POST. Add-row and remove-row are submit buttons
+// handled by BindGrid, so unsaved edits survive them.
+func Grid(config GridConfig, rows interface{}) g.Node {
+	if config.Name == "" {
+		config.Name = "rows"
+	}
+	if config.SaveLabel == "" {
+		config.SaveLabel = "Save"
+	}
+
+	head := []g.Node{}
+	for _, col := range config.Columns {
+		head = append(head, h.Th(g.Attr("scope", "col"), g.Text(col.Label)))
+	}
+	if config.RemoveRow {
+		head = append(head, h.Th(g.Attr("scope", "col"), h.Span(h.Class("sr-only"), g.Text("Actions"))))
+	}
+
+	v := reflect.ValueOf(rows)
+	body := []g.Node{}
+	for i := 0; v.Kind() == reflect.Slice && i < v.Len(); i++ {
+		row := reflect.Indirect(v.Index(i))
+		cells := []g.Node{}
+		for _, col := range config.Columns {
+			cells = append(cells, h.Td(gridInput(config.Name, i, col, gridValue(row, col.Field))))
+		}
+		if config.RemoveRow {
+			cells = append(cells, h.Td(h.Button(h.Type("submit"), h.Name(ActionField),
+				h.Value(GridRemove+":"+strconv.Itoa(i)), g.Attr("formnovalidate"),
+				h.Aria("label", fmt.Sprintf("Remove row %d", i+1)), g.Text("Remove"))))
+		}
+		body = append(body, h.Tr(cells...))
+	}
+	count := 0
+	if v.Kind() == reflect.Slice {
+		count = v.Len()
+	}
+
+	return Form(FormConfig{Action: config.Action, Method: "POST", Class: "grid-form"},
+		// Pressing Enter in a cell clicks the first submit button of the
+		// form, which would otherwise be the first row's Remove
+		h.Button(h.Type("submit"), h.Name(ActionField), h.Value(GridSave), g.Attr("tabindex", "-1"),
+			h.Aria("hidden", "true"), h.Style("position: absolute; left: -10000px"), g.Text(config.SaveLabel)),
+		h.Input(h.Type("hidden"), h.Name(config.Name+".count"), h.Value(strconv.Itoa(count))),
+		h.Table(h.Class("table grid"), h.THead(h.Tr(head...)), h.TBody(body...)),
+		h.Div(h.Class("form-actions"),
+			h.Button(h.Type("submit"), h.Name(ActionField), h.Value(GridSave), g.Text(config.SaveLabel)),
+			g.If(config.AddRow, h.Button(h.Type("submit"), h.Name(ActionField), h.Value(GridAdd),
+				g.Attr("formnovalidate"), g.Text("Add row"))),
+		),
+	)
+}
+
+// gridInput renders the input of one cell
+func gridInput(name string, row int, col GridColumn, value string) g.Node {
+	field := fmt.Sprintf("%s[%d].%s", name, row, col.Field)
+	label := h.Aria("label", fmt.Sprintf("%s, row %d", col.Label, row+1))
+
+	if len(col.Options) > 0 {
+		options := []g.Node{}
+		for _, opt := range col.Options {
+			options = append(options, h.Option(h.Value(opt.Value), g.If(opt.Value == value, h.Selected()), g.Text(opt.Label)))
+		}
+		return h.Select(h.Name(field), label, g.Group(col.Attrs), g.Group(options))
+	}
+
+	inputType := col.Type
+	if inputType == "" {
+		inputType = "text"
+	}
+	if inputType == "checkbox" {
+		return h.Input(h.Type("checkbox"), h.Name(field), h.Value("on"), label,
+			g.If(value == "true", h.Checked()), g.Group(col.Attrs))
+	}
+	return h.Input(h.Type(inputType), h.Name(field), h.Value(value), label, g.Group(col.Attrs))
+}
+
+// gridValue formats the struct field with the given form name for an input
+func gridValue(row reflect.Value, name string) string {
+	t := row.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldName := field.Tag.Get("form")
+		if fieldName == "" {
+			fieldName = field.Name
+		}
+		if fieldName != name {
+			continue
+		}
+		value := reflect.Indirect(row.Field(i))
+		if !value.IsValid() {
+			return ""
+		}
+		switch v := value.Interface().(type) {
+		case time.Time:
+			if v.IsZero() {
+				return ""
+			}
+			layout := field.Tag.Get("layout")
+			if layout == "" {
+				layout = "2006-01-02"
+			}
+			return v.Format(layout)
+		case Money:
+			return v.Decimal()
+		}
+		return fmt.Sprint(value.Interface())
+	}
+	return ""
+}
+
+// BindGrid binds a submitted Grid named name into dst, a pointer to a
+// slice of structs, and applies add-row and remove-row actions to it.
+// Save when the returned action is GridSave; otherwise render the grid
+// again with dst.
+func (c *Context) BindGrid(name string, dst interface{}) (GridAction, error) {
+	slice := reflect.ValueOf(dst)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return GridAction{}, fmt.Errorf("nojs: BindGrid needs a pointer to a slice, got %T", dst)
+	}
+	slice = slice.Elem()
+	elemType := slice.Type().Elem()
+
+	c.Request.ParseForm()
+	count, _ := strconv.Atoi(c.Request.PostForm.Get(name + ".count"))
+	if count < 0 || count > 10000 {
+		return GridAction{}, NewHTTPError(http.StatusBadRequest, "Invalid grid")
+	}
+
+	// Group the fields by row in one pass over the form
+	rowValues := make([]url.Values, count)
+	for key, vals := range c.Request.PostForm {
+		rest, ok := strings.CutPrefix(key, name+"[")
+		if !ok {
+			continue
+		}
+		index, field, ok := strings.Cut(rest, "].")
+		i, err := strconv.Atoi(index)
+		if !ok || err != nil || i < 0 || i >= count || strconv.Itoa(i) != index {
+			continue
+		}
+		if rowValues[i] == nil {
+			rowValues[i] = url.Values{}
+		}
+		rowValues[i][field] = vals
+	}
+
+	errs := FieldErrors{}
+	rows := reflect.MakeSlice(slice.Type(), 0, count+1)
+	for i := 0; i < count; i++ {
+		prefix := fmt.Sprintf("%s[%d].", name, i)
+		values := rowValues[i]
+		if values == nil {
+			values = url.Values{}
+		}
+
+		row := reflect.New(elemType).Elem()
+		target := row
+		if elemType.Kind() == reflect.Ptr {
+			row.Set(reflect.New(elemType.Elem()))
+			target = row.Elem()
+		}
+		rowErrs := FieldErrors{}
+		bindStruct(values, target, "", rowErrs, func(string) {})
+		for field, message := range rowErrs {
+			errs[prefix+field] = message
+		}
+		rows = reflect.Append(rows, row)
+	}
+
+	action := GridAction{Kind: GridSave}
+	switch submitted := c.SubmitAction(); {
+	case submitted == GridAdd:
+		action.Kind = GridAdd
+		zero := reflect.New(elemType).Elem()
+		if elemType.Kind() == reflect.Ptr {
+			zero.Set(reflect.New(elemType.Elem()))
+		}
+		rows = reflect.Append(rows, zero)
+	case strings.HasPrefix(submitted, GridRemove+":"):
+		index, err := strconv.Atoi(strings.TrimPrefix(submitted, GridRemove+":"))
+		if err == nil && index >= 0 && index < rows.Len() {
+			action = GridAction{Kind: GridRemove, Row: index}
+			rows = reflect.AppendSlice(rows.Slice(0, index), rows.Slice(index+1, rows.Len()))
+		}
+	}
+
+	slice.Set(rows)
+	if action.Kind == GridSave {
+		return action, errs.Err()
+	}
+	return action, nil
+}
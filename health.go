@@ -0,0 +1,267 @@
+package nojs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	g "maragu.dev/gomponents"
+)
+
+// Build details reported by /version, set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/jairo/mavis/nojs.Version=1.4.0"
+//
+// Empty values fall back to what debug.ReadBuildInfo knows: the module
+// version and the VCS revision and time stamped by go build.
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+// HealthCheck is a startup self-check, e.g. rendering a template or
+// pinging the database
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+	// Optional checks only degrade readiness when they fail, e.g. a
+	// mailer the site can run without for a while
+	Optional bool
+}
+
+// HealthConfig configures the endpoints registered by Health
+type HealthConfig struct {
+	Checks []HealthCheck
+	// FailFast aborts startup when a required check fails. Otherwise
+	// the server starts and the readiness endpoint reports the failure.
+	FailFast bool
+	// Timeout bounds each check
+	Timeout time.Duration
+	// RetryInterval is how often the readiness endpoint reruns failed
+	// checks, so it recovers once e.g. the database is back
+	RetryInterval time.Duration
+
+	VersionPath   string
+	BuildInfoPath string
+	LivePath      string
+	ReadyPath     string
+}
+
+// DefaultHealthConfig returns the default health configuration
+func DefaultHealthConfig() HealthConfig {
+	return HealthConfig{
+		Timeout:       5 * time.Second,
+		RetryInterval: 30 * time.Second,
+		VersionPath:   "/version",
+		BuildInfoPath: "/buildinfo",
+		LivePath:      "/healthz",
+		ReadyPath:     "/readyz",
+	}
+}
+
+// Health registers deploy health endpoints and runs the checks when the
+// server starts:
+//
+//   - VersionPath: version, commit and build time as JSON
+//   - BuildInfoPath: the full debug.ReadBuildInfo output, including
+//     dependency versions; guard it if those should stay private
+//   - LivePath: 200 while the process serves requests
+//   - ReadyPath: 200 when every required check passes, 503 when one
+//     fails or the server is shutting down, with the results as JSON
+//
+// Point the deploy's health gate at ReadyPath so a release with a broken
+// template or unreachable store never takes traffic.
+func (s *Server) Health(config ...HealthConfig) {
+	cfg := DefaultHealthConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	hs := &healthState{config: cfg}
+
+	s.OnStart(func(ctx context.Context) error {
+		hs.run(ctx, false)
+		if cfg.FailFast {
+			if name, err := hs.firstFailure(); err != nil {
+				return fmt.Errorf("nojs: health check %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+
+	s.GET(cfg.VersionPath, func(ctx *Context) error {
+		return ctx.JSON(http.StatusOK, buildVersion())
+	})
+	s.GET(cfg.BuildInfoPath, func(ctx *Context) error {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return NewHTTPError(http.StatusNotFound, "Build info not available")
+		}
+		return ctx.Text(http.StatusOK, info.String())
+	})
+	s.GET(cfg.LivePath, func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "ok\n")
+	})
+	s.GET(cfg.ReadyPath, func(ctx *Context) error {
+		select {
+		case <-s.root().shutdown:
+			return ctx.JSON(http.StatusServiceUnavailable, map[string]string{"status": "shutting down"})
+		default:
+		}
+		return hs.report(ctx)
+	})
+}
+
+// healthState holds the latest check results
+type healthState struct {
+	config  HealthConfig
+	mu      sync.Mutex
+	results map[string]error
+	checked time.Time
+}
+
+// run runs the checks, or with onlyFailed just those that failed last
+// time
+func (hs *healthState) run(ctx context.Context, onlyFailed bool) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if hs.results == nil {
+		hs.results = make(map[string]error)
+	}
+	for _, check := range hs.config.Checks {
+		if err, ran := hs.results[check.Name]; onlyFailed && ran && err == nil {
+			continue
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, hs.config.Timeout)
+		hs.results[check.Name] = check.Check(checkCtx)
+		cancel()
+	}
+	hs.checked = time.Now()
+}
+
+// firstFailure returns the first failing required check
+func (hs *healthState) firstFailure() (string, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	for _, check := range hs.config.Checks {
+		if err := hs.results[check.Name]; err != nil && !check.Optional {
+			return check.Name, err
+		}
+	}
+	return "", nil
+}
+
+// report answers a readiness probe, rerunning failed checks when
+// RetryInterval has passed
+func (hs *healthState) report(ctx *Context) error {
+	hs.mu.Lock()
+	stale := time.Since(hs.checked) >= hs.config.RetryInterval
+	hs.mu.Unlock()
+	if stale {
+		hs.run(ctx.Request.Context(), true)
+	}
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	status, code := "ok", http.StatusOK
+	checks := make(map[string]string, len(hs.config.Checks))
+	for _, check := range hs.config.Checks {
+		err := hs.results[check.Name]
+		if err == nil {
+			checks[check.Name] = "ok"
+			continue
+		}
+		checks[check.Name] = err.Error()
+		if status == "ok" {
+			status = "degraded"
+		}
+		if !check.Optional {
+			status, code = "failing", http.StatusServiceUnavailable
+		}
+	}
+	return ctx.JSON(code, map[string]interface{}{"status": status, "checks": checks})
+}
+
+// buildVersion combines the link-time variables with the build info
+func buildVersion() map[string]string {
+	v := map[string]string{
+		"version": Version,
+		"commit":  Commit,
+		"built":   BuildTime,
+		"go":      runtime.Version(),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v["version"] == "" {
+			v["version"] = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && v["commit"] == "":
+				v["commit"] = setting.Value
+			case setting.Key == "vcs.time" && v["built"] == "":
+				v["built"] = setting.Value
+			case setting.Key == "vcs.modified" && setting.Value == "true":
+				v["modified"] = "true"
+			}
+		}
+	}
+	return v
+}
+
+// RenderCheck checks that node renders, e.g. the home page's layout
+// with sample data
+func RenderCheck(name string, node func() g.Node) HealthCheck {
+	return HealthCheck{Name: name, Check: func(context.Context) error {
+		return node().Render(io.Discard)
+	}}
+}
+
+// PingCheck checks a store with a PingContext method, such as *sql.DB
+func PingCheck(name string, store interface{ PingContext(context.Context) error }) HealthCheck {
+	return HealthCheck{Name: name, Check: store.PingContext}
+}
+
+// SMTPCheck checks that the mail server at addr (host:port) accepts
+// auth, upgrading to TLS first when the server offers STARTTLS. It is
+// optional: a site can serve pages while mail is down.
+func SMTPCheck(name, addr string, auth smtp.Auth) HealthCheck {
+	return HealthCheck{Name: name, Optional: true, Check: func(ctx context.Context) error {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		client, err := smtp.NewClient(conn, host)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		defer client.Close()
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				return err
+			}
+		}
+		if auth != nil {
+			if err := client.Auth(auth); err != nil {
+				return err
+			}
+		}
+		return client.Quit()
+	}}
+}
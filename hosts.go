@@ -0,0 +1,59 @@
+package nojs
+
+import (
+	"net"
+	"strings"
+)
+
+// hostRoute maps a host pattern to the server handling it
+type hostRoute struct {
+	pattern string
+	app     *Server
+}
+
+// subdomainKey stores the part of the host matched by a wildcard
+type subdomainKey struct{}
+
+// Host serves requests for host with app. A pattern such as
+// "*.example.com" matches any subdomain (but not example.com itself);
+// read the matched part with ctx.Subdomain. Requests for hosts without a
+// match fall back to the server's own routes. Exact hosts take
+// precedence over wildcards.
+func (s *Server) Host(host string, app *Server) {
+	app.parent = s
+	s.hosts = append(s.hosts, hostRoute{pattern: strings.ToLower(host), app: app})
+}
+
+// hostName returns a Host header without port and trailing dot, lower
+// cased, e.g. "example.com" for "Example.com.:8080"
+func hostName(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// matchHost finds the server for a request host
+func (s *Server) matchHost(host string) (*Server, string, bool) {
+	host = hostName(host)
+
+	for _, route := range s.hosts {
+		if route.pattern == host {
+			return route.app, "", true
+		}
+	}
+
+	for _, route := range s.hosts {
+		if suffix, ok := strings.CutPrefix(route.pattern, "*"); ok && strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+			return route.app, strings.TrimSuffix(host, suffix), true
+		}
+	}
+	return nil, "", false
+}
+
+// Subdomain returns the part of the host matched by a wildcard Host
+// pattern, e.g. "acme" for acme.example.com and "*.example.com"
+func (c *Context) Subdomain() string {
+	sub, _ := c.Request.Context().Value(subdomainKey{}).(string)
+	return sub
+}
@@ -0,0 +1,247 @@
+package nojs
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	g "maragu.dev/gomponents"
+)
+
+// Event is a message published to a Hub room
+type Event struct {
+	Room string
+	Type string
+	Data []byte // Usually rendered HTML
+	Time time.Time
+}
+
+// Hub fans out events to streaming subscribers grouped by room. Slow
+// subscribers miss events instead of blocking publishers.
+type Hub struct {
+	mu    sync.RWMutex
+	rooms map[string]map[chan Event]struct{}
+	stats sync.Map // Room name to *roomCounters
+
+	lastSweep atomic.Int64 // Unix nanoseconds
+}
+
+// roomStatsIdle is how long the counters of a room without subscribers
+// or events are kept, so rooms named after short-lived things such as
+// documents or games do not pile up
+const roomStatsIdle = 10 * time.Minute
+
+// roomCounters counts the events of a room
+type roomCounters struct {
+	published  atomic.Uint64
+	delivered  atomic.Uint64
+	dropped    atomic.Uint64
+	latency    atomic.Int64 // Total nanoseconds from publish to delivery
+	lastActive atomic.Int64 // Unix nanoseconds of the last use
+}
+
+// counters returns the counters of room, creating them on first use
+func (hub *Hub) counters(room string) *roomCounters {
+	now := time.Now().UnixNano()
+	if last := hub.lastSweep.Load(); now-last > int64(time.Minute) && hub.lastSweep.CompareAndSwap(last, now) {
+		hub.sweep(now)
+	}
+
+	var c *roomCounters
+	if v, ok := hub.stats.Load(room); ok {
+		c = v.(*roomCounters)
+	} else {
+		v, _ := hub.stats.LoadOrStore(room, &roomCounters{})
+		c = v.(*roomCounters)
+	}
+	c.lastActive.Store(now)
+	return c
+}
+
+// sweep drops the counters of rooms idle for longer than roomStatsIdle
+func (hub *Hub) sweep(now int64) {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	hub.stats.Range(func(key, value interface{}) bool {
+		room, c := key.(string), value.(*roomCounters)
+		if len(hub.rooms[room]) == 0 && now-c.lastActive.Load() > int64(roomStatsIdle) {
+			hub.stats.Delete(room)
+		}
+		return true
+	})
+}
+
+// NewHub creates an empty hub
+func NewHub() *Hub {
+	return &Hub{rooms: make(map[string]map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving events published to room and a
+// function that must be called to unsubscribe
+func (hub *Hub) Subscribe(room string) (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	hub.counters(room)
+	hub.mu.Lock()
+	if hub.rooms[room] == nil {
+		hub.rooms[room] = make(map[chan Event]struct{})
+	}
+	hub.rooms[room][ch] = struct{}{}
+	hub.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			hub.mu.Lock()
+			delete(hub.rooms[room], ch)
+			if len(hub.rooms[room]) == 0 {
+				delete(hub.rooms, room)
+			}
+			hub.mu.Unlock()
+		})
+	}
+}
+
+// Publish sends an event to every subscriber of its room
+func (hub *Hub) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	counters := hub.counters(event.Room)
+	counters.published.Add(1)
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	for ch := range hub.rooms[event.Room] {
+		select {
+		case ch <- event:
+		default:
+			counters.dropped.Add(1)
+		}
+	}
+}
+
+// PublishNode renders node and publishes it to room
+func (hub *Hub) PublishNode(room, eventType string, node g.Node) error {
+	var buf strings.Builder
+	if err := node.Render(&buf); err != nil {
+		return err
+	}
+	hub.Publish(Event{Room: room, Type: eventType, Data: []byte(buf.String())})
+	return nil
+}
+
+// Subscribers returns the number of subscribers in room
+func (hub *Hub) Subscribers(room string) int {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	return len(hub.rooms[room])
+}
+
+// RoomStats are the counters of a Hub room since it was first used, or
+// since it was last idle for ten minutes
+type RoomStats struct {
+	Room        string
+	Subscribers int
+	// Backlog is the number of events waiting in subscriber buffers
+	Backlog   int
+	Published uint64
+	// Delivered counts events written to streams by Follow or returned
+	// to long polls
+	Delivered uint64
+	// Dropped counts events missed by subscribers with full buffers
+	Dropped uint64
+	// DeliveryTime is the total time from publish to delivery
+	DeliveryTime time.Duration
+}
+
+// MeanLatency returns the mean time from publish to delivery
+func (rs RoomStats) MeanLatency() time.Duration {
+	if rs.Delivered == 0 {
+		return 0
+	}
+	return rs.DeliveryTime / time.Duration(rs.Delivered)
+}
+
+// Stats returns the counters of every room that has subscribers or had
+// them or events in the last ten minutes, sorted by room
+func (hub *Hub) Stats() []RoomStats {
+	var stats []RoomStats
+	hub.mu.RLock()
+	hub.stats.Range(func(key, value interface{}) bool {
+		room, c := key.(string), value.(*roomCounters)
+		rs := RoomStats{
+			Room:         room,
+			Subscribers:  len(hub.rooms[room]),
+			Published:    c.published.Load(),
+			Delivered:    c.delivered.Load(),
+			Dropped:      c.dropped.Load(),
+			DeliveryTime: time.Duration(c.latency.Load()),
+		}
+		for ch := range hub.rooms[room] {
+			rs.Backlog += len(ch)
+		}
+		stats = append(stats, rs)
+		return true
+	})
+	hub.mu.RUnlock()
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Room < stats[j].Room })
+	return stats
+}
+
+// delivered records an event written to a subscriber's stream
+func (hub *Hub) delivered(event Event) {
+	c := hub.counters(event.Room)
+	c.delivered.Add(1)
+	c.latency.Add(int64(time.Since(event.Time)))
+}
+
+// Follow subscribes to room and writes each event's data to the stream
+// until the client disconnects, sending keep-alives while idle. On server
+// shutdown it writes the configured shutdown message and returns. When
+// render is non-nil it is called for every event instead of writing the
+// event data as is.
+func (sw *StreamWriter) Follow(hub *Hub, room string, render func(Event) g.Node) error {
+	_, err := sw.follow(hub, room, render, 0)
+	return err
+}
+
+// follow is Follow returning after limit events, reporting whether it
+// did; a limit of 0 follows the room until the client disconnects
+func (sw *StreamWriter) follow(hub *Hub, room string, render func(Event) g.Node, limit int) (bool, error) {
+	events, unsubscribe := hub.Subscribe(room)
+	defer unsubscribe()
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	for written := 0; limit == 0 || written < limit; {
+		select {
+		case <-sw.context.Request.Context().Done():
+			return false, nil
+		case <-sw.Done():
+			return false, sw.writeShutdownMessage()
+		case event := <-events:
+			var err error
+			if render != nil {
+				err = sw.WriteNode(render(event))
+			} else {
+				_, err = sw.Write(event.Data)
+			}
+			if err != nil {
+				return false, err
+			}
+			hub.delivered(event)
+			written++
+		case <-keepAlive.C:
+			if err := sw.KeepAlive(); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
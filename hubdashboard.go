@@ -0,0 +1,240 @@
+package nojs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// HubDashboardConfig configures HubDashboard
+type HubDashboardConfig struct {
+	// Interval is how often rooms are sampled and the page updated
+	Interval time.Duration
+	// History is how many samples the sparklines show
+	History int
+	// Frames is how many snapshots a page streams before reloading
+	// itself, so an open dashboard does not grow without bound
+	Frames int
+}
+
+// DefaultHubDashboardConfig samples every two seconds, charts the last
+// two minutes and reloads the page every ten minutes
+func DefaultHubDashboardConfig() HubDashboardConfig {
+	return HubDashboardConfig{Interval: 2 * time.Second, History: 60, Frames: 300}
+}
+
+// HubDashboard serves a live admin page at path showing, for every room
+// of hubs (keyed by display name), its subscribers, backlog, published,
+// delivered and dropped events and mean delivery latency, with sparklines
+// of subscribers and delivery rate. The page streams a new snapshot every
+// interval, hiding the previous ones with CSS, and reloads itself after
+// Frames snapshots, so it needs no JS and requires
+// ServerConfig.StreamingEnabled. Protect path with an admin
+// check; room names may be private.
+func (s *Server) HubDashboard(path string, hubs map[string]*Hub, config ...HubDashboardConfig) {
+	cfg := DefaultHubDashboardConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+		defaults := DefaultHubDashboardConfig()
+		if cfg.Interval <= 0 {
+			cfg.Interval = defaults.Interval
+		}
+		if cfg.History <= 0 {
+			cfg.History = defaults.History
+		}
+		if cfg.Frames <= 0 {
+			cfg.Frames = defaults.Frames
+		}
+	}
+	d := &hubDashboard{hubs: hubs, config: cfg, history: make(map[string][]hubSample)}
+	go d.sample(s.root().shutdown)
+
+	s.GET(path, func(ctx *Context) error {
+		sw, err := ctx.Stream()
+		if err != nil {
+			return err
+		}
+		if err := sw.StartHTML("Hub dashboard", hubDashboardStyles()); err != nil {
+			return err
+		}
+		if err := sw.WriteNode(g.Raw(`<main class="hub-live">`), d.render()); err != nil {
+			return err
+		}
+
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+		for frame := 1; ; frame++ {
+			if frame >= cfg.Frames {
+				if err := sw.WriteString(`</main><meta http-equiv="refresh" content="0">`); err != nil {
+					return err
+				}
+				return sw.EndHTML()
+			}
+			select {
+			case <-ctx.Request.Context().Done():
+				return nil
+			case <-sw.Done():
+				return sw.Close()
+			case <-ticker.C:
+				if err := sw.WriteNode(d.render()); err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
+
+// hubSample is one sample of a room's counters
+type hubSample struct {
+	subscribers int
+	delivered   uint64
+}
+
+// hubDashboard keeps the sample history of every room
+type hubDashboard struct {
+	hubs   map[string]*Hub
+	config HubDashboardConfig
+
+	mu      sync.Mutex
+	history map[string][]hubSample // Keyed by hub name and room
+}
+
+// sample records every room's counters each interval until done closes
+func (d *hubDashboard) sample(done <-chan struct{}) {
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+	for {
+		d.record()
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// record samples every room, forgetting rooms the hubs no longer report
+func (d *hubDashboard) record() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	seen := make(map[string]bool, len(d.history))
+	for name, hub := range d.hubs {
+		for _, rs := range hub.Stats() {
+			key := name + "\x00" + rs.Room
+			seen[key] = true
+			samples := append(d.history[key], hubSample{subscribers: rs.Subscribers, delivered: rs.Delivered})
+			if len(samples) > d.config.History {
+				samples = samples[len(samples)-d.config.History:]
+			}
+			d.history[key] = samples
+		}
+	}
+	for key := range d.history {
+		if !seen[key] {
+			delete(d.history, key)
+		}
+	}
+}
+
+// render returns a snapshot of every hub
+func (d *hubDashboard) render() g.Node {
+	names := make([]string, 0, len(d.hubs))
+	for name := range d.hubs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var sections []g.Node
+	for _, name := range names {
+		var rows []g.Node
+		for _, rs := range d.hubs[name].Stats() {
+			samples := d.history[name+"\x00"+rs.Room]
+			subscribers := make([]float64, len(samples))
+			rate := make([]float64, 0, len(samples))
+			for i, sample := range samples {
+				subscribers[i] = float64(sample.subscribers)
+				if i > 0 {
+					rate = append(rate, float64(sample.delivered-samples[i-1].delivered)/d.config.Interval.Seconds())
+				}
+			}
+			rows = append(rows, h.Tr(
+				h.Td(g.Text(rs.Room)),
+				h.Td(g.Text(strconv.Itoa(rs.Subscribers)), Sparkline(subscribers)),
+				h.Td(g.Text(strconv.Itoa(rs.Backlog))),
+				h.Td(g.Text(strconv.FormatUint(rs.Published, 10))),
+				h.Td(g.Text(strconv.FormatUint(rs.Delivered, 10)), Sparkline(rate)),
+				h.Td(g.If(rs.Dropped > 0, h.Class("hub-dropped")), g.Text(strconv.FormatUint(rs.Dropped, 10))),
+				h.Td(g.Text(rs.MeanLatency().Round(time.Microsecond).String())),
+			))
+		}
+		if len(rows) == 0 {
+			rows = append(rows, h.Tr(h.Td(g.Attr("colspan", "7"), g.Text("No rooms yet"))))
+		}
+		sections = append(sections, h.Section(
+			h.H2(g.Text(name)),
+			h.Table(
+				h.THead(h.Tr(
+					h.Th(g.Attr("scope", "col"), g.Text("Room")),
+					h.Th(g.Attr("scope", "col"), g.Text("Subscribers")),
+					h.Th(g.Attr("scope", "col"), g.Text("Backlog")),
+					h.Th(g.Attr("scope", "col"), g.Text("Published")),
+					h.Th(g.Attr("scope", "col"), g.Text("Delivered")),
+					h.Th(g.Attr("scope", "col"), g.Text("Dropped")),
+					h.Th(g.Attr("scope", "col"), g.Text("Mean latency")),
+				)),
+				h.TBody(rows...),
+			),
+		))
+	}
+	return h.Div(h.Class("hub-frame"),
+		h.H1(g.Text("Hubs")),
+		h.P(h.Class("hub-updated"), g.Text("Updated "+time.Now().Format("15:04:05"))),
+		g.Group(sections),
+	)
+}
+
+// Sparkline renders values as a small inline SVG line chart
+func Sparkline(values []float64) g.Node {
+	const width, height = 100, 20
+	if len(values) < 2 {
+		return nil
+	}
+	max := 0.0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	points := make([]string, len(values))
+	for i, v := range values {
+		y := float64(height)
+		if max > 0 {
+			y = height - v/max*(height-2) - 1
+		}
+		points[i] = fmt.Sprintf("%.1f,%.1f", float64(i)*width/float64(len(values)-1), y)
+	}
+	return g.Raw(fmt.Sprintf(`<svg class="sparkline" width="%d" height="%d" viewBox="0 0 %d %d" aria-hidden="true">`+
+		`<polyline fill="none" stroke="currentColor" stroke-width="1.5" points="%s"/></svg>`,
+		width, height, width, height, strings.Join(points, " ")))
+}
+
+// hubDashboardStyles hides all but the latest snapshot
+func hubDashboardStyles() g.Node {
+	return Style(`
+body { font-family: system-ui, sans-serif; margin: 2rem; }
+.hub-live > .hub-frame:not(:last-child) { display: none; }
+.hub-frame table { border-collapse: collapse; width: 100%; }
+.hub-frame th, .hub-frame td { text-align: left; padding: 0.4rem 0.75rem; border-bottom: 1px solid #ddd; white-space: nowrap; }
+.hub-frame .sparkline { vertical-align: middle; margin-left: 0.5rem; color: #4f46e5; }
+.hub-dropped { color: #b91c1c; font-weight: 600; }
+.hub-updated { color: #666; }
+`)
+}
@@ -0,0 +1,216 @@
+package nojs
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventCodec encodes hub events in a compact binary form for sending
+// them between processes, e.g. over a message bus linking the hubs of
+// several instances. An encoded event is:
+//
+//	"NH" version flags
+//	uvarint length + room
+//	uvarint length + type
+//	varint Unix nanoseconds of Time
+//	uvarint length + data, deflated when flags has bit 0 set
+//
+// The version byte changes only for incompatible changes. New fields are
+// appended and skipped by older decoders, so instances of neighbouring
+// releases can share a bus.
+type EventCodec struct {
+	// CompressAbove deflates data larger than this many bytes, 1KB by
+	// default. Rendered HTML, with its repeated markup, compresses well.
+	CompressAbove int
+	// MaxSize limits the decoded data, 16MB by default
+	MaxSize int
+}
+
+// eventWireVersion is the version written by EventCodec
+const eventWireVersion = 1
+
+// eventFlagDeflate marks deflated data
+const eventFlagDeflate = 1
+
+// ErrEventVersion is returned for events encoded by an incompatible
+// release
+var ErrEventVersion = errors.New("nojs: unsupported event encoding version")
+
+// errEventWire is returned for truncated or malformed events
+var errEventWire = errors.New("nojs: malformed encoded event")
+
+// Compressors are expensive to create, so they are reused
+var (
+	deflaters = sync.Pool{New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	}}
+	inflaters = sync.Pool{New: func() interface{} {
+		return flate.NewReader(nil)
+	}}
+)
+
+func (c EventCodec) compressAbove() int {
+	if c.CompressAbove > 0 {
+		return c.CompressAbove
+	}
+	return 1 << 10
+}
+
+func (c EventCodec) maxSize() int {
+	if c.MaxSize > 0 {
+		return c.MaxSize
+	}
+	return 16 << 20
+}
+
+// Encode returns the binary form of event
+func (c EventCodec) Encode(event Event) ([]byte, error) {
+	data, flags := event.Data, byte(0)
+	if len(data) > c.compressAbove() {
+		var buf bytes.Buffer
+		w := deflaters.Get().(*flate.Writer)
+		w.Reset(&buf)
+		_, err := w.Write(data)
+		if err == nil {
+			err = w.Close()
+		}
+		deflaters.Put(w)
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() < len(data) {
+			data, flags = buf.Bytes(), eventFlagDeflate
+		}
+	}
+
+	b := make([]byte, 0, 4+len(event.Room)+len(event.Type)+len(data)+4*binary.MaxVarintLen64)
+	b = append(b, 'N', 'H', eventWireVersion, flags)
+	b = appendBytes(b, []byte(event.Room))
+	b = appendBytes(b, []byte(event.Type))
+	var nanos int64
+	if !event.Time.IsZero() {
+		nanos = event.Time.UnixNano()
+	}
+	b = binary.AppendVarint(b, nanos)
+	b = appendBytes(b, data)
+	return b, nil
+}
+
+func appendBytes(b, field []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(field)))
+	return append(b, field...)
+}
+
+// Decode parses an event encoded by Encode
+func (c EventCodec) Decode(b []byte) (Event, error) {
+	if len(b) < 4 || b[0] != 'N' || b[1] != 'H' {
+		return Event{}, errEventWire
+	}
+	if b[2] != eventWireVersion {
+		return Event{}, fmt.Errorf("%w %d", ErrEventVersion, b[2])
+	}
+	flags := b[3]
+	r := eventReader{b: b[4:]}
+
+	var event Event
+	event.Room = string(r.bytes())
+	event.Type = string(r.bytes())
+	if nanos := r.varint(); nanos != 0 {
+		event.Time = time.Unix(0, nanos)
+	}
+	data := r.bytes()
+	if r.err != nil {
+		return Event{}, r.err
+	}
+	// Fields added by later releases follow; they are skipped
+
+	if flags&eventFlagDeflate == 0 {
+		if len(data) > c.maxSize() {
+			return Event{}, errEventWire
+		}
+		event.Data = append([]byte(nil), data...)
+		return event, nil
+	}
+	fr := inflaters.Get().(io.ReadCloser)
+	defer inflaters.Put(fr)
+	if err := fr.(flate.Resetter).Reset(bytes.NewReader(data), nil); err != nil {
+		return Event{}, err
+	}
+	var inflated bytes.Buffer
+	inflated.Grow(4 * len(data))
+	if _, err := inflated.ReadFrom(io.LimitReader(fr, int64(c.maxSize())+1)); err != nil {
+		return Event{}, fmt.Errorf("nojs: inflating event: %w", err)
+	}
+	if inflated.Len() > c.maxSize() {
+		return Event{}, errEventWire
+	}
+	event.Data = inflated.Bytes()
+	return event, nil
+}
+
+// eventReader reads the fields of an encoded event, remembering the
+// first error
+type eventReader struct {
+	b   []byte
+	err error
+}
+
+func (r *eventReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.err = errEventWire
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *eventReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.err = errEventWire
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *eventReader) bytes() []byte {
+	n := r.uvarint()
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(len(r.b)) {
+		r.err = errEventWire
+		return nil
+	}
+	field := r.b[:n]
+	r.b = r.b[n:]
+	return field
+}
+
+// PublishEncoded decodes an event received from another process and
+// publishes it to the local subscribers of its room. The event keeps its
+// original time, so delivery latency includes the trip between
+// processes.
+func (hub *Hub) PublishEncoded(codec EventCodec, b []byte) error {
+	event, err := codec.Decode(b)
+	if err != nil {
+		return err
+	}
+	hub.Publish(event)
+	return nil
+}
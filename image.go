@@ -0,0 +1,232 @@
+package nojs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// DefaultImageFormats are the modern formats offered for JPEG and PNG
+// images, in order of preference
+var DefaultImageFormats = []string{"avif", "webp"}
+
+// convertibleImages lists the source types converted to modern formats
+var convertibleImages = map[string]bool{".jpg": true, ".jpeg": true, ".png": true}
+
+// ImageConverter writes the image at src to dst in format ("avif" or
+// "webp")
+type ImageConverter func(src, dst, format string) error
+
+// ExecImageConverter converts images with the avifenc and cwebp command
+// line tools, which must be installed on the server
+func ExecImageConverter(src, dst, format string) error {
+	var cmd *exec.Cmd
+	switch format {
+	case "avif":
+		cmd = exec.Command("avifenc", "--speed", "6", src, dst)
+	case "webp":
+		cmd = exec.Command("cwebp", "-quiet", "-q", "80", src, "-o", dst)
+	default:
+		return fmt.Errorf("nojs: unsupported image format %q", format)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("nojs: %s: %v: %s", cmd.Path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// imageVariants serves AVIF and WebP versions of a static directory's
+// JPEG and PNG images, converting each image once on first request
+type imageVariants struct {
+	dir      string
+	cacheDir string
+	formats  []string
+	convert  ImageConverter
+
+	mu     sync.Mutex
+	locks  map[string]*sync.Mutex
+	failed map[string]time.Time // Source mod time of failed conversions
+}
+
+func newImageVariants(dir string, o StaticOptions) *imageVariants {
+	cacheDir := o.ImageCacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(os.TempDir(), "nojs-images")
+	}
+	// Static directories sharing the cache, e.g. of several apps on one
+	// host, must not serve each other's variants for the same name
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	sum := sha256.Sum256([]byte(abs))
+	cacheDir = filepath.Join(cacheDir, hex.EncodeToString(sum[:8]))
+	convert := o.ImageConverter
+	if convert == nil {
+		convert = ExecImageConverter
+	}
+	return &imageVariants{
+		dir:      dir,
+		cacheDir: cacheDir,
+		formats:  o.ImageFormats,
+		convert:  convert,
+		locks:    make(map[string]*sync.Mutex),
+		failed:   make(map[string]time.Time),
+	}
+}
+
+// serve sends the best variant of the image at name the client accepts,
+// reporting whether it did. The format query parameter, as used by
+// Image, selects a variant explicitly.
+func (iv *imageVariants) serve(w http.ResponseWriter, r *http.Request, name string) bool {
+	if !convertibleImages[strings.ToLower(path.Ext(name))] {
+		return false
+	}
+	src := filepath.Join(iv.dir, filepath.FromSlash(name))
+	info, err := os.Stat(src)
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	format := r.URL.Query().Get("format")
+	if !Contains(iv.formats, format) {
+		format = ""
+		w.Header().Add("Vary", "Accept")
+		for _, f := range iv.formats {
+			if acceptsEncoding(r.Header.Get("Accept"), "image/"+f) {
+				format = f
+				break
+			}
+		}
+	}
+	if format == "" {
+		return false
+	}
+
+	dst, err := iv.variant(src, filepath.Join(iv.cacheDir, filepath.FromSlash(name)+"."+format), format, info.ModTime())
+	if err != nil {
+		return false
+	}
+	f, err := os.Open(dst)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "image/"+format)
+	http.ServeContent(w, r, name, stat.ModTime(), f)
+	return true
+}
+
+// variant returns dst, converting src into it first when it is missing
+// or older than src. Failures are logged once per source version.
+func (iv *imageVariants) variant(src, dst, format string, modTime time.Time) (string, error) {
+	if info, err := os.Stat(dst); err == nil && !info.ModTime().Before(modTime) {
+		return dst, nil
+	}
+
+	iv.mu.Lock()
+	if failed, ok := iv.failed[dst]; ok && failed.Equal(modTime) {
+		iv.mu.Unlock()
+		return "", fmt.Errorf("nojs: converting %s failed before", src)
+	}
+	lock, ok := iv.locks[dst]
+	if !ok {
+		lock = &sync.Mutex{}
+		iv.locks[dst] = lock
+	}
+	iv.mu.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+	if info, err := os.Stat(dst); err == nil && !info.ModTime().Before(modTime) {
+		return dst, nil // Converted while waiting
+	}
+
+	err := os.MkdirAll(filepath.Dir(dst), 0o755)
+	if err == nil {
+		tmp := dst + ".tmp." + format // Converters pick the format from the extension
+		if err = iv.convert(src, tmp, format); err == nil {
+			err = os.Rename(tmp, dst)
+		}
+		os.Remove(tmp)
+	}
+	if err != nil {
+		log.Printf("nojs: converting %s to %s: %v", src, format, err)
+		iv.mu.Lock()
+		iv.failed[dst] = modTime
+		iv.mu.Unlock()
+		return "", err
+	}
+	return dst, nil
+}
+
+// ImageConfig configures an Image
+type ImageConfig struct {
+	Src    string
+	Alt    string
+	Width  int
+	Height int
+	// Formats are offered as <picture> sources before Src, defaulting
+	// to DefaultImageFormats. They are served by Static with
+	// StaticOptions.ImageFormats.
+	Formats []string
+	Class   string
+	// Eager loads the image right away, for images above the fold
+	Eager bool
+}
+
+// Image renders a JPEG or PNG image as a <picture> offering AVIF and
+// WebP variants, falling back to Src in browsers that support neither.
+// Width and Height reserve space so the page does not jump on load.
+func Image(config ImageConfig) g.Node {
+	loading := "lazy"
+	if config.Eager {
+		loading = "eager"
+	}
+	img := h.Img(
+		h.Src(config.Src),
+		h.Alt(config.Alt),
+		h.Loading(loading),
+		g.Attr("decoding", "async"),
+		g.If(config.Width > 0, h.Width(strconv.Itoa(config.Width))),
+		g.If(config.Height > 0, h.Height(strconv.Itoa(config.Height))),
+		g.If(config.Class != "", h.Class(config.Class)),
+	)
+
+	base, _, _ := strings.Cut(config.Src, "?")
+	if !convertibleImages[strings.ToLower(path.Ext(base))] {
+		return img
+	}
+	formats := config.Formats
+	if formats == nil {
+		formats = DefaultImageFormats
+	}
+	sep := "?"
+	if strings.Contains(config.Src, "?") {
+		sep = "&"
+	}
+
+	var sources []g.Node
+	for _, format := range formats {
+		sources = append(sources, h.Source(h.Type("image/"+format), h.SrcSet(config.Src+sep+"format="+format)))
+	}
+	return h.Picture(append(sources, img)...)
+}
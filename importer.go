@@ -0,0 +1,357 @@
+package nojs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// ImportField is a destination field of a CSV import
+type ImportField struct {
+	Name     string
+	Label    string
+	Required bool
+	// Validate checks a value; see also the Validators map
+	Validate func(value string) error
+}
+
+// ImportRow is a mapped CSV row keyed by ImportField.Name
+type ImportRow map[string]string
+
+// ImportJob tracks a running import
+type ImportJob struct {
+	ID       string
+	Total    int
+	Done     int
+	Failed   int
+	Err      error
+	Started  time.Time
+	Finished time.Time
+}
+
+// Importer is a CSV import wizard: upload, column mapping, validation
+// preview with row-level errors, and a batched background import with a
+// progress page. Mount it with Server.Mount:
+//
+//	importer := nojs.NewImporter(staging, fields, saveContacts)
+//	server.Mount("/contacts/import", importer.Server())
+type Importer struct {
+	Staging *Staging
+	Fields  []ImportField
+	// Import stores one batch of valid rows
+	Import    func(ctx context.Context, rows []ImportRow) error
+	BatchSize int
+	// PreviewRows is the number of rows shown on the preview page
+	PreviewRows int
+	// Wrap renders content as a full page, e.g. Layout.Wrap
+	Wrap func(content g.Node) g.Node
+
+	mu   sync.Mutex
+	jobs map[string]*ImportJob
+}
+
+// NewImporter creates an import wizard for fields
+func NewImporter(staging *Staging, fields []ImportField, importFn func(ctx context.Context, rows []ImportRow) error) *Importer {
+	return &Importer{
+		Staging:     staging,
+		Fields:      fields,
+		Import:      importFn,
+		BatchSize:   100,
+		PreviewRows: 20,
+		Wrap: func(content g.Node) g.Node {
+			return Page{Title: "Import", Body: h.Main(content)}.Render()
+		},
+		jobs: make(map[string]*ImportJob),
+	}
+}
+
+// Server returns the wizard routes, to be mounted under a prefix
+func (im *Importer) Server() *Server {
+	s := NewServer()
+	s.GET("/", im.uploadPage)
+	s.POST("/upload", im.upload)
+	s.GET("/map", im.mapPage)
+	s.GET("/preview", im.previewPage)
+	s.POST("/start", im.start)
+	s.GET("/job", im.jobPage)
+	return s
+}
+
+func (im *Importer) uploadPage(ctx *Context) error {
+	return ctx.HTML(http.StatusOK, im.Wrap(h.Div(h.Class("import"),
+		h.H1(g.Text("Import from CSV")),
+		h.Form(h.Action(ctx.URL("/upload")), h.Method("POST"), h.EncType("multipart/form-data"),
+			h.Label(g.Text("CSV file "), h.Input(h.Type("file"), h.Name("file"), h.Accept(".csv,text/csv"), h.Required())),
+			SubmitButton("Upload"),
+		),
+	)))
+}
+
+func (im *Importer) upload(ctx *Context) error {
+	staged, err := im.Staging.Stage(ctx, "file")
+	if err != nil {
+		return err
+	}
+	return ctx.Redirect(http.StatusSeeOther, ctx.URL("/map")+"?"+StagingTokenField+"="+staged.Token)
+}
+
+// readCSV returns the header and all records of a staged file
+func (im *Importer) readCSV(ctx *Context, token string) ([]string, [][]string, error) {
+	f, _, err := im.Staging.Open(ctx, token)
+	if err != nil {
+		return nil, nil, WrapHTTPError(http.StatusNotFound, "The upload has expired, please upload the file again", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, nil, WrapHTTPError(http.StatusBadRequest, "The file is not a valid CSV file", err)
+	}
+	var records [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, WrapHTTPError(http.StatusBadRequest, "The file is not a valid CSV file", err)
+		}
+		records = append(records, record)
+	}
+	return header, records, nil
+}
+
+// mapPage shows a select per CSV column, preselecting fields whose name
+// or label matches the column header
+func (im *Importer) mapPage(ctx *Context) error {
+	token := ctx.Query(StagingTokenField)
+	header, _, err := im.readCSV(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	rows := []g.Node{}
+	for i, column := range header {
+		selected := ctx.QueryValues("map")
+		choice := ""
+		if i < len(selected) {
+			choice = selected[i]
+		} else {
+			for _, field := range im.Fields {
+				if strings.EqualFold(column, field.Name) || strings.EqualFold(column, field.Label) {
+					choice = field.Name
+				}
+			}
+		}
+
+		options := []g.Node{h.Option(h.Value(""), g.Text("— skip —"))}
+		for _, field := range im.Fields {
+			options = append(options, h.Option(h.Value(field.Name), g.If(field.Name == choice, h.Selected()), g.Text(field.Label)))
+		}
+		id := "map-" + strconv.Itoa(i)
+		rows = append(rows, h.Tr(
+			h.Td(h.Label(h.For(id), g.Text(column))),
+			h.Td(h.Select(h.ID(id), h.Name("map"), g.Group(options))),
+		))
+	}
+
+	return ctx.HTML(http.StatusOK, im.Wrap(h.Div(h.Class("import"),
+		h.H1(g.Text("Match columns")),
+		h.Form(h.Action(ctx.URL("/preview")), h.Method("GET"),
+			h.Input(h.Type("hidden"), h.Name(StagingTokenField), h.Value(token)),
+			h.Table(h.Class("table"),
+				h.THead(h.Tr(h.Th(g.Text("Column in file")), h.Th(g.Text("Import as")))),
+				h.TBody(rows...),
+			),
+			SubmitButton("Preview"),
+		),
+	)))
+}
+
+// mapRows applies a column mapping to records and validates them,
+// returning the rows and an error message per invalid row index
+func (im *Importer) mapRows(mapping []string, records [][]string) ([]ImportRow, map[int]string) {
+	rows := make([]ImportRow, len(records))
+	errs := map[int]string{}
+	for i, record := range records {
+		row := ImportRow{}
+		for col, name := range mapping {
+			if name != "" && col < len(record) {
+				row[name] = strings.TrimSpace(record[col])
+			}
+		}
+		rows[i] = row
+
+		var problems []string
+		for _, field := range im.Fields {
+			value := row[field.Name]
+			if field.Required && value == "" {
+				problems = append(problems, field.Label+" is required")
+				continue
+			}
+			if value != "" && field.Validate != nil {
+				if err := field.Validate(value); err != nil {
+					problems = append(problems, field.Label+" "+err.Error())
+				}
+			}
+		}
+		if len(problems) > 0 {
+			errs[i] = strings.Join(problems, "; ")
+		}
+	}
+	return rows, errs
+}
+
+func (im *Importer) previewPage(ctx *Context) error {
+	token := ctx.Query(StagingTokenField)
+	mapping := ctx.QueryValues("map")
+	_, records, err := im.readCSV(ctx, token)
+	if err != nil {
+		return err
+	}
+	rows, errs := im.mapRows(mapping, records)
+
+	headers := []g.Node{h.Th(g.Text("Row"))}
+	for _, field := range im.Fields {
+		headers = append(headers, h.Th(g.Text(field.Label)))
+	}
+	headers = append(headers, h.Th(g.Text("Problems")))
+
+	body := []g.Node{}
+	for i, row := range rows {
+		if i >= im.PreviewRows {
+			break
+		}
+		cells := []g.Node{h.Td(g.Text(strconv.Itoa(i + 2)))}
+		for _, field := range im.Fields {
+			cells = append(cells, h.Td(g.Text(row[field.Name])))
+		}
+		cells = append(cells, h.Td(h.Class("field-error"), g.Text(errs[i])))
+		body = append(body, h.Tr(g.If(errs[i] != "", h.Class("row-invalid")), g.Group(cells)))
+	}
+
+	// Rows are numbered as in a spreadsheet: row 1 is the header
+	var invalid []g.Node
+	for i := range rows {
+		if errs[i] != "" && i >= im.PreviewRows {
+			invalid = append(invalid, h.Li(g.Textf("Row %d: %s", i+2, errs[i])))
+		}
+	}
+
+	back := ctx.URL("/map") + "?" + url.Values{StagingTokenField: {token}, "map": mapping}.Encode()
+	hidden := []g.Node{h.Input(h.Type("hidden"), h.Name(StagingTokenField), h.Value(token))}
+	for _, name := range mapping {
+		hidden = append(hidden, h.Input(h.Type("hidden"), h.Name("map"), h.Value(name)))
+	}
+
+	valid := len(rows) - len(errs)
+	return ctx.HTML(http.StatusOK, im.Wrap(h.Div(h.Class("import"),
+		h.H1(g.Text("Check the data")),
+		h.P(g.Textf("%d rows can be imported, %d have problems and will be skipped.", valid, len(errs))),
+		h.Table(h.Class("table"), h.THead(h.Tr(headers...)), h.TBody(body...)),
+		g.If(len(invalid) > 0, h.Details(h.Summary(g.Textf("%d more rows with problems", len(invalid))), h.Ul(invalid...))),
+		h.Form(h.Action(ctx.URL("/start")), h.Method("POST"), g.Group(hidden),
+			g.If(valid > 0, SubmitButton(fmt.Sprintf("Import %d rows", valid))),
+			h.A(h.Href(back), g.Text("Change column matching")),
+		),
+	)))
+}
+
+func (im *Importer) start(ctx *Context) error {
+	token := ctx.Form(StagingTokenField)
+	ctx.Request.ParseForm()
+	_, records, err := im.readCSV(ctx, token)
+	if err != nil {
+		return err
+	}
+	rows, errs := im.mapRows(ctx.Request.PostForm["map"], records)
+
+	valid := make([]ImportRow, 0, len(rows))
+	for i, row := range rows {
+		if errs[i] == "" {
+			valid = append(valid, row)
+		}
+	}
+
+	b := make([]byte, 8)
+	rand.Read(b)
+	job := &ImportJob{ID: hex.EncodeToString(b), Total: len(valid), Started: time.Now()}
+	im.mu.Lock()
+	im.jobs[job.ID] = job
+	im.mu.Unlock()
+
+	im.Staging.Discard(ctx, token)
+	go im.run(job, valid)
+	return ctx.Redirect(http.StatusSeeOther, ctx.URL("/job")+"?id="+job.ID)
+}
+
+// run imports rows in batches, recording progress on job
+func (im *Importer) run(job *ImportJob, rows []ImportRow) {
+	for start := 0; start < len(rows); start += im.BatchSize {
+		end := start + im.BatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		err := im.Import(context.Background(), rows[start:end])
+
+		im.mu.Lock()
+		if err != nil {
+			job.Failed += end - start
+			job.Err = err
+		} else {
+			job.Done += end - start
+		}
+		im.mu.Unlock()
+	}
+
+	im.mu.Lock()
+	job.Finished = time.Now()
+	im.mu.Unlock()
+}
+
+// Job returns a copy of an import job's progress
+func (im *Importer) Job(id string) (ImportJob, bool) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	job, ok := im.jobs[id]
+	if !ok {
+		return ImportJob{}, false
+	}
+	return *job, true
+}
+
+func (im *Importer) jobPage(ctx *Context) error {
+	job, ok := im.Job(ctx.Query("id"))
+	if !ok {
+		return NewHTTPError(http.StatusNotFound, "Import not found")
+	}
+
+	finished := !job.Finished.IsZero()
+	percent := 100
+	if job.Total > 0 {
+		percent = (job.Done + job.Failed) * 100 / job.Total
+	}
+
+	return ctx.HTML(http.StatusOK, im.Wrap(h.Div(h.Class("import"),
+		g.If(!finished, AutoRefresh(2)),
+		h.H1(g.If(finished, g.Text("Import finished")), g.If(!finished, g.Text("Importing…"))),
+		h.Progress(h.Value(strconv.Itoa(job.Done+job.Failed)), h.Max(strconv.Itoa(job.Total)), g.Textf("%d%%", percent)),
+		h.P(g.Textf("%d of %d rows imported.", job.Done, job.Total)),
+		g.If(job.Failed > 0, Alert(fmt.Sprintf("%d rows failed: %v", job.Failed, job.Err), "error")),
+	)))
+}
@@ -0,0 +1,49 @@
+package nojs
+
+import (
+	"net/url"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// EditParam is the query parameter naming the item being edited inline
+const EditParam = "edit"
+
+// Editing reports whether the item with the given id is in edit mode
+func (c *Context) Editing(id string) bool {
+	return c.Query(EditParam) == id
+}
+
+// InlineEdit shows displayNode with an "Edit" link, or formNode with a
+// "Cancel" link when the URL contains ?edit=id. The form should redirect
+// back to a URL without the edit parameter once saved.
+func InlineEdit(ctx *Context, id string, displayNode, formNode g.Node) g.Node {
+	if ctx.Editing(id) {
+		return h.Div(h.ID(id), h.Class("inline-edit editing"),
+			formNode,
+			h.A(h.Href(editURL(ctx.Request.URL, "")+"#"+id), h.Class("inline-edit-cancel"), g.Text("Cancel")),
+		)
+	}
+
+	return h.Div(h.ID(id), h.Class("inline-edit"),
+		displayNode,
+		h.A(h.Href(editURL(ctx.Request.URL, id)+"#"+id), h.Class("inline-edit-link"), g.Text("Edit")),
+	)
+}
+
+// editURL returns the path and query of u with the edit parameter set to
+// id, or removed when id is empty
+func editURL(u *url.URL, id string) string {
+	query := u.Query()
+	if id == "" {
+		query.Del(EditParam)
+	} else {
+		query.Set(EditParam, id)
+	}
+
+	if encoded := query.Encode(); encoded != "" {
+		return u.Path + "?" + encoded
+	}
+	return u.Path
+}
@@ -0,0 +1,263 @@
+package nojs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// InvoiceParty is the issuer or recipient of an invoice
+type InvoiceParty struct {
+	Name    string
+	Address []string
+	TaxID   string
+}
+
+// InvoiceLine is a line item
+type InvoiceLine struct {
+	Description string
+	Quantity    int64
+	UnitPrice   Money
+	// TaxRate in basis points, e.g. 2100 for 21%
+	TaxRate int64
+}
+
+// Amount returns the line total before tax
+func (l InvoiceLine) Amount() Money {
+	return l.UnitPrice.Mul(l.Quantity)
+}
+
+// Invoice is a printable invoice or receipt
+type Invoice struct {
+	Title    string // Defaults to "Invoice"
+	Number   string
+	Issued   time.Time
+	Due      time.Time
+	Currency string // Currency of every line's UnitPrice
+	From     InvoiceParty
+	To       InvoiceParty
+	Lines    []InvoiceLine
+	Notes    string
+}
+
+// InvoiceTotals holds the computed totals of an invoice
+type InvoiceTotals struct {
+	Subtotal Money
+	Tax      Money
+	Total    Money
+	// TaxByRate maps a rate in basis points to the tax charged at it
+	TaxByRate map[int64]Money
+}
+
+// Totals computes subtotal and tax per rate. Tax is rounded half up per
+// rate rather than per line, as most tax authorities require. It fails
+// when a line is priced in another currency than the invoice.
+func (inv Invoice) Totals() (InvoiceTotals, error) {
+	zero := NewMoney(0, inv.Currency)
+	t := InvoiceTotals{Subtotal: zero, Tax: zero, TaxByRate: make(map[int64]Money)}
+	base := make(map[int64]Money)
+	var err error
+	for _, line := range inv.Lines {
+		if t.Subtotal, err = t.Subtotal.Add(line.Amount()); err != nil {
+			return InvoiceTotals{}, err
+		}
+		if _, ok := base[line.TaxRate]; !ok {
+			base[line.TaxRate] = zero
+		}
+		if base[line.TaxRate], err = base[line.TaxRate].Add(line.Amount()); err != nil {
+			return InvoiceTotals{}, err
+		}
+	}
+	for rate, amount := range base {
+		if rate == 0 {
+			continue
+		}
+		tax := amount.MulRate(rate)
+		t.TaxByRate[rate] = tax
+		if t.Tax, err = t.Tax.Add(tax); err != nil {
+			return InvoiceTotals{}, err
+		}
+	}
+	if t.Total, err = t.Subtotal.Add(t.Tax); err != nil {
+		return InvoiceTotals{}, err
+	}
+	return t, nil
+}
+
+// ApplyRate returns amount multiplied by a rate in basis points, rounded
+// half away from zero
+func ApplyRate(amount, basisPoints int64) int64 {
+	product := amount * basisPoints
+	if product < 0 {
+		return -((-product + 5000) / 10000)
+	}
+	return (product + 5000) / 10000
+}
+
+// formatRate formats basis points as a percentage
+func formatRate(basisPoints int64) string {
+	s := fmt.Sprintf("%d.%02d", basisPoints/100, basisPoints%100)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".") + "%"
+}
+
+// InvoiceView renders an invoice. Include InvoiceStyles() for print
+// friendly layout. Rendering fails when the totals cannot be computed.
+func InvoiceView(inv Invoice) g.Node {
+	title := inv.Title
+	if title == "" {
+		title = "Invoice"
+	}
+	totals, err := inv.Totals()
+	if err != nil {
+		return g.NodeFunc(func(io.Writer) error {
+			return fmt.Errorf("nojs: invoice %s: %w", inv.Number, err)
+		})
+	}
+
+	rows := []g.Node{}
+	for _, line := range inv.Lines {
+		rows = append(rows, h.Tr(
+			h.Td(g.Text(line.Description)),
+			h.Td(h.Class("num"), g.Textf("%d", line.Quantity)),
+			h.Td(h.Class("num"), g.Text(line.UnitPrice.String())),
+			h.Td(h.Class("num"), g.Text(formatRate(line.TaxRate))),
+			h.Td(h.Class("num"), g.Text(line.Amount().String())),
+		))
+	}
+
+	rates := make([]int64, 0, len(totals.TaxByRate))
+	for rate := range totals.TaxByRate {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i] < rates[j] })
+
+	summary := []g.Node{
+		invoiceTotalRow("Subtotal", totals.Subtotal.String(), ""),
+	}
+	for _, rate := range rates {
+		summary = append(summary, invoiceTotalRow("Tax "+formatRate(rate), totals.TaxByRate[rate].String(), ""))
+	}
+	summary = append(summary, invoiceTotalRow("Total", totals.Total.String(), "invoice-total"))
+
+	return h.Article(h.Class("invoice"),
+		h.Header(h.Class("invoice-header"),
+			h.H1(g.Text(title)),
+			h.Dl(
+				g.If(inv.Number != "", g.Group([]g.Node{h.Dt(g.Text("Number")), h.Dd(g.Text(inv.Number))})),
+				g.If(!inv.Issued.IsZero(), g.Group([]g.Node{h.Dt(g.Text("Date")), h.Dd(g.Text(FormatDate(inv.Issued)))})),
+				g.If(!inv.Due.IsZero(), g.Group([]g.Node{h.Dt(g.Text("Due")), h.Dd(g.Text(FormatDate(inv.Due)))})),
+			),
+		),
+		h.Div(h.Class("invoice-parties"),
+			InvoiceAddress("From", inv.From),
+			InvoiceAddress("Bill to", inv.To),
+		),
+		h.Table(h.Class("invoice-lines"),
+			h.THead(h.Tr(
+				h.Th(g.Text("Description")),
+				h.Th(h.Class("num"), g.Text("Qty")),
+				h.Th(h.Class("num"), g.Text("Unit price")),
+				h.Th(h.Class("num"), g.Text("Tax")),
+				h.Th(h.Class("num"), g.Text("Amount")),
+			)),
+			h.TBody(rows...),
+			h.TFoot(summary...),
+		),
+		g.If(inv.Notes != "", h.P(h.Class("invoice-notes"), g.Text(inv.Notes))),
+	)
+}
+
+func invoiceTotalRow(label, amount, class string) g.Node {
+	return h.Tr(g.If(class != "", h.Class(class)),
+		h.Th(h.ColSpan("4"), h.Class("num"), g.Text(label)),
+		h.Td(h.Class("num"), g.Text(amount)),
+	)
+}
+
+// InvoiceAddress renders an invoice party block
+func InvoiceAddress(label string, party InvoiceParty) g.Node {
+	lines := []g.Node{h.Strong(g.Text(party.Name))}
+	for _, line := range party.Address {
+		lines = append(lines, h.Br(), g.Text(line))
+	}
+	if party.TaxID != "" {
+		lines = append(lines, h.Br(), g.Text("Tax ID: "+party.TaxID))
+	}
+
+	return h.Section(h.Class("invoice-party"),
+		h.H2(g.Text(label)),
+		h.Address(lines...),
+	)
+}
+
+// InvoiceStyles returns screen and print CSS for InvoiceView
+func InvoiceStyles() g.Node {
+	return Style(`
+.invoice { max-width: 50rem; margin: 0 auto; padding: 2rem; background: #fff; color: #000; }
+.invoice-header { display: flex; justify-content: space-between; align-items: flex-start; }
+.invoice-header dl { display: grid; grid-template-columns: auto auto; gap: 0.25rem 1rem; margin: 0; }
+.invoice-header dd { margin: 0; }
+.invoice-parties { display: flex; justify-content: space-between; gap: 2rem; margin: 2rem 0; }
+.invoice-party h2 { font-size: 0.9rem; text-transform: uppercase; color: #555; }
+.invoice-party address { font-style: normal; }
+.invoice-lines { width: 100%; border-collapse: collapse; }
+.invoice-lines th, .invoice-lines td { padding: 0.5rem; border-bottom: 1px solid #ddd; text-align: left; }
+.invoice-lines .num { text-align: right; white-space: nowrap; }
+.invoice-total th, .invoice-total td { font-weight: bold; border-top: 2px solid #000; }
+@media print {
+  body * { visibility: hidden; }
+  .invoice, .invoice * { visibility: visible; }
+  .invoice { position: absolute; left: 0; top: 0; padding: 0; max-width: none; }
+  @page { margin: 1.5cm; }
+}
+`)
+}
+
+// PDFRenderer converts an HTML document to PDF
+type PDFRenderer func(html []byte) ([]byte, error)
+
+// CommandPDFRenderer returns a PDFRenderer that pipes HTML through an
+// external tool reading stdin and writing stdout, e.g.
+// CommandPDFRenderer("wkhtmltopdf", "--quiet", "-", "-")
+func CommandPDFRenderer(name string, args ...string) PDFRenderer {
+	return func(html []byte) ([]byte, error) {
+		var out, stderr bytes.Buffer
+		cmd := exec.Command(name, args...)
+		cmd.Stdin = bytes.NewReader(html)
+		cmd.Stdout = &out
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+		}
+		return out.Bytes(), nil
+	}
+}
+
+// PDF renders node to HTML, converts it with renderer and sends it as a
+// PDF download named filename
+func (c *Context) PDF(filename string, node g.Node, renderer PDFRenderer) error {
+	var buf bytes.Buffer
+	if err := node.Render(&buf); err != nil {
+		return err
+	}
+
+	pdf, err := renderer(buf.Bytes())
+	if err != nil {
+		return WrapHTTPError(http.StatusInternalServerError, "Could not generate PDF", err)
+	}
+
+	c.ResponseWriter.Header().Set("Content-Type", "application/pdf")
+	c.ResponseWriter.Header().Set("Content-Disposition", contentDisposition("attachment", filename))
+	c.ResponseWriter.WriteHeader(http.StatusOK)
+	c.written = true
+	_, err = c.ResponseWriter.Write(pdf)
+	return err
+}
@@ -0,0 +1,202 @@
+package nojs
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	g "maragu.dev/gomponents"
+)
+
+// JSONLD renders data as a schema.org JSON-LD script tag for Page.Head.
+// The JSON is HTML-escaped, so values cannot close the script element.
+func JSONLD(data interface{}) g.Node {
+	return g.NodeFunc(func(w io.Writer) error {
+		return writeJSONLD(w, data)
+	})
+}
+
+func writeJSONLD(w io.Writer, data interface{}) error {
+	b, err := json.Marshal(data) // Escapes <, > and &
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `<script type="application/ld+json">`); err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, `</script>`)
+	return err
+}
+
+// ld is a JSON-LD object that leaves out empty values
+type ld map[string]interface{}
+
+func newLD(typ string) ld {
+	return ld{"@context": "https://schema.org", "@type": typ}
+}
+
+func (o ld) set(key string, value interface{}) ld {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return o
+		}
+	case []string:
+		if len(v) == 0 {
+			return o
+		}
+	case time.Time:
+		if v.IsZero() {
+			return o
+		}
+		value = v.Format(time.RFC3339)
+	case ld:
+		if v == nil {
+			return o
+		}
+		delete(v, "@context")
+	}
+	o[key] = value
+	return o
+}
+
+// named returns a nested entity with a name, or nil when name is empty
+func named(typ, name string) ld {
+	if name == "" {
+		return nil
+	}
+	return ld{"@type": typ, "name": name}
+}
+
+// Article is a schema.org Article, rendered as JSON-LD. Use it as a
+// Page.Head node.
+type Article struct {
+	Headline      string
+	Description   string
+	URL           string
+	Images        []string
+	Author        string
+	Publisher     string
+	PublisherLogo string
+	DatePublished time.Time
+	DateModified  time.Time
+}
+
+// Render implements g.Node
+func (a Article) Render(w io.Writer) error {
+	publisher := named("Organization", a.Publisher)
+	if publisher != nil && a.PublisherLogo != "" {
+		publisher["logo"] = ld{"@type": "ImageObject", "url": a.PublisherLogo}
+	}
+	return writeJSONLD(w, newLD("Article").
+		set("headline", a.Headline).
+		set("description", a.Description).
+		set("mainEntityOfPage", a.URL).
+		set("image", a.Images).
+		set("author", named("Person", a.Author)).
+		set("publisher", publisher).
+		set("datePublished", a.DatePublished).
+		set("dateModified", a.DateModified))
+}
+
+// Product is a schema.org Product with a single offer, rendered as
+// JSON-LD. Availability is a schema.org ItemAvailability name such as
+// "InStock" or "OutOfStock".
+type Product struct {
+	Name         string
+	Description  string
+	URL          string
+	Images       []string
+	SKU          string
+	Brand        string
+	Price        Money
+	Availability string
+	// RatingValue and ReviewCount add an aggregate rating when set
+	RatingValue float64
+	ReviewCount int
+}
+
+// Render implements g.Node
+func (p Product) Render(w io.Writer) error {
+	product := newLD("Product").
+		set("name", p.Name).
+		set("description", p.Description).
+		set("url", p.URL).
+		set("image", p.Images).
+		set("sku", p.SKU).
+		set("brand", named("Brand", p.Brand))
+	if p.Price.Currency != "" {
+		offer := ld{"@type": "Offer", "price": p.Price.Decimal(), "priceCurrency": p.Price.Currency}
+		if p.Availability != "" {
+			offer["availability"] = "https://schema.org/" + p.Availability
+		}
+		product.set("offers", offer.set("url", p.URL))
+	}
+	if p.ReviewCount > 0 {
+		product.set("aggregateRating", ld{"@type": "AggregateRating", "ratingValue": p.RatingValue, "reviewCount": p.ReviewCount})
+	}
+	return writeJSONLD(w, product)
+}
+
+// Breadcrumb is one level of a BreadcrumbList
+type Breadcrumb struct {
+	Name string
+	URL  string
+}
+
+// BreadcrumbList is a schema.org BreadcrumbList, from the home page down
+// to the current page, rendered as JSON-LD
+type BreadcrumbList []Breadcrumb
+
+// Render implements g.Node
+func (b BreadcrumbList) Render(w io.Writer) error {
+	items := make([]ld, len(b))
+	for i, crumb := range b {
+		items[i] = ld{"@type": "ListItem", "position": i + 1, "name": crumb.Name}.set("item", crumb.URL)
+	}
+	return writeJSONLD(w, newLD("BreadcrumbList").set("itemListElement", items))
+}
+
+// SchemaEvent is a schema.org Event (Event is the hub's message type),
+// rendered as JSON-LD. Online events use URL as their location; others
+// name a venue and its address.
+type SchemaEvent struct {
+	Name        string
+	Description string
+	URL         string
+	Images      []string
+	Start       time.Time
+	End         time.Time
+	Online      bool
+	Venue       string
+	Address     string
+	Organizer   string
+	// Price adds a ticket offer when set; a zero amount is a free event
+	Price *Money
+}
+
+// Render implements g.Node
+func (e SchemaEvent) Render(w io.Writer) error {
+	event := newLD("Event").
+		set("name", e.Name).
+		set("description", e.Description).
+		set("url", e.URL).
+		set("image", e.Images).
+		set("startDate", e.Start).
+		set("endDate", e.End).
+		set("organizer", named("Organization", e.Organizer))
+	if e.Online {
+		event.set("eventAttendanceMode", "https://schema.org/OnlineEventAttendanceMode")
+		event.set("location", ld{"@type": "VirtualLocation"}.set("url", e.URL))
+	} else {
+		event.set("eventAttendanceMode", "https://schema.org/OfflineEventAttendanceMode")
+		event.set("location", ld{"@type": "Place"}.set("name", e.Venue).set("address", e.Address))
+	}
+	if e.Price != nil {
+		event.set("offers", ld{"@type": "Offer", "price": e.Price.Decimal(), "priceCurrency": e.Price.Currency}.set("url", e.URL))
+	}
+	return writeJSONLD(w, event)
+}
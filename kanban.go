@@ -0,0 +1,113 @@
+package nojs
+
+import (
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// BoardCard is a card on a Board
+type BoardCard struct {
+	ID      string
+	Title   string
+	Content g.Node
+}
+
+// BoardColumn is a column of cards on a Board
+type BoardColumn struct {
+	ID    string
+	Title string
+	Cards []BoardCard
+}
+
+// BoardConfig configures a Board
+type BoardConfig struct {
+	Columns []BoardColumn
+	// MoveAction receives the move forms, read them with ctx.BoardMove
+	MoveAction string
+	// Target is the form target, set it to "_top" when the board is
+	// rendered inside a streaming iframe
+	Target string
+}
+
+// Board creates a kanban board where each card has a small form to move
+// it to another column
+func Board(config BoardConfig) g.Node {
+	columns := []g.Node{h.Class("kanban-board")}
+	for _, column := range config.Columns {
+		cards := []g.Node{h.Class("kanban-cards")}
+		for _, card := range column.Cards {
+			cards = append(cards, boardCard(config, column.ID, card))
+		}
+
+		columns = append(columns, h.Section(h.ID("column-"+column.ID), h.Class("kanban-column"),
+			h.H3(g.Text(column.Title), h.Span(h.Class("kanban-count"), g.Textf(" (%d)", len(column.Cards)))),
+			h.Ul(cards...),
+		))
+	}
+	return h.Div(columns...)
+}
+
+func boardCard(config BoardConfig, columnID string, card BoardCard) g.Node {
+	// Every card has a select, so its id must be derived from the card
+	options := []g.Node{h.Name("column"), h.ID("move-" + card.ID), h.Aria("label", "Move to column")}
+	for _, column := range config.Columns {
+		options = append(options, h.Option(h.Value(column.ID), g.If(column.ID == columnID, h.Selected()), g.Text(column.Title)))
+	}
+
+	return h.Li(h.ID("card-"+card.ID), h.Class("kanban-card"),
+		h.H4(g.Text(card.Title)),
+		card.Content,
+		Form(FormConfig{Action: config.MoveAction, Class: "kanban-move"},
+			g.If(config.Target != "", h.Target(config.Target)),
+			h.Input(h.Type("hidden"), h.Name("card"), h.Value(card.ID)),
+			h.Select(options...),
+			SubmitButton("Move", h.Class("button-small")),
+		),
+	)
+}
+
+// BoardMove returns the card and destination column submitted by a
+// Board move form
+func (c *Context) BoardMove() (cardID, columnID string) {
+	return c.Form("card"), c.Form("column")
+}
+
+// maxBoardFrames is how many boards StreamBoard writes before reloading
+// the page, since every copy stays in the document
+const maxBoardFrames = 100
+
+// StreamBoard streams the board returned by render, then re-renders it
+// every time an event is published to room. Earlier copies are hidden
+// with CSS, so the page always shows the latest board without JS; after
+// 100 of them the page reloads itself to start over. Publish to room
+// after handling a move to update every viewer.
+func (sw *StreamWriter) StreamBoard(hub *Hub, room string, render func() g.Node) error {
+	err := sw.WriteNode(
+		Style(`.kanban-live > .kanban-board:not(:last-child) { display: none; }`),
+		g.Raw(`<div class="kanban-live">`),
+		render(),
+	)
+	if err != nil {
+		return err
+	}
+
+	full, err := sw.follow(hub, room, func(Event) g.Node { return render() }, maxBoardFrames-1)
+	if err != nil {
+		return err
+	}
+	if full {
+		return sw.WriteString(`</div><meta http-equiv="refresh" content="0">`)
+	}
+	return sw.WriteString("</div>")
+}
+
+// KanbanStyles returns the CSS used by Board
+func KanbanStyles() g.Node {
+	return Style(`
+.kanban-board { display: flex; gap: 1rem; overflow-x: auto; align-items: flex-start; }
+.kanban-column { flex: 0 0 16rem; background: #f4f5f7; border-radius: 6px; padding: 0.5rem; }
+.kanban-cards { list-style: none; margin: 0; padding: 0; }
+.kanban-card { background: #fff; border-radius: 4px; padding: 0.5rem; margin-bottom: 0.5rem; box-shadow: 0 1px 2px rgba(0,0,0,0.15); }
+.kanban-move { display: flex; gap: 0.25rem; margin-top: 0.5rem; }
+`)
+}
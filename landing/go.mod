@@ -11,3 +11,9 @@ require (
 replace github.com/kidandcat/nojs => ../
 
 replace github.com/jairo/mavis/nojs/demo => ../demo
+
+require (
+	golang.org/x/crypto v0.33.0 // indirect
+	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+)
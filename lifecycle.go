@@ -0,0 +1,59 @@
+package nojs
+
+import (
+	"context"
+	"log"
+)
+
+// lifecycleHooks holds the callbacks registered on a Server
+type lifecycleHooks struct {
+	start    []func(context.Context) error
+	shutdown []func(context.Context) error
+	request  []func(*Context)
+	response []func(*Context, error)
+}
+
+// OnStart registers a callback run before the server starts listening,
+// e.g. to warm caches or open database pools. An error aborts startup.
+func (s *Server) OnStart(hook func(ctx context.Context) error) {
+	s.hooks.start = append(s.hooks.start, hook)
+}
+
+// OnShutdown registers a callback run after a graceful shutdown, in
+// reverse order of registration
+func (s *Server) OnShutdown(hook func(ctx context.Context) error) {
+	s.hooks.shutdown = append(s.hooks.shutdown, hook)
+}
+
+// OnRequest registers a callback run for every routed request before the
+// middleware chain
+func (s *Server) OnRequest(hook func(ctx *Context)) {
+	s.hooks.request = append(s.hooks.request, hook)
+}
+
+// OnResponse registers a callback run for every routed request after the
+// handler and error handling, with the error the handler returned
+func (s *Server) OnResponse(hook func(ctx *Context, err error)) {
+	s.hooks.response = append(s.hooks.response, hook)
+}
+
+// starting runs the OnStart hooks and startup self-tests
+func (s *Server) starting() error {
+	for _, hook := range s.hooks.start {
+		if err := hook(context.Background()); err != nil {
+			return err
+		}
+	}
+	s.startBufferingCheck()
+	s.startDevReload()
+	return nil
+}
+
+// stopping runs the OnShutdown hooks, logging their errors
+func (s *Server) stopping(ctx context.Context) {
+	for i := len(s.hooks.shutdown) - 1; i >= 0; i-- {
+		if err := s.hooks.shutdown[i](ctx); err != nil {
+			log.Printf("nojs: shutdown hook: %v", err)
+		}
+	}
+}
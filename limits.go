@@ -0,0 +1,53 @@
+package nojs
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// RouteLimits overrides the server-wide timeouts and limits a request
+// body for the routes it is applied to. Zero values keep the server
+// configuration.
+type RouteLimits struct {
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// MaxBodyBytes rejects larger request bodies with 413
+	MaxBodyBytes int64
+}
+
+// Limits middleware applies RouteLimits per route, e.g.
+//
+//	server.Route("/upload", nojs.Limits(nojs.RouteLimits{
+//		ReadTimeout:  5 * time.Minute,
+//		MaxBodyBytes: 100 << 20,
+//	})(uploadHandler))
+//
+// or to a group of routes with Use on a mounted Server. Streaming
+// handlers are exempt from WriteTimeout automatically.
+func Limits(limits RouteLimits) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			rc := http.NewResponseController(ctx.ResponseWriter)
+			if limits.ReadTimeout > 0 {
+				rc.SetReadDeadline(time.Now().Add(limits.ReadTimeout))
+			}
+			if limits.WriteTimeout > 0 {
+				rc.SetWriteDeadline(time.Now().Add(limits.WriteTimeout))
+			}
+			if limits.MaxBodyBytes > 0 {
+				if ctx.Request.ContentLength > limits.MaxBodyBytes {
+					return NewHTTPError(http.StatusRequestEntityTooLarge, "Request Entity Too Large")
+				}
+				ctx.Request.Body = http.MaxBytesReader(ctx.ResponseWriter, ctx.Request.Body, limits.MaxBodyBytes)
+			}
+
+			err := next(ctx)
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return WrapHTTPError(http.StatusRequestEntityTooLarge, "Request Entity Too Large", err)
+			}
+			return err
+		}
+	}
+}
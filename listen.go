@@ -0,0 +1,148 @@
+package nojs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Serve serves requests on an existing listener, e.g. one created by a
+// process supervisor or for tests
+func (s *Server) Serve(l net.Listener) error {
+	return s.ServeWithContext(context.Background(), l)
+}
+
+// ServeWithContext serves requests on l until ctx is done, then drains
+// streams, shuts down gracefully and runs the OnShutdown hooks
+func (s *Server) ServeWithContext(ctx context.Context, l net.Listener) error {
+	srv := s.newHTTPServer(l.Addr().String())
+
+	if err := s.starting(); err != nil {
+		l.Close()
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		s.DrainStreams()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+		s.stopping(shutdownCtx)
+	}()
+
+	fmt.Printf("NoJS server starting on %s\n", l.Addr())
+	err := srv.Serve(l)
+	if errors.Is(err, http.ErrServerClosed) {
+		// Serve returns as soon as Shutdown starts; wait for connections
+		// to drain and the OnShutdown hooks to run
+		<-done
+	}
+	return err
+}
+
+// StartUnix serves requests on a Unix socket at path with the given file
+// mode, e.g. 0660 for a socket shared with nginx or Caddy. A stale socket
+// left by a crashed process is removed first; the socket is removed again
+// when the server stops.
+func (s *Server) StartUnix(path string, perm os.FileMode) error {
+	return s.StartUnixWithContext(context.Background(), path, perm)
+}
+
+// StartUnixWithContext is StartUnix with graceful shutdown when ctx is done
+func (s *Server) StartUnixWithContext(ctx context.Context, path string, perm os.FileMode) error {
+	if err := removeStaleSocket(path); err != nil {
+		return err
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	// Closing a Unix listener unlinks its socket file
+	defer l.Close()
+
+	if err := os.Chmod(path, perm); err != nil {
+		return err
+	}
+	return s.ServeWithContext(ctx, l)
+}
+
+// removeStaleSocket deletes a socket file nobody is listening on
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("nojs: %s exists and is not a socket", path)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("nojs: %s is in use by another process", path)
+	}
+	return os.Remove(path)
+}
+
+// listenFdsStart is the first file descriptor passed by systemd
+const listenFdsStart = 3
+
+// SystemdListeners returns the sockets passed by systemd socket activation
+// (LISTEN_PID and LISTEN_FDS), or nil when the process was not activated
+func SystemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, n)
+	for fd := listenFdsStart; fd < listenFdsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("nojs: systemd fd %d: %w", fd, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// StartSystemd serves requests on the first socket passed by systemd
+// socket activation, falling back to listening on addr when the process
+// was started directly
+func (s *Server) StartSystemd(ctx context.Context, addr string) error {
+	listeners, err := SystemdListeners()
+	if err != nil {
+		return err
+	}
+	if len(listeners) == 0 {
+		return s.StartWithContext(ctx, addr)
+	}
+	for _, l := range listeners[1:] {
+		l.Close()
+	}
+	return s.ServeWithContext(ctx, listeners[0])
+}
@@ -0,0 +1,72 @@
+package nojs
+
+import (
+	"log/slog"
+	"os"
+	"time"
+)
+
+// LoggerConfig configures the Logger middleware
+type LoggerConfig struct {
+	// Logger receives the records; it defaults to slog.Default(), or to a
+	// JSON logger on stderr when JSON is set
+	Logger *slog.Logger
+	JSON   bool
+	// Level is used for successful requests; 4xx are logged at warn and
+	// 5xx at error level
+	Level slog.Level
+}
+
+// Logger middleware logs each request with log/slog: method, path, real
+// response status, bytes written, duration, remote IP, user agent and
+// request ID
+func Logger(config ...LoggerConfig) Middleware {
+	var cfg LoggerConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+		if cfg.JSON {
+			logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+		}
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			start := time.Now()
+			err := next(ctx)
+
+			// Errors are only sent when the handler has not answered yet
+			status := ctx.Status()
+			if err != nil && !ctx.HeadersSent() {
+				status = errorStatus(err)
+			}
+
+			level := cfg.Level
+			switch {
+			case status >= 500:
+				level = slog.LevelError
+			case status >= 400:
+				level = slog.LevelWarn
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", ctx.Request.Method),
+				slog.String("path", ctx.Request.URL.Path),
+				slog.Int("status", status),
+				slog.Int64("bytes", ctx.BytesWritten()),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote_ip", ctx.ClientIP()),
+				slog.String("user_agent", ctx.Request.UserAgent()),
+				slog.String("request_id", ctx.RequestID()),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+			logger.LogAttrs(ctx.Request.Context(), level, "request", attrs...)
+			return err
+		}
+	}
+}
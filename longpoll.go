@@ -0,0 +1,243 @@
+package nojs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// LongPollConfig configures a LongPoll
+type LongPollConfig struct {
+	// Wait is how long a poll blocks when there are no new events
+	Wait time.Duration
+	// History is how many events are kept per room for pollers catching up
+	History int
+	// Idle stops following rooms nobody has polled for this long
+	Idle time.Duration
+	// Param is the query parameter carrying the resume token
+	Param string
+	// CSS are the stylesheets of the pages served by Handler
+	CSS []string
+}
+
+// DefaultLongPollConfig waits 25 seconds, below the idle timeout of most
+// proxies, and keeps the last 100 events of every room
+func DefaultLongPollConfig() LongPollConfig {
+	return LongPollConfig{
+		Wait:    25 * time.Second,
+		History: 100,
+		Idle:    5 * time.Minute,
+		Param:   "since",
+	}
+}
+
+// LongPoll delivers the events of a Hub to clients that poll for them,
+// the fallback real-time transport for networks whose proxies buffer
+// streaming responses. Each poll carries the resume token of the last
+// one and blocks until there are newer events or the wait is over.
+type LongPoll struct {
+	hub    *Hub
+	config LongPollConfig
+
+	mu          sync.Mutex
+	rooms       map[string]*pollRoom
+	lastCleanup time.Time
+}
+
+// pollRoom keeps the recent events of a room followed for pollers
+type pollRoom struct {
+	epoch       string // Changes when the room is followed anew, invalidating tokens
+	events      []Event
+	next        uint64        // Sequence number of the next event
+	changed     chan struct{} // Closed and replaced on every event
+	lastPoll    time.Time
+	unsubscribe func()
+	done        chan struct{} // Closed when the room is no longer followed
+}
+
+// PollResult is the outcome of a poll
+type PollResult struct {
+	// Events are the events published since the token, oldest first
+	Events []Event
+	// Next is the token for the next poll
+	Next string
+	// Reset is true for a first poll and when events were missed, e.g.
+	// after a restart or a long absence. Render the full current state.
+	Reset bool
+}
+
+// NewLongPoll creates a LongPoll following rooms of hub on demand
+func NewLongPoll(hub *Hub, config ...LongPollConfig) *LongPoll {
+	cfg := DefaultLongPollConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+		defaults := DefaultLongPollConfig()
+		if cfg.Wait <= 0 {
+			cfg.Wait = defaults.Wait
+		}
+		if cfg.History <= 0 {
+			cfg.History = defaults.History
+		}
+		if cfg.Idle <= 0 {
+			cfg.Idle = defaults.Idle
+		}
+		if cfg.Param == "" {
+			cfg.Param = defaults.Param
+		}
+	}
+	return &LongPoll{hub: hub, config: cfg, rooms: make(map[string]*pollRoom)}
+}
+
+// room returns the followed room, subscribing to it on first use
+func (lp *LongPoll) room(name string, now time.Time) *pollRoom {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+
+	if now.Sub(lp.lastCleanup) > time.Minute {
+		lp.lastCleanup = now
+		for other, r := range lp.rooms {
+			if now.Sub(r.lastPoll) > lp.config.Idle {
+				r.unsubscribe()
+				close(r.done)
+				delete(lp.rooms, other)
+			}
+		}
+	}
+
+	r := lp.rooms[name]
+	if r == nil {
+		epoch := make([]byte, 4)
+		rand.Read(epoch)
+		events, unsubscribe := lp.hub.Subscribe(name)
+		r = &pollRoom{epoch: hex.EncodeToString(epoch), changed: make(chan struct{}), unsubscribe: unsubscribe, done: make(chan struct{})}
+		lp.rooms[name] = r
+		go lp.follow(r, events)
+	}
+	r.lastPoll = now
+	return r
+}
+
+// follow records the events of a room until it is unsubscribed
+func (lp *LongPoll) follow(r *pollRoom, events <-chan Event) {
+	for {
+		var event Event
+		select {
+		case event = <-events:
+		case <-r.done:
+			return
+		}
+		lp.mu.Lock()
+		r.events = append(r.events, event)
+		if len(r.events) > lp.config.History {
+			r.events = r.events[len(r.events)-lp.config.History:]
+		}
+		r.next++
+		close(r.changed)
+		r.changed = make(chan struct{})
+		lp.mu.Unlock()
+	}
+}
+
+// Poll returns the events of room published after token, waiting up to
+// the configured time for one if there are none yet. An empty token
+// starts following the room and returns at once.
+func (lp *LongPoll) Poll(ctx context.Context, room, token string) (PollResult, error) {
+	r := lp.room(room, time.Now())
+	timer := time.NewTimer(lp.config.Wait)
+	defer timer.Stop()
+
+	for {
+		lp.mu.Lock()
+		result, ready := r.since(token)
+		changed := r.changed
+		lp.mu.Unlock()
+		if ready {
+			for _, event := range result.Events {
+				lp.hub.delivered(event)
+			}
+			return result, nil
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return result, nil
+		case <-ctx.Done():
+			return result, ctx.Err()
+		}
+	}
+}
+
+// since returns the events after token, reporting whether the poll can
+// be answered at once. The caller must hold the LongPoll's lock.
+func (r *pollRoom) since(token string) (PollResult, bool) {
+	result := PollResult{Next: r.epoch + "." + strconv.FormatUint(r.next, 10)}
+	epoch, seqText, _ := strings.Cut(token, ".")
+	seq, err := strconv.ParseUint(seqText, 10, 64)
+	oldest := r.next - uint64(len(r.events))
+	if epoch != r.epoch || err != nil || seq < oldest || seq > r.next {
+		result.Events = append([]Event(nil), r.events...)
+		result.Reset = true
+		return result, true
+	}
+	if seq == r.next {
+		return result, false
+	}
+	result.Events = append([]Event(nil), r.events[seq-oldest:]...)
+	return result, true
+}
+
+// Handler serves a page that long-polls the room returned by room and
+// reloads itself with a meta refresh as soon as the poll is answered,
+// so it shows new events without JavaScript. Embed it where the updates
+// should appear, usually in an iframe:
+//
+//	poll := nojs.NewLongPoll(hub)
+//	server.GET("/updates", poll.Handler(
+//		func(ctx *nojs.Context) string { return ctx.Query("room") },
+//		func(ctx *nojs.Context, result nojs.PollResult) g.Node { return messageList(ctx.Query("room")) },
+//	))
+//
+// Server shutdown answers pending polls at once.
+func (lp *LongPoll) Handler(room func(ctx *Context) string, render func(ctx *Context, result PollResult) g.Node) Handler {
+	return func(ctx *Context) error {
+		pollCtx, cancel := context.WithCancel(ctx.Request.Context())
+		defer cancel()
+		go func() {
+			select {
+			case <-ctx.server.root().shutdown:
+				cancel()
+			case <-pollCtx.Done():
+			}
+		}()
+
+		result, err := lp.Poll(pollCtx, room(ctx), ctx.Query(lp.config.Param))
+		if err != nil && ctx.Request.Context().Err() != nil {
+			return nil // Client gone
+		}
+
+		q := ctx.Request.URL.Query()
+		q.Set(lp.config.Param, result.Next)
+		next := (&url.URL{Path: ctx.URL(ctx.Request.URL.Path), RawQuery: q.Encode()}).String()
+
+		ctx.NoStore()
+		return ctx.HTML(http.StatusOK, Page{
+			CSS: lp.config.CSS,
+			Head: []g.Node{h.Meta(
+				g.Attr("http-equiv", "refresh"),
+				g.Attr("content", fmt.Sprintf("0; url=%s", next)),
+			)},
+			Body: render(ctx, result),
+		}.Render())
+	}
+}
@@ -0,0 +1,162 @@
+package nojs
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// mediaTypes covers media extensions missing from many system mime tables
+var mediaTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".oga":  "audio/ogg",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".webm": "video/webm",
+	".ogv":  "video/ogg",
+	".mov":  "video/quicktime",
+	".vtt":  "text/vtt",
+}
+
+// MediaType returns the content type for a media file name
+func MediaType(name string) string {
+	ext := path.Ext(name)
+	if t, ok := mediaTypes[ext]; ok {
+		return t
+	}
+	return mime.TypeByExtension(ext)
+}
+
+// Media serves audio and video files from a directory with byte-range
+// support, so browsers can seek and resume playback
+func (s *Server) Media(pattern string, dir string) {
+	s.routes = append(s.routes, routeRecord{pattern: pattern, name: "media files from " + dir})
+	s.mux.Handle(pattern, http.StripPrefix(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveMedia(w, r, filepath.Join(dir, filepath.FromSlash(path.Clean("/"+r.URL.Path))))
+	})))
+}
+
+// ServeMedia sends a media file with byte-range support. Single and
+// multi-range requests are answered with 206 Partial Content.
+func (c *Context) ServeMedia(file string) error {
+	c.written = true
+	return serveMedia(c.ResponseWriter, c.Request, file)
+}
+
+func serveMedia(w http.ResponseWriter, r *http.Request, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		http.NotFound(w, r)
+		return nil
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return nil
+	}
+
+	if ctype := MediaType(file); ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	return nil
+}
+
+// MediaSource is one encoding of an audio or video resource
+type MediaSource struct {
+	Src  string
+	Type string // Detected from Src when empty
+}
+
+// MediaTrack is a text track such as subtitles or captions
+type MediaTrack struct {
+	Src     string
+	Kind    string // "subtitles", "captions", ...
+	Lang    string
+	Label   string
+	Default bool
+}
+
+// MediaConfig configures an Audio or Video element
+type MediaConfig struct {
+	Sources  []MediaSource
+	Tracks   []MediaTrack
+	Poster   string // Video only
+	Controls bool
+	Autoplay bool
+	Loop     bool
+	Muted    bool
+	Preload  string // "none", "metadata" or "auto"
+	Class    string
+}
+
+// Audio creates an audio player with source fallbacks and a download link
+// for browsers that cannot play any of the sources
+func Audio(config MediaConfig) g.Node {
+	return h.Audio(mediaNodes(config)...)
+}
+
+// Video creates a video player with source fallbacks and a download link
+// for browsers that cannot play any of the sources
+func Video(config MediaConfig) g.Node {
+	nodes := mediaNodes(config)
+	if config.Poster != "" {
+		nodes = append([]g.Node{h.Poster(config.Poster)}, nodes...)
+	}
+	return h.Video(nodes...)
+}
+
+func mediaNodes(config MediaConfig) []g.Node {
+	nodes := []g.Node{
+		g.If(config.Controls, h.Controls()),
+		g.If(config.Autoplay, h.AutoPlay()),
+		g.If(config.Loop, h.Loop()),
+		g.If(config.Muted, h.Muted()),
+		g.If(config.Preload != "", h.Preload(config.Preload)),
+		g.If(config.Class != "", h.Class(config.Class)),
+	}
+
+	for _, src := range config.Sources {
+		mediaType := src.Type
+		if mediaType == "" {
+			mediaType = MediaType(src.Src)
+		}
+		nodes = append(nodes, h.Source(h.Src(src.Src), g.If(mediaType != "", h.Type(mediaType))))
+	}
+
+	for _, track := range config.Tracks {
+		nodes = append(nodes, g.El("track",
+			h.Src(track.Src),
+			g.If(track.Kind != "", g.Attr("kind", track.Kind)),
+			g.If(track.Lang != "", g.Attr("srclang", track.Lang)),
+			g.If(track.Label != "", g.Attr("label", track.Label)),
+			g.If(track.Default, g.Attr("default")),
+		))
+	}
+
+	// Fallback content for browsers without support for any source
+	if len(config.Sources) > 0 {
+		nodes = append(nodes, h.P(
+			g.Text("Your browser cannot play this media. "),
+			h.A(h.Href(config.Sources[0].Src), g.Attr("download"), g.Text("Download it")),
+			g.Text(" instead."),
+		))
+	}
+
+	return nodes
+}
@@ -0,0 +1,300 @@
+// Package metrics exposes Prometheus metrics for a nojs server: request
+// counts and latency by route pattern, in-flight requests, open streams,
+// bytes streamed and the rooms of registered hubs
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jairo/mavis/nojs"
+)
+
+// Config configures the metrics endpoint
+type Config struct {
+	// Path serves the metrics in the Prometheus text format
+	Path string
+	// Buckets are the latency histogram upper bounds in seconds
+	Buckets []float64
+}
+
+// DefaultConfig returns sensible defaults
+func DefaultConfig() Config {
+	return Config{
+		Path:    "/metrics",
+		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}
+}
+
+// Metrics collects request and stream metrics
+type Metrics struct {
+	buckets []float64
+
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	latencies map[string]*histogram
+	hubs      map[string]*nojs.Hub
+
+	inFlight atomic.Int64
+	server   *nojs.Server
+}
+
+type requestKey struct {
+	route, method string
+	code          int
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// New creates an empty collector
+func New(config ...Config) *Metrics {
+	cfg := DefaultConfig()
+	if len(config) > 0 && len(config[0].Buckets) > 0 {
+		cfg.Buckets = config[0].Buckets
+	}
+	return &Metrics{
+		buckets:   cfg.Buckets,
+		requests:  make(map[requestKey]uint64),
+		latencies: make(map[string]*histogram),
+		hubs:      make(map[string]*nojs.Hub),
+	}
+}
+
+// Hub exports the per-room counters of hub, labelled with name
+func (m *Metrics) Hub(name string, hub *nojs.Hub) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hubs[name] = hub
+}
+
+// Streams exports the open streams and streamed bytes of s. Register
+// calls it for the server it is given.
+func (m *Metrics) Streams(s *nojs.Server) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.server = s
+}
+
+// Register adds the metrics middleware to s and serves the metrics at
+// Config.Path. Put the endpoint behind authentication or on an internal
+// listener in production.
+func Register(s *nojs.Server, config ...Config) *Metrics {
+	cfg := DefaultConfig()
+	if len(config) > 0 {
+		if config[0].Path != "" {
+			cfg.Path = config[0].Path
+		}
+		if len(config[0].Buckets) > 0 {
+			cfg.Buckets = config[0].Buckets
+		}
+	}
+
+	m := New(cfg)
+	m.Streams(s)
+	s.Use(m.Middleware())
+	s.GET(cfg.Path, func(ctx *nojs.Context) error {
+		ctx.ResponseWriter.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		ctx.ResponseWriter.WriteHeader(http.StatusOK)
+		return m.Write(ctx.ResponseWriter)
+	})
+	return m
+}
+
+// Middleware records every request, including those that panic. Errors
+// returned after the response started are counted with the status the
+// client got.
+func (m *Metrics) Middleware() nojs.Middleware {
+	return func(next nojs.Handler) nojs.Handler {
+		return func(ctx *nojs.Context) (err error) {
+			start := time.Now()
+			m.inFlight.Add(1)
+			returned := false
+			defer func() {
+				m.inFlight.Add(-1)
+				status := ctx.Status()
+				if !ctx.HeadersSent() {
+					switch {
+					case !returned:
+						status = http.StatusInternalServerError
+					case err != nil:
+						status = http.StatusInternalServerError
+						var httpErr *nojs.HTTPError
+						if errors.As(err, &httpErr) {
+							status = httpErr.Code
+						}
+					}
+				}
+				m.observe(ctx.Route(), methodLabel(ctx.Request.Method), status, time.Since(start))
+			}()
+			err = next(ctx)
+			returned = true
+			return err
+		}
+	}
+}
+
+// methodLabel returns method for the standard methods and "OTHER" for
+// the rest, so clients cannot create a series per made-up method
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
+
+// observe records a finished request
+func (m *Metrics) observe(route, method string, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{route, method, status}]++
+
+	hist := m.latencies[route]
+	if hist == nil {
+		hist = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.latencies[route] = hist
+	}
+	seconds := d.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			hist.counts[i]++
+		}
+	}
+	hist.sum += seconds
+	hist.count++
+}
+
+// Write writes the metrics in the Prometheus text exposition format
+func (m *Metrics) Write(w io.Writer) error {
+	var b strings.Builder
+
+	m.mu.Lock()
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].code < keys[j].code
+	})
+	b.WriteString("# HELP nojs_http_requests_total Requests handled, by route pattern, method and status.\n")
+	b.WriteString("# TYPE nojs_http_requests_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "nojs_http_requests_total{route=%s,method=%s,code=\"%d\"} %d\n",
+			label(k.route), label(k.method), k.code, m.requests[k])
+	}
+
+	server := m.server
+	routes := make([]string, 0, len(m.latencies))
+	for route := range m.latencies {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	b.WriteString("# HELP nojs_http_request_duration_seconds Request latency, by route pattern.\n")
+	b.WriteString("# TYPE nojs_http_request_duration_seconds histogram\n")
+	for _, route := range routes {
+		hist := m.latencies[route]
+		for i, bound := range m.buckets {
+			fmt.Fprintf(&b, "nojs_http_request_duration_seconds_bucket{route=%s,le=\"%s\"} %d\n",
+				label(route), strconv.FormatFloat(bound, 'g', -1, 64), hist.counts[i])
+		}
+		fmt.Fprintf(&b, "nojs_http_request_duration_seconds_bucket{route=%s,le=\"+Inf\"} %d\n", label(route), hist.count)
+		fmt.Fprintf(&b, "nojs_http_request_duration_seconds_sum{route=%s} %g\n", label(route), hist.sum)
+		fmt.Fprintf(&b, "nojs_http_request_duration_seconds_count{route=%s} %d\n", label(route), hist.count)
+	}
+	m.mu.Unlock()
+
+	b.WriteString("# HELP nojs_http_requests_in_flight Requests being handled.\n")
+	b.WriteString("# TYPE nojs_http_requests_in_flight gauge\n")
+	fmt.Fprintf(&b, "nojs_http_requests_in_flight %d\n", m.inFlight.Load())
+	if server != nil {
+		open, bytes := server.StreamStats()
+		b.WriteString("# HELP nojs_streams_active Open streaming responses.\n")
+		b.WriteString("# TYPE nojs_streams_active gauge\n")
+		fmt.Fprintf(&b, "nojs_streams_active %d\n", open)
+		b.WriteString("# HELP nojs_stream_bytes_total Bytes written to streaming responses.\n")
+		b.WriteString("# TYPE nojs_stream_bytes_total counter\n")
+		fmt.Fprintf(&b, "nojs_stream_bytes_total %d\n", bytes)
+	}
+
+	m.writeHubs(&b)
+
+	b.WriteString("# HELP nojs_boundary_trips_total Failures contained by nojs.Boundary, by call site.\n")
+	b.WriteString("# TYPE nojs_boundary_trips_total counter\n")
+	for _, bs := range nojs.BoundaryStats() {
+		fmt.Fprintf(&b, "nojs_boundary_trips_total{location=%s} %d\n", label(bs.Location), bs.Trips)
+	}
+
+	_, err := w.Write([]byte(b.String()))
+	return err
+}
+
+// hubMetrics describes the per-room hub metrics
+var hubMetrics = []struct {
+	name, kind, help string
+	value            func(nojs.RoomStats) string
+}{
+	{"nojs_hub_subscribers", "gauge", "Streams subscribed to a hub room.",
+		func(rs nojs.RoomStats) string { return strconv.Itoa(rs.Subscribers) }},
+	{"nojs_hub_backlog", "gauge", "Events waiting in subscriber buffers.",
+		func(rs nojs.RoomStats) string { return strconv.Itoa(rs.Backlog) }},
+	{"nojs_hub_published_total", "counter", "Events published to a hub room.",
+		func(rs nojs.RoomStats) string { return strconv.FormatUint(rs.Published, 10) }},
+	{"nojs_hub_delivered_total", "counter", "Events written to subscriber streams.",
+		func(rs nojs.RoomStats) string { return strconv.FormatUint(rs.Delivered, 10) }},
+	{"nojs_hub_dropped_total", "counter", "Events missed by subscribers with full buffers.",
+		func(rs nojs.RoomStats) string { return strconv.FormatUint(rs.Dropped, 10) }},
+	{"nojs_hub_delivery_seconds_total", "counter", "Total time from publish to delivery; divide by delivered for the mean.",
+		func(rs nojs.RoomStats) string { return strconv.FormatFloat(rs.DeliveryTime.Seconds(), 'g', -1, 64) }},
+}
+
+// writeHubs writes the metrics of every room of the registered hubs
+func (m *Metrics) writeHubs(b *strings.Builder) {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.hubs))
+	for name := range m.hubs {
+		names = append(names, name)
+	}
+	hubs := make(map[string][]nojs.RoomStats, len(m.hubs))
+	for name, hub := range m.hubs {
+		hubs[name] = hub.Stats()
+	}
+	m.mu.Unlock()
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	for _, metric := range hubMetrics {
+		fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, name := range names {
+			for _, rs := range hubs[name] {
+				fmt.Fprintf(b, "%s{hub=%s,room=%s} %s\n", metric.name, label(name), label(rs.Room), metric.value(rs))
+			}
+		}
+	}
+}
+
+// label quotes a label value
+func label(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
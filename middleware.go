@@ -1,46 +1,21 @@
 package nojs
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"time"
 )
 
-// Logger middleware logs HTTP requests
-func Logger() Middleware {
-	return func(next Handler) Handler {
-		return func(ctx *Context) error {
-			start := time.Now()
-			
-			// Call the next handler
-			err := next(ctx)
-			
-			// Log the request
-			duration := time.Since(start)
-			status := http.StatusOK
-			if err != nil {
-				if httpErr, ok := err.(*HTTPError); ok {
-					status = httpErr.Code
-				} else {
-					status = http.StatusInternalServerError
-				}
-			}
-			
-			log.Printf("%s %s %d %v", ctx.Request.Method, ctx.Request.URL.Path, status, duration)
-			
-			return err
-		}
-	}
-}
-
 // Recovery middleware recovers from panics
 func Recovery() Middleware {
 	return func(next Handler) Handler {
 		return func(ctx *Context) (err error) {
 			defer func() {
 				if r := recover(); r != nil {
+					if r == http.ErrAbortHandler {
+						panic(r) // Deliberately aborted response
+					}
 					log.Printf("Panic recovered: %v", r)
 					err = NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
 				}
@@ -111,7 +86,7 @@ func RequireMethod(methods ...string) Middleware {
 	}
 }
 
-// RateLimit implements basic rate limiting
+// RateLimit implements basic rate limiting per client IP, see ctx.ClientIP
 func RateLimit(requests int, duration time.Duration) Middleware {
 	type visitor struct {
 		count    int
@@ -122,7 +97,7 @@ func RateLimit(requests int, duration time.Duration) Middleware {
 	
 	return func(next Handler) Handler {
 		return func(ctx *Context) error {
-			ip := ctx.Request.RemoteAddr
+			ip := ctx.ClientIP()
 			now := time.Now()
 			
 			v, exists := visitors[ip]
@@ -170,70 +145,8 @@ func BasicAuth(realm string, users map[string]string) Middleware {
 				return NewHTTPError(http.StatusUnauthorized, "Unauthorized")
 			}
 			
+			ctx.Set(KeyUser, username)
 			return next(ctx)
 		}
 	}
 }
-
-// Session represents a simple session store
-type Session struct {
-	store map[string]interface{}
-}
-
-// Get retrieves a value from the session
-func (s *Session) Get(key string) interface{} {
-	return s.store[key]
-}
-
-// Set stores a value in the session
-func (s *Session) Set(key string, value interface{}) {
-	s.store[key] = value
-}
-
-// SessionManager provides simple cookie-based sessions
-func SessionManager(secret string) Middleware {
-	// This is a simplified implementation
-	// In production, use proper session management with encryption
-	sessions := make(map[string]*Session)
-	
-	return func(next Handler) Handler {
-		return func(ctx *Context) error {
-			// Get or create session ID
-			cookie, err := ctx.Request.Cookie("session")
-			sessionID := ""
-			
-			if err == nil {
-				sessionID = cookie.Value
-			} else {
-				sessionID = fmt.Sprintf("%d", time.Now().UnixNano())
-				http.SetCookie(ctx.ResponseWriter, &http.Cookie{
-					Name:     "session",
-					Value:    sessionID,
-					Path:     "/",
-					HttpOnly: true,
-					SameSite: http.SameSiteStrictMode,
-				})
-			}
-			
-			// Get or create session
-			session, exists := sessions[sessionID]
-			if !exists {
-				session = &Session{store: make(map[string]interface{})}
-				sessions[sessionID] = session
-			}
-			
-			// Add session to request context
-			ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), "session", session))
-			
-			return next(ctx)
-		}
-	}
-}
-
-// GetSession retrieves the session from context
-func GetSession(ctx *Context) *Session {
-	if session, ok := ctx.Request.Context().Value("session").(*Session); ok {
-		return session
-	}
-	return nil
-}
\ No newline at end of file
@@ -0,0 +1,242 @@
+// Package migrate runs SQL schema migrations shipped as embedded files.
+// Files are named <version>_<name>.up.sql and <version>_<name>.down.sql,
+// e.g. 0001_create_users.up.sql. Applied versions are recorded in a
+// table, per source, so several modules can ship their own schema:
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	m := migrate.New(db, migrate.Source{Name: "app", FS: migrations, Dir: "migrations"})
+//	server.OnStart(m.Up)
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Source is a set of migration files
+type Source struct {
+	// Name identifies the source in the version table, e.g. "auth"
+	Name string
+	FS   fs.FS
+	Dir  string
+}
+
+// Migration is one versioned schema change
+type Migration struct {
+	Source  string
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+	DB *sql.DB
+	// Table records applied versions
+	Table   string
+	Sources []Source
+}
+
+// New creates a Migrator recording versions in "schema_migrations"
+func New(db *sql.DB, sources ...Source) *Migrator {
+	return &Migrator{DB: db, Table: "schema_migrations", Sources: sources}
+}
+
+// Load reads the migrations of a source, sorted by version
+func Load(src Source) ([]Migration, error) {
+	dir := src.Dir
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := fs.ReadDir(src.FS, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		file := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(file, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(file, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(file, "."+direction+".sql")
+		num, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %s: file name must start with a version number", file)
+		}
+		body, err := fs.ReadFile(src.FS, path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Source: src.Name, Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migrate: %s version %d has no up migration", src.Name, m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// init creates the version table
+func (m *Migrator) init(ctx context.Context) error {
+	_, err := m.DB.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (source VARCHAR(255) NOT NULL, version INTEGER NOT NULL, PRIMARY KEY (source, version))",
+		m.Table))
+	return err
+}
+
+// applied returns the applied versions of a source
+func (m *Migrator) applied(ctx context.Context, source string) (map[int]bool, error) {
+	rows, err := m.DB.QueryContext(ctx, fmt.Sprintf("SELECT version FROM %s WHERE source = '%s'", m.Table, quote(source)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := map[int]bool{}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		versions[v] = true
+	}
+	return versions, rows.Err()
+}
+
+// Up applies all pending migrations of every source in version order,
+// each in its own transaction. Its signature fits Server.OnStart.
+func (m *Migrator) Up(ctx context.Context) error {
+	if err := m.init(ctx); err != nil {
+		return err
+	}
+	for _, src := range m.Sources {
+		migrations, err := Load(src)
+		if err != nil {
+			return err
+		}
+		applied, err := m.applied(ctx, src.Name)
+		if err != nil {
+			return err
+		}
+		for _, mig := range migrations {
+			if applied[mig.Version] {
+				continue
+			}
+			record := fmt.Sprintf("INSERT INTO %s (source, version) VALUES ('%s', %d)", m.Table, quote(src.Name), mig.Version)
+			if err := m.run(ctx, mig.Up, record); err != nil {
+				return fmt.Errorf("migrate: %s %d_%s up: %w", src.Name, mig.Version, mig.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Down reverts the latest steps applied migrations of the named source
+func (m *Migrator) Down(ctx context.Context, source string, steps int) error {
+	if err := m.init(ctx); err != nil {
+		return err
+	}
+	for _, src := range m.Sources {
+		if src.Name != source {
+			continue
+		}
+		migrations, err := Load(src)
+		if err != nil {
+			return err
+		}
+		applied, err := m.applied(ctx, src.Name)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			mig := migrations[i]
+			if !applied[mig.Version] {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migrate: %s %d_%s has no down migration", src.Name, mig.Version, mig.Name)
+			}
+			record := fmt.Sprintf("DELETE FROM %s WHERE source = '%s' AND version = %d", m.Table, quote(src.Name), mig.Version)
+			if err := m.run(ctx, mig.Down, record); err != nil {
+				return fmt.Errorf("migrate: %s %d_%s down: %w", src.Name, mig.Version, mig.Name, err)
+			}
+			steps--
+		}
+		return nil
+	}
+	return fmt.Errorf("migrate: unknown source %q", source)
+}
+
+// Version returns the highest applied version of a source
+func (m *Migrator) Version(ctx context.Context, source string) (int, error) {
+	if err := m.init(ctx); err != nil {
+		return 0, err
+	}
+	applied, err := m.applied(ctx, source)
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// run executes a migration and its bookkeeping statement in a transaction
+func (m *Migrator) run(ctx context.Context, statements, record string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, statements); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// quote escapes a string literal. Source names come from code, but the
+// version table statements avoid driver-specific placeholders.
+func quote(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
@@ -0,0 +1,253 @@
+package nojs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidMoney is returned when an amount cannot be parsed
+var ErrInvalidMoney = errors.New("invalid amount")
+
+// ErrCurrencyMismatch is returned when combining amounts of different
+// currencies
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// ErrMoneyOverflow is returned when an amount does not fit in int64
+// minor units
+var ErrMoneyOverflow = errors.New("amount out of range")
+
+// Money is an amount in minor units (e.g. cents) of a currency. Keeping
+// integer minor units avoids floating point rounding errors.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// currencyDigits lists currencies whose minor unit is not 1/100
+var currencyDigits = map[string]int{
+	"JPY": 0, "KRW": 0, "CLP": 0, "ISK": 0, "VND": 0,
+	"BHD": 3, "KWD": 3, "OMR": 3, "JOD": 3, "TND": 3,
+}
+
+// CurrencyDigits returns the number of minor unit digits of a currency
+func CurrencyDigits(currency string) int {
+	if d, ok := currencyDigits[strings.ToUpper(currency)]; ok {
+		return d
+	}
+	return 2
+}
+
+// NewMoney creates an amount from minor units
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// ParseMoney parses user input such as "12", "12.5", "1,234.56" or
+// "1.234,56". The last separator followed by at most the currency's
+// minor digits is the decimal separator; other separators are grouping.
+func ParseMoney(input, currency string) (Money, error) {
+	digits := CurrencyDigits(currency)
+	s := strings.TrimSpace(input)
+	s = strings.NewReplacer(" ", "", "\u00a0", "", "'", "").Replace(s)
+
+	negative := false
+	if strings.HasPrefix(s, "-") {
+		negative = true
+		s = s[1:]
+	}
+	if s == "" {
+		return Money{}, ErrInvalidMoney
+	}
+
+	whole, frac := s, ""
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 && len(s)-i-1 <= digits && len(s)-i-1 > 0 && digits > 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	whole = strings.NewReplacer(",", "", ".", "").Replace(whole)
+	if whole == "" {
+		whole = "0"
+	}
+	// ParseInt would accept a sign in either part, e.g. "12.-5"
+	if !isDigits(whole) || !isDigits(frac) {
+		return Money{}, ErrInvalidMoney
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return Money{}, ErrMoneyOverflow
+	}
+	for len(frac) < digits {
+		frac += "0"
+	}
+	minor := int64(0)
+	if frac != "" {
+		if minor, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return Money{}, ErrInvalidMoney
+		}
+	}
+
+	if units > (math.MaxInt64-minor)/pow10(digits) {
+		return Money{}, ErrMoneyOverflow
+	}
+	amount := units*pow10(digits) + minor
+	if negative {
+		amount = -amount
+	}
+	return NewMoney(amount, currency), nil
+}
+
+// isDigits reports whether s consists of ASCII digits only
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+// match returns ErrCurrencyMismatch when two amounts have different
+// currencies
+func (m Money) match(o Money) error {
+	if m.Currency != o.Currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	return nil
+}
+
+// Add returns m + o. It fails if the currencies differ or the sum
+// overflows.
+func (m Money) Add(o Money) (Money, error) {
+	if err := m.match(o); err != nil {
+		return Money{}, err
+	}
+	sum := m.Amount + o.Amount
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{sum, m.Currency}, nil
+}
+
+// Sub returns m - o. It fails if the currencies differ or the difference
+// overflows.
+func (m Money) Sub(o Money) (Money, error) {
+	if err := m.match(o); err != nil {
+		return Money{}, err
+	}
+	diff := m.Amount - o.Amount
+	if (o.Amount > 0 && diff > m.Amount) || (o.Amount < 0 && diff < m.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{diff, m.Currency}, nil
+}
+
+// Mul returns m multiplied by a quantity
+func (m Money) Mul(quantity int64) Money {
+	return Money{m.Amount * quantity, m.Currency}
+}
+
+// MulRate returns m multiplied by a rate in basis points (2100 = 21%),
+// rounded half away from zero
+func (m Money) MulRate(basisPoints int64) Money {
+	return Money{ApplyRate(m.Amount, basisPoints), m.Currency}
+}
+
+// Allocate splits m into n parts that add up to m exactly, spreading the
+// remainder over the first parts
+func (m Money) Allocate(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+	parts := make([]Money, n)
+	share, rest := m.Amount/int64(n), m.Amount%int64(n)
+	for i := range parts {
+		parts[i] = Money{share, m.Currency}
+		if int64(i) < rest {
+			parts[i].Amount++
+		} else if int64(i) < -rest {
+			parts[i].Amount--
+		}
+	}
+	return parts
+}
+
+// Neg returns -m
+func (m Money) Neg() Money { return Money{-m.Amount, m.Currency} }
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool { return m.Amount == 0 }
+
+// Cmp compares m and o, returning -1, 0 or +1. It fails if the
+// currencies differ.
+func (m Money) Cmp(o Money) (int, error) {
+	if err := m.match(o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Decimal formats the amount without currency, e.g. "1234.50", suitable
+// for form input values
+func (m Money) Decimal() string {
+	digits := CurrencyDigits(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	if digits == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+	p := pow10(digits)
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/p, digits, amount%p)
+}
+
+// String formats the amount with grouping and currency, e.g. "1,234.50 EUR"
+func (m Money) String() string {
+	dec := m.Decimal()
+	sign := ""
+	if strings.HasPrefix(dec, "-") {
+		sign, dec = "-", dec[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(dec, ".")
+
+	var grouped strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(r)
+	}
+
+	s := sign + grouped.String()
+	if hasFrac {
+		s += "." + frac
+	}
+	if m.Currency != "" {
+		s += " " + m.Currency
+	}
+	return s
+}
+
+// FormMoney parses a form field as an amount in currency
+func (c *Context) FormMoney(name, currency string) (Money, error) {
+	return ParseMoney(c.Form(name), currency)
+}
@@ -0,0 +1,128 @@
+package nojs
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Accepts returns the type among offers the client prefers according to
+// its Accept header, or "" when it accepts none of them. Offers are full
+// media types such as "application/json"; on a tie the earlier offer
+// wins. A missing Accept header accepts anything.
+func (c *Context) Accepts(offers ...string) string {
+	return negotiateType(c.Request.Header.Get("Accept"), offers)
+}
+
+// Negotiate calls the offer for the representation the client prefers,
+// so one handler can serve HTML to browsers and JSON to API clients.
+// The response is sent with status, whatever status the offer passes
+// to ctx.HTML or ctx.JSON. Clients accepting none of the offers get 406.
+// HTML wins ties, e.g. for "Accept: */*".
+//
+//	return ctx.Negotiate(http.StatusOK, map[string]func() error{
+//		"text/html":        func() error { return ctx.HTML(http.StatusOK, page) },
+//		"application/json": func() error { return ctx.JSON(http.StatusOK, todos) },
+//	})
+func (c *Context) Negotiate(status int, offers map[string]func() error) error {
+	types := make([]string, 0, len(offers))
+	for t := range offers {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if (types[i] == "text/html") != (types[j] == "text/html") {
+			return types[i] == "text/html"
+		}
+		return types[i] < types[j]
+	})
+
+	c.ResponseWriter.Header().Add("Vary", "Accept")
+	chosen := c.Accepts(types...)
+	if chosen == "" {
+		return NewHTTPError(http.StatusNotAcceptable, "Not Acceptable, available: "+strings.Join(types, ", "))
+	}
+
+	w := c.ResponseWriter
+	c.ResponseWriter = &statusOverride{ResponseWriter: w, status: status}
+	defer func() { c.ResponseWriter = w }()
+	return offers[chosen]()
+}
+
+// statusOverride replaces the status code written through it
+type statusOverride struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusOverride) WriteHeader(status int) {
+	if informational(status) {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *statusOverride) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// mediaRange is one entry of an Accept header
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// negotiateType picks the offer with the highest q-value, taking each
+// offer's q from the most specific matching range
+func negotiateType(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		if len(offers) > 0 {
+			return offers[0]
+		}
+		return ""
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mediaType)), "/")
+		if !ok {
+			continue
+		}
+		r := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		typ, subtype, _ := strings.Cut(strings.ToLower(offer), "/")
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			s := -1
+			switch {
+			case r.typ == typ && r.subtype == subtype:
+				s = 2
+			case r.typ == typ && r.subtype == "*":
+				s = 1
+			case r.typ == "*" && r.subtype == "*":
+				s = 0
+			}
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,294 @@
+// Package nojstest provides helpers for testing nojs applications
+package nojstest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jairo/mavis/nojs"
+)
+
+// FuzzConfig configures FuzzRoutes
+type FuzzConfig struct {
+	// Seed makes runs reproducible; the seed is logged on failure
+	Seed int64
+	// Iterations is the number of random requests per route
+	Iterations int
+	// Fields are form field names to use besides random ones, e.g. the
+	// fields your handlers read
+	Fields []string
+	// MaxFieldSize is the largest generated field value
+	MaxFieldSize int
+	// Timeout ends streaming handlers
+	Timeout time.Duration
+	// Skip excludes route patterns, e.g. ones with side effects on
+	// external systems
+	Skip []string
+}
+
+// DefaultFuzzConfig returns sensible defaults
+func DefaultFuzzConfig() FuzzConfig {
+	return FuzzConfig{
+		Seed:         time.Now().UnixNano(),
+		Iterations:   50,
+		MaxFieldSize: 1 << 20,
+		Timeout:      time.Second,
+	}
+}
+
+// methods includes ones routes rarely handle, to check 405 handling
+var methods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// overrides are _method values sent with POST, valid and not
+var overrides = []string{"", "PUT", "PATCH", "DELETE", "GET", "delete", "TRACE", "CONNECT", "X\x00Y", strings.Repeat("A", 300)}
+
+// FuzzRoutes sends malformed and hostile requests to every route of s:
+// broken URL encoding, oversized and invalid UTF-8 values, thousands of
+// parameters, wrong content types and method overrides. A route fails
+// when its handler panics or responds with a 5xx status, or when a method
+// it does not handle gets anything but 405 Method Not Allowed with an
+// Allow header. A path no route matches must get 404 Not Found.
+func FuzzRoutes(t testing.TB, s *nojs.Server, config ...FuzzConfig) {
+	t.Helper()
+	cfg := DefaultFuzzConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	for _, route := range s.Routes() {
+		if route.Host != "" || skipped(cfg.Skip, route.Pattern) {
+			continue
+		}
+		path := route.Pattern
+		if strings.HasSuffix(path, "/") {
+			path += randomToken(rnd)
+		}
+
+		if err := checkMethods(s, route, path, cfg.Timeout); err != nil {
+			t.Errorf("seed %d: %s: %v", cfg.Seed, route.Pattern, err)
+		}
+		for i := 0; i < cfg.Iterations; i++ {
+			req := randomRequest(rnd, cfg, path)
+			if err := Check(s, req, cfg.Timeout); err != nil {
+				t.Errorf("seed %d: %s %s: %v", cfg.Seed, req.Method, route.Pattern, err)
+				break
+			}
+		}
+	}
+
+	if err := checkNotFound(s, "/nojs-fuzz-"+randomToken(rnd), cfg.Timeout); err != nil {
+		t.Errorf("seed %d: %v", cfg.Seed, err)
+	}
+}
+
+// checkMethods sends each method route does not handle and expects 405
+// with an Allow header. OPTIONS is left out as CORS middleware answers
+// it, and 401 and 403 are accepted from middleware that rejects the
+// request before it is routed, e.g. CSRF checks on POST.
+func checkMethods(s *nojs.Server, route nojs.RouteInfo, path string, timeout time.Duration) error {
+	if len(route.Methods) == 0 {
+		return nil
+	}
+	for _, method := range methods {
+		if method == http.MethodOptions || contains(route.Methods, method) {
+			continue
+		}
+		rec, err := serve(s, httptest.NewRequest(method, path, nil), timeout)
+		if err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+		switch {
+		case rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden:
+		case rec.Code != http.StatusMethodNotAllowed:
+			return fmt.Errorf("%s: status %d, want %d", method, rec.Code, http.StatusMethodNotAllowed)
+		case rec.Header().Get("Allow") == "":
+			return fmt.Errorf("%s: 405 without Allow header", method)
+		}
+	}
+	return nil
+}
+
+// checkNotFound requests path, which no route should match, and expects
+// 404. A redirect to the canonical form of path is followed once. The
+// check is skipped when s has a catch-all route.
+func checkNotFound(s *nojs.Server, path string, timeout time.Duration) error {
+	for _, route := range s.Routes() {
+		if route.Host == "" && (route.Pattern == "/" || strings.HasPrefix(route.Pattern, "/{")) {
+			return nil
+		}
+	}
+	rec, err := serve(s, httptest.NewRequest(http.MethodGet, path, nil), timeout)
+	if err == nil && rec.Code >= 300 && rec.Code < 400 && rec.Header().Get("Location") != "" {
+		rec, err = serve(s, httptest.NewRequest(http.MethodGet, rec.Header().Get("Location"), nil), timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", path, err)
+	}
+	if rec.Code != http.StatusNotFound {
+		return fmt.Errorf("GET %s: status %d, want %d", path, rec.Code, http.StatusNotFound)
+	}
+	return nil
+}
+
+// FuzzForm registers a native Go fuzz target posting arbitrary form bodies
+// to path:
+//
+//	func FuzzSignup(f *testing.F) {
+//		nojstest.FuzzForm(f, newServer(), "/signup")
+//	}
+func FuzzForm(f *testing.F, s *nojs.Server, path string) {
+	f.Add("name=Ada&email=ada%40example.com")
+	f.Add("a=%zz&b=%&&=c")
+	f.Add("_method=DELETE")
+	f.Add(strings.Repeat("x=1&", 2000))
+	f.Fuzz(func(t *testing.T, body string) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if err := Check(s, req, time.Second); err != nil {
+			t.Errorf("POST %s %q: %v", path, body, err)
+		}
+	})
+}
+
+// Check serves req and returns an error if the handler panics or
+// responds with a 5xx status. Requests are canceled after timeout so
+// streaming handlers return.
+func Check(s *nojs.Server, req *http.Request, timeout time.Duration) error {
+	_, err := serve(s, req, timeout)
+	return err
+}
+
+// serve serves req like Check, returning the response as well
+func serve(s *nojs.Server, req *http.Request, timeout time.Duration) (rec *httptest.ResponseRecorder, err error) {
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code >= 500 {
+		return rec, fmt.Errorf("status %d: %s", rec.Code, strings.TrimSpace(rec.Body.String()))
+	}
+	return rec, nil
+}
+
+func skipped(skip []string, pattern string) bool {
+	return contains(skip, pattern)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// randomRequest builds one hostile request for path
+func randomRequest(rnd *rand.Rand, cfg FuzzConfig, path string) *http.Request {
+	method := methods[rnd.Intn(len(methods))]
+	fields := randomFields(rnd, cfg)
+
+	var body []byte
+	if method != http.MethodGet && method != http.MethodHead {
+		if method == http.MethodPost {
+			if override := overrides[rnd.Intn(len(overrides))]; override != "" {
+				fields = append(fields, "_method="+url.QueryEscape(override))
+			}
+		}
+		body = []byte(strings.Join(fields, "&"))
+	}
+
+	query := ""
+	if rnd.Intn(3) == 0 {
+		query = "?" + strings.Join(randomFields(rnd, cfg), "&")
+	}
+
+	req := httptest.NewRequest(method, "/", bytes.NewReader(body))
+	req.URL.Path = path
+	req.URL.RawQuery = strings.TrimPrefix(query, "?")
+	req.RequestURI = path + query
+
+	switch rnd.Intn(6) {
+	case 0:
+		req.Header.Set("Content-Type", "multipart/form-data; boundary=broken")
+	case 1:
+		req.Header.Set("Content-Type", "application/json")
+	case 2:
+		// No content type
+	default:
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return req
+}
+
+// randomFields generates encoded name=value pairs, some of them broken
+func randomFields(rnd *rand.Rand, cfg FuzzConfig) []string {
+	n := rnd.Intn(8)
+	if rnd.Intn(10) == 0 {
+		n = 5000 // parameter flood
+	}
+
+	fields := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		name := randomToken(rnd)
+		if len(cfg.Fields) > 0 && rnd.Intn(2) == 0 {
+			name = cfg.Fields[rnd.Intn(len(cfg.Fields))]
+		}
+		if rnd.Intn(4) == 0 {
+			name += "[]" // array-style names
+		}
+		fields = append(fields, name+"="+randomValue(rnd, cfg))
+	}
+	return fields
+}
+
+// randomValue returns a form value, possibly oversized or badly encoded
+func randomValue(rnd *rand.Rand, cfg FuzzConfig) string {
+	switch rnd.Intn(10) {
+	case 0:
+		return "%zz%"
+	case 1:
+		return url.QueryEscape("\xff\xfe\x00invalid utf-8")
+	case 2:
+		return strings.Repeat("A", rnd.Intn(cfg.MaxFieldSize+1))
+	case 3:
+		return url.QueryEscape("<script>alert(1)</script>")
+	case 4:
+		return "-1"
+	case 5:
+		return "99999999999999999999999"
+	case 6:
+		return ""
+	default:
+		return url.QueryEscape(randomToken(rnd))
+	}
+}
+
+func randomToken(rnd *rand.Rand) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789_-"
+	b := make([]byte, 1+rnd.Intn(12))
+	for i := range b {
+		b[i] = letters[rnd.Intn(len(letters))]
+	}
+	return string(b)
+}
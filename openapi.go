@@ -0,0 +1,302 @@
+package nojs
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIOperation documents a JSON API endpoint for the OpenAPI document.
+// Request and Response are example values (usually zero structs) whose
+// json or form tags name the fields and whose validate tags ("required",
+// "email", "min=N", "max=N") describe the constraints.
+type APIOperation struct {
+	Method      string
+	Pattern     string
+	Summary     string
+	Description string
+	Tags        []string
+	Request     interface{}
+	Response    interface{}
+}
+
+// OpenAPIInfo is the info section of an OpenAPI document
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Document registers an API operation to include in the OpenAPI document
+func (s *Server) Document(ops ...APIOperation) {
+	s.apiDocs = append(s.apiDocs, ops...)
+}
+
+// OpenAPI generates an OpenAPI 3 document from the documented operations
+// of the server and the apps mounted in it. Method routes without
+// documentation are listed with their handler name as summary, so the
+// document covers the whole route table. Paths are public paths, as
+// returned by URL. Named struct types are described once under
+// components and referenced, so recursive types are supported.
+func (s *Server) OpenAPI(info OpenAPIInfo) map[string]interface{} {
+	paths := map[string]map[string]interface{}{}
+	add := func(pattern, method string, op map[string]interface{}) {
+		if paths[pattern] == nil {
+			paths[pattern] = map[string]interface{}{}
+		}
+		paths[pattern][strings.ToLower(method)] = op
+	}
+
+	schemas := &openAPISchemas{defs: map[string]interface{}{}, names: map[reflect.Type]string{}}
+	for _, op := range s.documented() {
+		add(op.Pattern, op.Method, schemas.operation(op))
+	}
+	for _, route := range s.Routes() {
+		pattern := s.URL(route.Pattern)
+		if route.Host != "" || len(route.Methods) == 0 || paths[pattern][strings.ToLower(route.Methods[0])] != nil {
+			continue
+		}
+		add(pattern, route.Methods[0], map[string]interface{}{
+			"summary":   route.Handler,
+			"responses": map[string]interface{}{"200": map[string]interface{}{"description": "OK"}},
+		})
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    info,
+		"paths":   paths,
+	}
+	if len(schemas.defs) > 0 {
+		doc["components"] = map[string]interface{}{"schemas": schemas.defs}
+	}
+	return doc
+}
+
+// documented returns the documented operations of the server and the
+// apps mounted in it, with public patterns
+func (s *Server) documented() []APIOperation {
+	var ops []APIOperation
+	for _, op := range s.apiDocs {
+		op.Pattern = s.URL(op.Pattern)
+		ops = append(ops, op)
+	}
+	for _, r := range s.routes {
+		if r.app != nil {
+			ops = append(ops, r.app.documented()...)
+		}
+	}
+	return ops
+}
+
+// ServeOpenAPI serves the OpenAPI document as JSON at pattern
+func (s *Server) ServeOpenAPI(pattern string, info OpenAPIInfo) {
+	s.GET(pattern, func(ctx *Context) error {
+		return ctx.JSON(http.StatusOK, s.OpenAPI(info))
+	})
+}
+
+// openAPISchemas collects the component schemas of named struct types
+type openAPISchemas struct {
+	defs  map[string]interface{}
+	names map[reflect.Type]string
+}
+
+// operation converts an APIOperation to an operation object
+func (s *openAPISchemas) operation(op APIOperation) map[string]interface{} {
+	out := map[string]interface{}{}
+	if op.Summary != "" {
+		out["summary"] = op.Summary
+	}
+	if op.Description != "" {
+		out["description"] = op.Description
+	}
+	if len(op.Tags) > 0 {
+		out["tags"] = op.Tags
+	}
+
+	if op.Request != nil {
+		t := reflect.TypeOf(op.Request)
+		if op.Method == http.MethodGet || op.Method == http.MethodDelete {
+			// Parameters need the properties themselves, not a reference
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			if t.Kind() == reflect.Struct {
+				out["parameters"] = openAPIParameters(s.object(t))
+			}
+		} else {
+			schema := s.schema(t)
+			out["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json":                  map[string]interface{}{"schema": schema},
+					"application/x-www-form-urlencoded": map[string]interface{}{"schema": schema},
+				},
+			}
+		}
+	}
+
+	response := map[string]interface{}{"description": "OK"}
+	if op.Response != nil {
+		response["content"] = map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": s.schema(reflect.TypeOf(op.Response))},
+		}
+	}
+	out["responses"] = map[string]interface{}{"200": response}
+	return out
+}
+
+// openAPIParameters turns an object schema into query parameters
+func openAPIParameters(schema map[string]interface{}) []interface{} {
+	props, _ := schema["properties"].(map[string]interface{})
+	required := map[string]bool{}
+	if names, ok := schema["required"].([]string); ok {
+		for _, name := range names {
+			required[name] = true
+		}
+	}
+
+	var params []interface{}
+	for name, prop := range props {
+		params = append(params, map[string]interface{}{
+			"name":     name,
+			"in":       "query",
+			"required": required[name],
+			"schema":   prop,
+		})
+	}
+	return params
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema describes a Go type as a JSON schema, referencing named structs
+func (s *openAPISchemas) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name, ok := s.names[t]
+		if !ok {
+			name = s.componentName(t)
+			// Registered before describing the fields, so fields of the
+			// same type reference it instead of recursing
+			s.names[t] = name
+			s.defs[name] = map[string]interface{}{}
+			s.defs[name] = s.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// object describes the fields of a struct type
+func (s *openAPISchemas) object(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := fieldName(field)
+		if name == "" {
+			continue
+		}
+		prop := s.schema(field.Type)
+		if applyValidateTag(prop, field.Tag.Get("validate")) {
+			required = append(required, name)
+		}
+		props[name] = prop
+	}
+	schema := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// componentName returns a unique component name for a named type, using
+// only the characters OpenAPI allows in them
+func (s *openAPISchemas) componentName(t reflect.Type) string {
+	base := strings.Map(func(r rune) rune {
+		if r < 128 && (r == '.' || r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, t.Name())
+	name := base
+	for i := 2; s.defs[name] != nil; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	return name
+}
+
+// fieldName returns the wire name of a struct field from its json or form
+// tag, or "" for unexported and skipped fields
+func fieldName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+	for _, key := range []string{"json", "form"} {
+		if tag, ok := field.Tag.Lookup(key); ok {
+			name, _, _ := strings.Cut(tag, ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+	}
+	return field.Name
+}
+
+// applyValidateTag adds validate tag constraints to a property schema and
+// reports whether the field is required
+func applyValidateTag(prop map[string]interface{}, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		n, _ := strconv.Atoi(arg)
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			prop["format"] = "email"
+		case "min", "max":
+			if prop["$ref"] != nil {
+				continue
+			}
+			key := name + "imum"
+			if prop["type"] == "string" {
+				key = name + "Length"
+			} else if prop["type"] == "array" {
+				key = name + "Items"
+			}
+			prop[key] = n
+		}
+	}
+	return required
+}
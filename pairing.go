@@ -0,0 +1,231 @@
+package nojs
+
+import (
+	"crypto/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// pairingAlphabet leaves out characters that are easily confused (0/O, 1/I)
+const pairingAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Pairing signs a device in by approving it from another one, like TV
+// and console apps do: the new device shows a code and QR code, the user
+// enters or scans it on a device where they are logged in, and the
+// waiting page, which refreshes itself, continues as that user. It needs
+// the SessionManager middleware. Mount it with Server.Mount:
+//
+//	pairing := nojs.NewPairing()
+//	server.Mount("/pair", pairing.Server())
+//
+// The new device opens /pair/, the approving device /pair/enter.
+type Pairing struct {
+	// TTL is how long a code can be entered
+	TTL time.Duration
+	// RefreshSeconds is how often the waiting page checks for approval
+	RefreshSeconds int
+	// Done is where the paired device goes once approved
+	Done string
+	// LoginURL is where approvers who are not logged in are sent, with
+	// the pairing page in the next query parameter
+	LoginURL string
+	// UserID returns the user approving the pairing; defaults to
+	// ctx.UserID
+	UserID func(ctx *Context) string
+	// Pair logs the waiting device in as userID; defaults to
+	// Session.SetUser
+	Pair func(ctx *Context, userID string) error
+	// Wrap renders content as a full page, e.g. Layout.Wrap
+	Wrap func(content g.Node) g.Node
+
+	mu        sync.Mutex
+	codes     map[string]*pairingRequest
+	bySession map[string]*pairingRequest
+}
+
+type pairingRequest struct {
+	code      string
+	sessionID string
+	created   time.Time
+	userID    string // Set once approved
+}
+
+// NewPairing creates a pairing flow with ten-minute codes
+func NewPairing() *Pairing {
+	return &Pairing{
+		TTL:            10 * time.Minute,
+		RefreshSeconds: 3,
+		Done:           "/",
+		UserID: func(ctx *Context) string {
+			return ctx.UserID()
+		},
+		Pair: func(ctx *Context, userID string) error {
+			GetSession(ctx).SetUser(userID)
+			return nil
+		},
+		Wrap: func(content g.Node) g.Node {
+			return Page{Title: "Pair device", Body: h.Main(content)}.Render()
+		},
+		codes:     make(map[string]*pairingRequest),
+		bySession: make(map[string]*pairingRequest),
+	}
+}
+
+// Server returns the pairing routes, to be mounted under a prefix
+func (p *Pairing) Server() *Server {
+	s := NewServer()
+	s.GET("/", p.waitPage)
+	s.GET("/enter", p.enterPage)
+	s.POST("/enter", p.approve)
+	return s
+}
+
+// waitPage shows the code on the new device until it is approved
+func (p *Pairing) waitPage(ctx *Context) error {
+	session := GetSession(ctx)
+	if session == nil {
+		return NewHTTPError(http.StatusInternalServerError, "Pairing needs the SessionManager middleware")
+	}
+
+	p.mu.Lock()
+	req := p.bySession[session.ID()]
+	if req != nil && req.userID != "" {
+		p.remove(req)
+		p.mu.Unlock()
+		if err := p.Pair(ctx, req.userID); err != nil {
+			return err
+		}
+		return ctx.Redirect(http.StatusSeeOther, p.Done)
+	}
+	if req == nil || time.Since(req.created) > p.TTL {
+		if req != nil {
+			p.remove(req)
+		}
+		req = p.create(session.ID())
+	}
+	p.mu.Unlock()
+
+	code := formatPairingCode(req.code)
+	enterURL := ctx.AbsoluteURL("/enter")
+	return ctx.HTML(http.StatusOK, p.Wrap(h.Div(h.Class("pairing"),
+		AutoRefresh(p.RefreshSeconds),
+		h.H1(g.Text("Pair this device")),
+		h.P(g.Text("Scan the QR code with a device where you are signed in, or go to "),
+			h.Strong(g.Text(enterURL)), g.Text(" and enter this code:")),
+		h.P(h.Class("pairing-code"), g.Text(code)),
+		h.Div(h.Class("pairing-qr"), QRCode(enterURL+"?code="+req.code, QRConfig{Level: QRMedium, Size: 240, Label: "Pairing QR code"})),
+		h.P(h.Class("pairing-expiry"), g.Textf("The code expires at %s. This page continues by itself once the device is approved.",
+			req.created.Add(p.TTL).Format("15:04"))),
+	)))
+}
+
+// enterPage asks the signed in user for the code shown on the new device
+func (p *Pairing) enterPage(ctx *Context) error {
+	if p.UserID(ctx) == "" {
+		return p.login(ctx)
+	}
+	return p.renderEnter(ctx, http.StatusOK, ctx.Query("code"), "")
+}
+
+// approve links the waiting device to the signed in user
+func (p *Pairing) approve(ctx *Context) error {
+	userID := p.UserID(ctx)
+	if userID == "" {
+		return p.login(ctx)
+	}
+
+	code := normalizePairingCode(ctx.Form("code"))
+	p.mu.Lock()
+	req := p.codes[code]
+	if req != nil && time.Since(req.created) > p.TTL {
+		p.remove(req)
+		req = nil
+	}
+	if req != nil {
+		req.userID = userID
+	}
+	p.mu.Unlock()
+	if req == nil {
+		return p.renderEnter(ctx, http.StatusUnprocessableEntity, ctx.Form("code"), "is unknown or has expired")
+	}
+
+	return ctx.HTML(http.StatusOK, p.Wrap(h.Div(h.Class("pairing"),
+		h.H1(g.Text("Device paired")),
+		h.P(g.Text("The other device will continue in a few seconds.")),
+	)))
+}
+
+func (p *Pairing) renderEnter(ctx *Context, status int, code, problem string) error {
+	return ctx.HTML(status, p.Wrap(h.Div(h.Class("pairing"),
+		h.H1(g.Text("Pair a device")),
+		h.P(g.Text("Enter the code shown on the device you want to sign in. It will be signed in as you.")),
+		Form(FormConfig{Action: ctx.URL("/enter")},
+			Input("Code", "code", "text", code,
+				h.Required(), h.AutoComplete("off"), g.Attr("autocapitalize", "characters"), h.AutoFocus()),
+			FieldError(problem),
+			SubmitButton("Pair device"),
+		),
+	)))
+}
+
+// login sends an approver who is not signed in to LoginURL
+func (p *Pairing) login(ctx *Context) error {
+	if p.LoginURL == "" {
+		return NewHTTPError(http.StatusUnauthorized, "Sign in to pair a device")
+	}
+	next := ctx.URL("/enter")
+	if code := ctx.Query("code"); code != "" {
+		next += "?code=" + url.QueryEscape(code)
+	}
+	return ctx.Redirect(http.StatusSeeOther, p.LoginURL+"?next="+url.QueryEscape(next))
+}
+
+// create starts a pairing request; the caller must hold p.mu
+func (p *Pairing) create(sessionID string) *pairingRequest {
+	for code, req := range p.codes {
+		if time.Since(req.created) > p.TTL {
+			delete(p.codes, code)
+			delete(p.bySession, req.sessionID)
+		}
+	}
+	req := &pairingRequest{sessionID: sessionID, created: time.Now()}
+	for req.code == "" || p.codes[req.code] != nil {
+		b := make([]byte, 8)
+		rand.Read(b)
+		for i := range b {
+			b[i] = pairingAlphabet[int(b[i])%len(pairingAlphabet)]
+		}
+		req.code = string(b)
+	}
+	p.codes[req.code] = req
+	p.bySession[sessionID] = req
+	return req
+}
+
+// remove deletes a request; the caller must hold p.mu
+func (p *Pairing) remove(req *pairingRequest) {
+	delete(p.codes, req.code)
+	delete(p.bySession, req.sessionID)
+}
+
+// formatPairingCode splits a code in two groups for reading aloud
+func formatPairingCode(code string) string {
+	return code[:4] + "-" + code[4:]
+}
+
+// normalizePairingCode accepts codes typed in lower case or with spaces
+// and dashes
+func normalizePairingCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
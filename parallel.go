@@ -0,0 +1,197 @@
+package nojs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// Section is one independently loaded part of a composite page, such as
+// a dashboard panel, rendered by ctx.Parallel
+type Section struct {
+	// ID is the id of the section element and the anchor its retry link
+	// returns to
+	ID    string
+	Title string
+	// Load fetches the section's data and renders it. It runs on its own
+	// goroutine and should return once ctx is done.
+	Load func(ctx context.Context) (g.Node, error)
+	// Placeholder is shown, followed by a retry link, when Load does not
+	// finish in time. A short "still loading" note is used when nil.
+	Placeholder g.Node
+}
+
+// ParallelConfig configures ctx.Parallel
+type ParallelConfig struct {
+	// Budget is the time all sections share; sections not loaded by then
+	// are replaced by placeholders
+	Budget time.Duration
+	// RetryBudget is the time given to the section whose retry link was
+	// followed
+	RetryBudget time.Duration
+	// Hedge starts a second, concurrent Load of a section still loading
+	// after this long and uses whichever finishes first, cutting tail
+	// latency for idempotent loaders. Zero disables hedging.
+	Hedge time.Duration
+}
+
+// DefaultParallelConfig returns the default time budgets
+func DefaultParallelConfig() ParallelConfig {
+	return ParallelConfig{
+		Budget:      300 * time.Millisecond,
+		RetryBudget: 3 * time.Second,
+	}
+}
+
+// parallelRetryParam names the section a retry link gives more time
+const parallelRetryParam = "section"
+
+// sectionResult is the outcome of loading a section
+type sectionResult struct {
+	node g.Node
+	err  error
+}
+
+// Parallel loads sections concurrently within a shared time budget and
+// renders them in order. Sections that fail or are still loading when
+// the budget is spent are replaced by a placeholder with a link reloading
+// the page with more time for that section, so a slow backend slows
+// down one panel instead of the whole page:
+//
+//	return ctx.Render(http.StatusOK, ctx.Parallel(nojs.DefaultParallelConfig(),
+//		nojs.Section{ID: "sales", Title: "Sales", Load: loadSales},
+//		nojs.Section{ID: "tickets", Title: "Open tickets", Load: loadTickets},
+//	))
+//
+// Loads still running when Parallel returns are cancelled.
+func (c *Context) Parallel(config ParallelConfig, sections ...Section) g.Node {
+	defaults := DefaultParallelConfig()
+	if config.Budget <= 0 {
+		config.Budget = defaults.Budget
+	}
+	if config.RetryBudget <= 0 {
+		config.RetryBudget = defaults.RetryBudget
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	results := make([]chan sectionResult, len(sections))
+	for i, s := range sections {
+		results[i] = make(chan sectionResult, 1)
+		go s.run(ctx, config.Hedge, results[i])
+	}
+
+	start := time.Now()
+	retry := c.Query(parallelRetryParam)
+	nodes := make([]g.Node, len(sections))
+	incomplete := false
+	for i, s := range sections {
+		budget := config.Budget
+		if s.ID != "" && s.ID == retry {
+			budget = config.RetryBudget
+		}
+		r, ok := s.await(ctx, results[i], time.Until(start.Add(budget)))
+		var content g.Node
+		switch {
+		case !ok:
+			placeholder := s.Placeholder
+			if placeholder == nil {
+				placeholder = g.Text("This is taking longer than usual. ")
+			}
+			content = h.P(h.Class("nojs-section-pending"), placeholder, c.sectionRetryLink(s.ID, "Load it"))
+			incomplete = true
+		case r.err != nil:
+			log.Printf("nojs: section %s of %s: %v", s.ID, c.Request.URL.Path, r.err)
+			content = h.P(h.Class("nojs-section-error"),
+				g.Text("This section could not be loaded. "), c.sectionRetryLink(s.ID, "Try again"))
+			incomplete = true
+		default:
+			content = r.node
+		}
+		nodes[i] = h.Section(g.If(s.ID != "", h.ID(s.ID)),
+			g.If(s.Title != "", h.H2(g.Text(s.Title))),
+			content,
+		)
+	}
+	if incomplete {
+		// Do not let caches keep the placeholders
+		c.NoStore()
+	}
+	return g.Group(nodes)
+}
+
+// await waits up to wait for the result of the section, reporting
+// whether it came. A finished result is used even when wait has passed.
+func (s Section) await(ctx context.Context, result <-chan sectionResult, wait time.Duration) (sectionResult, bool) {
+	select {
+	case r := <-result:
+		return r, true
+	default:
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case r := <-result:
+		return r, true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return sectionResult{}, false
+}
+
+// sectionRetryLink links to the current page with more time for section
+func (c *Context) sectionRetryLink(section, label string) g.Node {
+	q := c.Request.URL.Query()
+	q.Set(parallelRetryParam, section)
+	u := url.URL{Path: c.URL(c.Request.URL.Path), RawQuery: q.Encode()}
+	if section != "" {
+		u.Fragment = section
+	}
+	return h.A(h.Href(u.String()), g.Text(label))
+}
+
+// run loads the section, hedging with a second attempt if configured,
+// and sends the first result to out
+func (s Section) run(ctx context.Context, hedge time.Duration, out chan<- sectionResult) {
+	attempts := make(chan sectionResult, 2)
+	go s.attempt(ctx, attempts)
+
+	var hedged <-chan time.Time
+	if hedge > 0 {
+		timer := time.NewTimer(hedge)
+		defer timer.Stop()
+		hedged = timer.C
+	}
+	select {
+	case r := <-attempts:
+		out <- r
+		return
+	case <-hedged:
+		go s.attempt(ctx, attempts)
+	case <-ctx.Done():
+		return
+	}
+	select {
+	case r := <-attempts:
+		out <- r
+	case <-ctx.Done():
+	}
+}
+
+// attempt calls Load once, turning a panic into an error so a broken
+// section cannot take the server down
+func (s Section) attempt(ctx context.Context, out chan<- sectionResult) {
+	defer func() {
+		if p := recover(); p != nil {
+			out <- sectionResult{err: fmt.Errorf("panic: %v", p)}
+		}
+	}()
+	node, err := s.Load(ctx)
+	out <- sectionResult{node: node, err: err}
+}
@@ -0,0 +1,226 @@
+package nojs
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Typed accessors for query parameters and form fields. The plain
+// variants (QueryInt, FormTime, ...) return a 400 HTTPError when the value
+// is missing or malformed, so handlers can return it directly; the Or
+// variants fall back to a default instead. Bools follow checkbox
+// semantics: an absent value is false, not an error.
+//
+//	page := ctx.QueryIntOr("page", 1)
+//	due, err := ctx.FormTime("due")
+//	if err != nil {
+//		return err
+//	}
+
+// QueryInt returns a query parameter as an int
+func (c *Context) QueryInt(name string) (int, error) {
+	return parseInt(c.Query(name), "query parameter", name)
+}
+
+// QueryIntOr returns a query parameter as an int, or def when it is
+// missing or invalid
+func (c *Context) QueryIntOr(name string, def int) int {
+	return orDefault(c.QueryInt(name))(def)
+}
+
+// QueryInt64 returns a query parameter as an int64
+func (c *Context) QueryInt64(name string) (int64, error) {
+	return parseInt64(c.Query(name), "query parameter", name)
+}
+
+// QueryInt64Or returns a query parameter as an int64, or def when it is
+// missing or invalid
+func (c *Context) QueryInt64Or(name string, def int64) int64 {
+	return orDefault(c.QueryInt64(name))(def)
+}
+
+// QueryFloat returns a query parameter as a float64
+func (c *Context) QueryFloat(name string) (float64, error) {
+	return parseFloat(c.Query(name), "query parameter", name)
+}
+
+// QueryFloatOr returns a query parameter as a float64, or def when it is
+// missing or invalid
+func (c *Context) QueryFloatOr(name string, def float64) float64 {
+	return orDefault(c.QueryFloat(name))(def)
+}
+
+// QueryBool returns a query parameter as a bool, accepting on/off,
+// true/false, 1/0 and yes/no. A missing parameter is false.
+func (c *Context) QueryBool(name string) (bool, error) {
+	return parseBool(c.Query(name), "query parameter", name)
+}
+
+// QueryBoolOr returns a query parameter as a bool, or def when it is
+// missing or invalid
+func (c *Context) QueryBoolOr(name string, def bool) bool {
+	if strings.TrimSpace(c.Query(name)) == "" {
+		return def
+	}
+	return orDefault(c.QueryBool(name))(def)
+}
+
+// QueryTime returns a query parameter as a time, in any of the formats
+// of date, datetime-local and time inputs or RFC 3339
+func (c *Context) QueryTime(name string) (time.Time, error) {
+	return parseTime(c.Query(name), "query parameter", name)
+}
+
+// QueryTimeOr returns a query parameter as a time, or def when it is
+// missing or invalid
+func (c *Context) QueryTimeOr(name string, def time.Time) time.Time {
+	return orDefault(c.QueryTime(name))(def)
+}
+
+// FormInt returns a form field as an int
+func (c *Context) FormInt(name string) (int, error) {
+	return parseInt(c.Form(name), "field", name)
+}
+
+// FormIntOr returns a form field as an int, or def when it is missing
+// or invalid
+func (c *Context) FormIntOr(name string, def int) int {
+	return orDefault(c.FormInt(name))(def)
+}
+
+// FormInt64 returns a form field as an int64
+func (c *Context) FormInt64(name string) (int64, error) {
+	return parseInt64(c.Form(name), "field", name)
+}
+
+// FormInt64Or returns a form field as an int64, or def when it is
+// missing or invalid
+func (c *Context) FormInt64Or(name string, def int64) int64 {
+	return orDefault(c.FormInt64(name))(def)
+}
+
+// FormFloat returns a form field as a float64
+func (c *Context) FormFloat(name string) (float64, error) {
+	return parseFloat(c.Form(name), "field", name)
+}
+
+// FormFloatOr returns a form field as a float64, or def when it is
+// missing or invalid
+func (c *Context) FormFloatOr(name string, def float64) float64 {
+	return orDefault(c.FormFloat(name))(def)
+}
+
+// FormBool returns a form field as a bool. An unchecked checkbox, which
+// browsers do not submit, is false.
+func (c *Context) FormBool(name string) (bool, error) {
+	return parseBool(c.Form(name), "field", name)
+}
+
+// FormBoolOr returns a form field as a bool, or def when it is missing
+// or invalid
+func (c *Context) FormBoolOr(name string, def bool) bool {
+	if strings.TrimSpace(c.Form(name)) == "" {
+		return def
+	}
+	return orDefault(c.FormBool(name))(def)
+}
+
+// FormTime returns a form field as a time, in any of the formats of
+// date, datetime-local and time inputs or RFC 3339
+func (c *Context) FormTime(name string) (time.Time, error) {
+	return parseTime(c.Form(name), "field", name)
+}
+
+// FormTimeOr returns a form field as a time, or def when it is missing
+// or invalid
+func (c *Context) FormTimeOr(name string, def time.Time) time.Time {
+	return orDefault(c.FormTime(name))(def)
+}
+
+// orDefault turns a parse result into a function returning the value,
+// or its argument when parsing failed
+func orDefault[T any](v T, err error) func(def T) T {
+	return func(def T) T {
+		if err != nil {
+			return def
+		}
+		return v
+	}
+}
+
+// paramError is the 400 returned for a missing or malformed value
+func paramError(kind, name, problem string, err error) error {
+	return WrapHTTPError(http.StatusBadRequest, fmt.Sprintf("%s %q %s", kind, name, problem), err)
+}
+
+func requireParam(raw, kind, name string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", paramError(kind, name, "is required", nil)
+	}
+	return raw, nil
+}
+
+func parseInt(raw, kind, name string) (int, error) {
+	raw, err := requireParam(raw, kind, name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, paramError(kind, name, "is not a whole number", err)
+	}
+	return n, nil
+}
+
+func parseInt64(raw, kind, name string) (int64, error) {
+	raw, err := requireParam(raw, kind, name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, paramError(kind, name, "is not a whole number", err)
+	}
+	return n, nil
+}
+
+func parseFloat(raw, kind, name string) (float64, error) {
+	raw, err := requireParam(raw, kind, name)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	// ParseFloat accepts "NaN" and "Inf", which no form means
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, paramError(kind, name, "is not a number", err)
+	}
+	return f, nil
+}
+
+func parseBool(raw, kind, name string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "on", "true", "1", "yes":
+		return true, nil
+	case "", "off", "false", "0", "no":
+		return false, nil
+	}
+	return false, paramError(kind, name, "is not a valid yes/no value", nil)
+}
+
+func parseTime(raw, kind, name string) (time.Time, error) {
+	raw, err := requireParam(raw, kind, name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, paramError(kind, name, "is not a valid date or time", nil)
+}
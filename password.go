@@ -0,0 +1,249 @@
+package nojs
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// Password validation errors
+var (
+	ErrWeakPassword   = errors.New("is too easy to guess")
+	ErrPwnedPassword  = errors.New("has appeared in a data breach, choose another one")
+	ErrShortPassword  = errors.New("must be at least 8 characters")
+	ErrPasswordLength = errors.New("must be at most 128 characters")
+)
+
+// commonPasswords holds frequent passwords and fragments that make a
+// password trivially guessable
+var commonPasswords = []string{
+	"password", "123456", "qwerty", "letmein", "welcome", "admin", "login",
+	"iloveyou", "monkey", "dragon", "football", "baseball", "master", "shadow",
+	"sunshine", "princess", "trustno1", "abc123", "passw0rd", "starwars",
+	"superman", "batman", "hello", "freedom", "whatever", "qazwsx", "zaq12wsx",
+	"asdfgh", "zxcvbn", "111111", "000000", "654321", "secret", "charlie",
+}
+
+// keyboardRows are scanned for keyboard walks such as "qwerty" or "asdf"
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm", "abcdefghijklmnopqrstuvwxyz"}
+
+// PasswordStrength estimates how hard a password is to guess on a 0-4
+// scale like zxcvbn: 0 too guessable, 1 very guessable, 2 somewhat
+// guessable, 3 safely unguessable, 4 very unguessable. userInputs (name,
+// email, site name) are treated as known words. Feedback explains the
+// main weaknesses.
+func PasswordStrength(password string, userInputs ...string) (score int, feedback []string) {
+	lower := strings.ToLower(password)
+	effective := lower
+
+	// Remove known words, which add almost no entropy
+	words := append([]string{}, commonPasswords...)
+	for _, input := range userInputs {
+		for _, part := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len(part) >= 3 {
+				words = append(words, part)
+			}
+		}
+	}
+	for _, word := range words {
+		if strings.Contains(effective, word) {
+			effective = strings.ReplaceAll(effective, word, "\x00")
+			feedback = appendOnce(feedback, "Avoid common passwords and personal information")
+		}
+	}
+
+	// Collapse keyboard walks, sequences and repeats
+	runes := []rune(effective)
+	var kept []rune
+	for i, r := range runes {
+		if i >= 2 && (runes[i-1] == r && runes[i-2] == r) {
+			feedback = appendOnce(feedback, "Avoid repeated characters")
+			continue
+		}
+		if i >= 2 && isSequence(runes[i-2], runes[i-1], r) {
+			feedback = appendOnce(feedback, "Avoid sequences like abc, 123 or qwerty")
+			continue
+		}
+		kept = append(kept, r)
+	}
+
+	// Character pool of the original password
+	pool := 0
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+	if hasLower {
+		pool += 26
+	}
+	if hasUpper {
+		pool += 26
+	}
+	if hasDigit {
+		pool += 10
+	}
+	if hasSymbol {
+		pool += 33
+	}
+	if pool == 0 {
+		return 0, []string{"Enter a password"}
+	}
+
+	bits := float64(len(kept)) * math.Log2(float64(pool))
+	switch {
+	case bits < 28:
+		score = 0
+	case bits < 36:
+		score = 1
+	case bits < 60:
+		score = 2
+	case bits < 80:
+		score = 3
+	default:
+		score = 4
+	}
+
+	if score < 3 && len(password) < 12 {
+		feedback = appendOnce(feedback, "Use a longer password; a few unrelated words work well")
+	}
+	return score, feedback
+}
+
+// isSequence reports whether three runes are consecutive in the alphabet,
+// digits or a keyboard row, in either direction
+func isSequence(a, b, c rune) bool {
+	if (b-a == 1 && c-b == 1) || (a-b == 1 && b-c == 1) {
+		return true
+	}
+	triple := string([]rune{a, b, c})
+	reversed := string([]rune{c, b, a})
+	for _, row := range keyboardRows {
+		if strings.Contains(row, triple) || strings.Contains(row, reversed) {
+			return true
+		}
+	}
+	return false
+}
+
+func appendOnce(list []string, s string) []string {
+	if Contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
+
+// PwnedChecker checks passwords against the Have I Been Pwned range API
+// using k-anonymity: only the first five hex digits of the SHA-1 hash
+// leave the server
+type PwnedChecker struct {
+	Client *http.Client
+	URL    string // Defaults to https://api.pwnedpasswords.com/range/
+}
+
+// NewPwnedChecker creates a checker with a short request timeout
+func NewPwnedChecker() *PwnedChecker {
+	return &PwnedChecker{
+		Client: &http.Client{Timeout: 3 * time.Second},
+		URL:    "https://api.pwnedpasswords.com/range/",
+	}
+}
+
+// Count returns how many times the password appears in known breaches
+func (pc *PwnedChecker) Count(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pc.URL+prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := pc.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("pwned passwords: %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if ok && candidate == suffix {
+			return strconv.Atoi(count)
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// PasswordPolicy validates new passwords
+type PasswordPolicy struct {
+	MinScore int // Minimum PasswordStrength score, defaults to 2
+	// Pwned, when set, rejects breached passwords. Lookup failures are
+	// ignored so an API outage does not block registrations.
+	Pwned *PwnedChecker
+}
+
+// Validate returns ErrShortPassword, ErrWeakPassword or ErrPwnedPassword
+// when the password does not meet the policy
+func (p PasswordPolicy) Validate(ctx context.Context, password string, userInputs ...string) error {
+	if len([]rune(password)) < 8 {
+		return ErrShortPassword
+	}
+	if len([]rune(password)) > 128 {
+		return ErrPasswordLength
+	}
+
+	minScore := p.MinScore
+	if minScore == 0 {
+		minScore = 2
+	}
+	if score, _ := PasswordStrength(password, userInputs...); score < minScore {
+		return ErrWeakPassword
+	}
+
+	if p.Pwned != nil {
+		if count, err := p.Pwned.Count(ctx, password); err == nil && count > 0 {
+			return ErrPwnedPassword
+		}
+	}
+	return nil
+}
+
+// PasswordField renders a new-password input with its validation error
+// and, when a password was scored, strength feedback
+func PasswordField(label, name, errMessage string, feedback ...string) g.Node {
+	return h.Div(h.Class("password-field"),
+		Input(label, name, "password", "", h.AutoComplete("new-password"), h.MinLength("8"), h.Required()),
+		FieldError(errMessage),
+		g.If(len(feedback) > 0, h.Ul(h.Class("password-feedback"),
+			g.Map(feedback, func(f string) g.Node { return h.Li(g.Text(f)) }),
+		)),
+	)
+}
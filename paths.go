@@ -0,0 +1,108 @@
+package nojs
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// TrailingSlash is a policy for URLs ending in a slash
+type TrailingSlash int
+
+const (
+	// TrailingSlashAsIs serves paths as requested (the default)
+	TrailingSlashAsIs TrailingSlash = iota
+	// TrailingSlashRemove redirects /todos/ to /todos
+	TrailingSlashRemove
+	// TrailingSlashAdd redirects /todos to /todos/, except for file names
+	TrailingSlashAdd
+)
+
+// canonicalPath returns the path a request should be redirected to under
+// the configured TrailingSlash and CaseInsensitive policies, or "".
+// Paths matched by a specific route other than the catch-all "/" are left
+// alone, so subtree routes such as Static keep working.
+func (s *Server) canonicalPath(r *http.Request) string {
+	p := r.URL.Path
+	pattern := s.patternFor(r, p)
+	generic := pattern == "" || pattern == "/"
+
+	target := p
+	if s.config.CaseInsensitive && strings.ToLower(p) != p {
+		if lower := strings.ToLower(p); len(s.patternFor(r, lower)) > len(pattern) {
+			target = lower
+			generic = false
+		}
+	}
+
+	switch s.config.TrailingSlash {
+	case TrailingSlashRemove:
+		if generic && target != "/" && strings.HasSuffix(target, "/") {
+			target = strings.TrimRight(target, "/")
+			if target == "" {
+				target = "/"
+			}
+		}
+	case TrailingSlashAdd:
+		if generic && !strings.HasSuffix(target, "/") && !strings.Contains(path.Base(target), ".") {
+			target += "/"
+		}
+	}
+
+	if target == p {
+		return ""
+	}
+	return target
+}
+
+// patternFor returns the mux pattern that would serve path
+func (s *Server) patternFor(r *http.Request, p string) string {
+	u := *r.URL
+	u.Path, u.RawPath = p, ""
+	r2 := *r
+	r2.URL = &u
+	_, pattern := s.mux.Handler(&r2)
+	return pattern
+}
+
+// redirectCanonical redirects to the canonical path, keeping the query.
+// GET and HEAD get 301; other methods get 308 so the body is resent.
+func (s *Server) redirectCanonical(w http.ResponseWriter, r *http.Request, target string) {
+	target = s.URL(target)
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	status := http.StatusPermanentRedirect
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		status = http.StatusMovedPermanently
+	}
+	http.Redirect(w, r, target, status)
+}
+
+// CanonicalHost middleware redirects requests for any other host, such as
+// www.example.com or an old domain, to host, keeping the scheme, path and
+// query. The port and a trailing dot are ignored when comparing. Use
+// permanent redirects (301/308) once the setup is final; browsers cache
+// them.
+func CanonicalHost(host string, permanent bool) Middleware {
+	name := hostName(host)
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			if hostName(ctx.Request.Host) == name {
+				return next(ctx)
+			}
+
+			status := http.StatusFound
+			if permanent {
+				status = http.StatusMovedPermanently
+			}
+			if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+				status = http.StatusTemporaryRedirect
+				if permanent {
+					status = http.StatusPermanentRedirect
+				}
+			}
+			return ctx.Redirect(status, ctx.Scheme()+"://"+host+ctx.Request.URL.RequestURI())
+		}
+	}
+}
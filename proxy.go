@@ -0,0 +1,185 @@
+package nojs
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ProxyConfig configures the ProxyHeaders middleware
+type ProxyConfig struct {
+	// RedirectHTTPS sends plain HTTP requests to HTTPS with 301
+	RedirectHTTPS bool
+}
+
+// ProxyHeaders middleware applies X-Forwarded-Proto, X-Forwarded-Host and
+// the client address (see ctx.ClientIP) from the proxies listed in
+// ServerConfig.TrustedProxies to ctx.Request: RemoteAddr becomes the
+// client address and URL.Scheme the original scheme (read it with
+// ctx.Scheme). Headers from other peers are ignored, since clients can
+// send them too.
+func ProxyHeaders(config ...ProxyConfig) Middleware {
+	var cfg ProxyConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			r := ctx.Request
+			if inNetworks(ctx.server.trustedProxies(), remoteHost(r.RemoteAddr)) {
+				if proto := firstHeaderValue(r.Header.Get("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+					r.URL.Scheme = proto
+				}
+				if host := firstHeaderValue(r.Header.Get("X-Forwarded-Host")); host != "" {
+					r.Host = host
+				}
+				if ip := ctx.server.forwardedClient(r); ip != "" {
+					r.RemoteAddr = net.JoinHostPort(ip, "0")
+				}
+			}
+
+			if cfg.RedirectHTTPS && ctx.Scheme() != "https" {
+				redirectHTTPS(ctx.ResponseWriter, r)
+				ctx.written = true
+				return nil
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// Scheme returns "https" or "http" for the request as the client made it,
+// taking ProxyHeaders into account
+func (c *Context) Scheme() string {
+	if c.Request.URL.Scheme != "" {
+		return c.Request.URL.Scheme
+	}
+	if c.Request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// AbsoluteURL returns the full URL of a path on this server as the
+// client reaches it, e.g. for share links, QR codes and emails
+func (c *Context) AbsoluteURL(path string) string {
+	return c.Scheme() + "://" + c.Request.Host + c.URL(path)
+}
+
+// ClientIP returns the address of the client that made the request.
+// Behind a load balancer listed in ServerConfig.TrustedProxies, it is
+// taken from ServerConfig.ClientIPHeader or X-Forwarded-For, skipping
+// trusted hops from the right; otherwise it is the peer address, since
+// anyone can send those headers.
+func (c *Context) ClientIP() string {
+	host := remoteHost(c.Request.RemoteAddr)
+	if inNetworks(c.server.trustedProxies(), host) {
+		if ip := c.server.forwardedClient(c.Request); ip != "" {
+			return ip
+		}
+	}
+	return host
+}
+
+// trustedProxies parses ServerConfig.TrustedProxies of the root server once
+func (s *Server) trustedProxies() []*net.IPNet {
+	root := s.root()
+	root.proxiesOnce.Do(func() {
+		root.proxies = parseNetworks(root.config.TrustedProxies)
+	})
+	return root.proxies
+}
+
+// forwardedClient returns the client address from the configured
+// ClientIPHeader or the rightmost untrusted entry of X-Forwarded-For.
+// Entries left of it were added by the client and cannot be believed.
+func (s *Server) forwardedClient(r *http.Request) string {
+	trusted := s.trustedProxies()
+	var hops []string
+	switch header := s.root().config.ClientIPHeader; {
+	case header == "" || http.CanonicalHeaderKey(header) == "X-Forwarded-For":
+		hops = strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	case http.CanonicalHeaderKey(header) == "Forwarded":
+		hops = forwardedFor(strings.Join(r.Header.Values("Forwarded"), ","))
+	default:
+		// Set, not appended to, by the proxy
+		if ip := strings.TrimSpace(r.Header.Get(header)); net.ParseIP(ip) != nil {
+			return ip
+		}
+		return ""
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(hops[i])
+		if net.ParseIP(ip) == nil {
+			return ""
+		}
+		if !inNetworks(trusted, ip) || i == 0 {
+			return ip
+		}
+	}
+	return ""
+}
+
+// forwardedFor returns the for= addresses of a Forwarded header, e.g.
+// `for=192.0.2.60;proto=https, for="[2001:db8::1]:4711"`, without ports
+// and brackets. Obfuscated identifiers such as "unknown" are kept, so
+// they stop the walk over the hops.
+func forwardedFor(header string) []string {
+	var hops []string
+	for _, element := range strings.Split(header, ",") {
+		for _, pair := range strings.Split(element, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			if !strings.EqualFold(key, "for") {
+				continue
+			}
+			value = strings.Trim(value, `"`)
+			if host, _, err := net.SplitHostPort(value); err == nil {
+				value = host
+			}
+			hops = append(hops, strings.Trim(value, "[]"))
+		}
+	}
+	return hops
+}
+
+// parseNetworks parses addresses and CIDR ranges, ignoring invalid ones
+func parseNetworks(list []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, entry := range list {
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil {
+				bits := 8 * len(ip.To16())
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 32
+				}
+				networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			}
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// inNetworks reports whether ip is in one of networks
+func inNetworks(networks []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstHeaderValue returns the first entry of a comma-separated header
+func firstHeaderValue(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.ToLower(strings.TrimSpace(first))
+}
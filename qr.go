@@ -0,0 +1,519 @@
+package nojs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// QRLevel is the error correction level of a QR code; higher levels
+// survive more damage at the cost of a denser code
+type QRLevel int
+
+// QR error correction levels, recovering roughly 7%, 15%, 25% and 30%
+const (
+	QRLow QRLevel = iota
+	QRMedium
+	QRQuartile
+	QRHigh
+)
+
+// ErrQRTooLong is returned when data does not fit in a version 40 code
+var ErrQRTooLong = errors.New("nojs: data too long for a QR code")
+
+// QRConfig configures QRCode
+type QRConfig struct {
+	Level QRLevel
+	// Size is the rendered width and height in CSS pixels
+	Size int
+	// Label is the accessible name of the image, e.g. the encoded URL
+	Label string
+}
+
+// DefaultQRConfig renders 200px codes with medium error correction
+func DefaultQRConfig() QRConfig {
+	return QRConfig{Level: QRMedium, Size: 200}
+}
+
+// QRCode renders data as an inline SVG QR code, so no image service or
+// script is needed. It renders nothing when data is too long.
+func QRCode(data string, config ...QRConfig) g.Node {
+	cfg := DefaultQRConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	qr, err := EncodeQR(data, cfg.Level)
+	if err != nil {
+		return nil
+	}
+	label := cfg.Label
+	if label == "" {
+		label = "QR code"
+	}
+
+	const quiet = 4
+	var path strings.Builder
+	for y := 0; y < qr.Size; y++ {
+		for x := 0; x < qr.Size; x++ {
+			run := 0
+			for x+run < qr.Size && qr.Black(x+run, y) {
+				run++
+			}
+			if run > 0 {
+				fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", x+quiet, y+quiet, run, run)
+				x += run
+			}
+		}
+	}
+	view := qr.Size + 2*quiet
+	return g.El("svg",
+		g.Attr("xmlns", "http://www.w3.org/2000/svg"),
+		g.Attr("viewBox", fmt.Sprintf("0 0 %d %d", view, view)),
+		h.Width(fmt.Sprint(cfg.Size)), h.Height(fmt.Sprint(cfg.Size)),
+		g.Attr("shape-rendering", "crispEdges"),
+		h.Role("img"), h.Aria("label", label),
+		h.Class("qr-code"),
+		g.El("rect", h.Width("100%"), h.Height("100%"), g.Attr("fill", "#fff")),
+		g.El("path", g.Attr("d", path.String()), g.Attr("fill", "#000")),
+	)
+}
+
+// QR is an encoded QR code symbol
+type QR struct {
+	Size    int // Modules per side
+	modules []bool
+	isFunc  []bool
+}
+
+// Black reports whether the module at column x, row y is dark
+func (q *QR) Black(x, y int) bool {
+	return q.modules[y*q.Size+x]
+}
+
+// EncodeQR encodes data in byte mode using the smallest version that
+// fits and the mask with the lowest penalty
+func EncodeQR(data string, level QRLevel) (*QR, error) {
+	return encodeQR([]byte(data), level, -1)
+}
+
+// Per-version tables indexed by level and version (index 0 unused)
+var (
+	qrECCPerBlock = [4][41]int{
+		{0, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+		{0, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{0, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	}
+	qrBlocks = [4][41]int{
+		{0, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+		{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+		{0, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+		{0, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+	}
+	// qrFormatLevel is the level's value in the format information
+	qrFormatLevel = [4]int{1, 0, 3, 2}
+)
+
+// encodeQR builds the symbol; mask -1 picks the best mask
+func encodeQR(data []byte, level QRLevel, mask int) (*QR, error) {
+	if level < QRLow || level > QRHigh {
+		return nil, fmt.Errorf("nojs: invalid QR level %d", level)
+	}
+	version := 1
+	for ; ; version++ {
+		if version > 40 {
+			return nil, ErrQRTooLong
+		}
+		countBits := 8
+		if version >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= qrDataCodewords(version, level)*8 {
+			break
+		}
+	}
+
+	// Byte mode segment, terminator and padding
+	var bits qrBits
+	bits.append(0b0100, 4)
+	if version >= 10 {
+		bits.append(len(data), 16)
+	} else {
+		bits.append(len(data), 8)
+	}
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	capacity := qrDataCodewords(version, level) * 8
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i/8] |= 1 << (7 - i%8)
+		}
+	}
+
+	q := &QR{Size: version*4 + 17}
+	q.modules = make([]bool, q.Size*q.Size)
+	q.isFunc = make([]bool, q.Size*q.Size)
+	q.drawFunctionPatterns(version, level)
+	q.drawCodewords(qrInterleave(codewords, version, level))
+
+	if mask < 0 {
+		best := 0
+		for m := 0; m < 8; m++ {
+			q.applyMask(m)
+			q.drawFormat(level, m)
+			if p := q.penalty(); m == 0 || p < best {
+				best, mask = p, m
+			}
+			q.applyMask(m) // XOR again to undo
+		}
+	}
+	q.applyMask(mask)
+	q.drawFormat(level, mask)
+	q.isFunc = nil
+	return q, nil
+}
+
+// qrRawModules is the number of modules available for data and error
+// correction in a version
+func qrRawModules(version int) int {
+	n := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		n -= (25*align-10)*align - 55
+		if version >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+func qrDataCodewords(version int, level QRLevel) int {
+	return qrRawModules(version)/8 - qrECCPerBlock[level][version]*qrBlocks[level][version]
+}
+
+// qrInterleave splits data into blocks, appends Reed-Solomon error
+// correction to each and interleaves the result
+func qrInterleave(data []byte, version int, level QRLevel) []byte {
+	numBlocks := qrBlocks[level][version]
+	eccLen := qrECCPerBlock[level][version]
+	raw := qrRawModules(version) / 8
+	numShort := numBlocks - raw%numBlocks
+	shortLen := raw / numBlocks
+
+	divisor := qrDivisor(eccLen)
+	var dataBlocks, eccBlocks [][]byte
+	for i, k := 0, 0; i < numBlocks; i++ {
+		n := shortLen - eccLen
+		if i >= numShort {
+			n++
+		}
+		block := data[k : k+n]
+		k += n
+		dataBlocks = append(dataBlocks, block)
+		eccBlocks = append(eccBlocks, qrRemainder(block, divisor))
+	}
+
+	result := make([]byte, 0, raw)
+	for i := 0; i <= shortLen-eccLen; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < eccLen; i++ {
+		for _, block := range eccBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// qrMul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func qrMul(x, y byte) byte {
+	var z byte
+	for i := 7; i >= 0; i-- {
+		carry := z >> 7
+		z = z<<1 ^ carry*0x1D
+		z ^= (y >> i & 1) * x
+	}
+	return z
+}
+
+// qrDivisor returns the Reed-Solomon generator polynomial of a degree,
+// highest coefficient (always 1) omitted
+func qrDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = qrMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = qrMul(root, 0x02)
+	}
+	return result
+}
+
+func qrRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= qrMul(coef, factor)
+		}
+	}
+	return result
+}
+
+func (q *QR) set(x, y int, dark bool) {
+	q.modules[y*q.Size+x] = dark
+	q.isFunc[y*q.Size+x] = true
+}
+
+func (q *QR) drawFunctionPatterns(version int, level QRLevel) {
+	for i := 0; i < q.Size; i++ {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+
+	for _, c := range [][2]int{{3, 3}, {q.Size - 4, 3}, {3, q.Size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x >= 0 && x < q.Size && y >= 0 && y < q.Size {
+					d := max(abs(dx), abs(dy))
+					q.set(x, y, d != 2 && d != 4)
+				}
+			}
+		}
+	}
+
+	align := qrAlignmentPositions(version)
+	last := len(align) - 1
+	for i, ay := range align {
+		for j, ax := range align {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue // Finder patterns
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.set(ax+dx, ay+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	q.drawFormat(level, 0) // Reserve the area, rewritten after masking
+
+	if version >= 7 {
+		rem := version
+		for i := 0; i < 12; i++ {
+			rem = rem<<1 ^ (rem>>11)*0x1F25
+		}
+		bits := version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := bits>>i&1 != 0
+			a, b := q.Size-11+i%3, i/3
+			q.set(a, b, dark)
+			q.set(b, a, dark)
+		}
+	}
+}
+
+func qrAlignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	num := version/7 + 2
+	step := (version*4 + num*2 + 1) / (num*2 - 2) * 2
+	if version == 32 {
+		step = 26
+	}
+	positions := make([]int, num)
+	positions[0] = 6
+	for i, pos := num-1, version*4+17-7; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+func (q *QR) drawFormat(level QRLevel, mask int) {
+	data := qrFormatLevel[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		q.set(q.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, q.Size-15+i, bit(i))
+	}
+	q.set(8, q.Size-8, true) // Dark module
+}
+
+// drawCodewords places the data bits in the zigzag order of the spec
+func (q *QR) drawCodewords(data []byte) {
+	i := 0
+	for right := q.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // Skip the vertical timing pattern
+		}
+		for vert := 0; vert < q.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.Size - 1 - vert // Upward column
+				}
+				if !q.isFunc[y*q.Size+x] && i < len(data)*8 {
+					q.modules[y*q.Size+x] = data[i/8]>>(7-i%8)&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (q *QR) applyMask(mask int) {
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !q.isFunc[y*q.Size+x] {
+				q.modules[y*q.Size+x] = !q.modules[y*q.Size+x]
+			}
+		}
+	}
+}
+
+// penalty scores a masked symbol with the four rules of the spec
+func (q *QR) penalty() int {
+	score, dark := 0, 0
+	line := make([]bool, q.Size)
+	for _, vertical := range []bool{false, true} {
+		for a := 0; a < q.Size; a++ {
+			for b := 0; b < q.Size; b++ {
+				if vertical {
+					line[b] = q.Black(a, b)
+				} else {
+					line[b] = q.Black(b, a)
+				}
+			}
+			score += qrLinePenalty(line)
+		}
+	}
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			c := q.Black(x, y)
+			if c {
+				dark++
+			}
+			if x+1 < q.Size && y+1 < q.Size && c == q.Black(x+1, y) && c == q.Black(x, y+1) && c == q.Black(x+1, y+1) {
+				score += 3
+			}
+		}
+	}
+	total := q.Size * q.Size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return score + k*10
+}
+
+// qrFinderLike matches 1:1:3:1:1 with four light modules on one side
+var qrFinderLike = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// qrLinePenalty scores runs of five or more same-coloured modules and
+// finder-like patterns in one row or column
+func qrLinePenalty(line []bool) int {
+	score := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			score += run - 2
+		}
+		run = 1
+	}
+	for i := 0; i+11 <= len(line); i++ {
+		for _, pattern := range qrFinderLike {
+			match := true
+			for j, dark := range pattern {
+				if line[i+j] != dark {
+					match = false
+					break
+				}
+			}
+			if match {
+				score += 40
+			}
+		}
+	}
+	return score
+}
+
+// qrBits is a bit buffer, most significant bit first
+type qrBits []bool
+
+func (b *qrBits) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, value>>i&1 != 0)
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,73 @@
+package nojs
+
+import (
+	"strconv"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// quantityStepSuffix names the +/- submit buttons of a QuantityInput
+const quantityStepSuffix = "_step"
+
+// QuantityInput creates a number input flanked by decrement and increment
+// submit buttons. Read the result with ctx.Quantity. Pressing Enter
+// submits a form through its first submit button, so forms with a
+// QuantityInput should render their main submit button before it.
+func QuantityInput(label, name string, value, min, max int, attrs ...g.Node) g.Node {
+	id := "input-" + name
+	stepName := name + quantityStepSuffix
+
+	control := h.Div(h.Class("quantity"),
+		h.Button(h.Type("submit"), h.Name(stepName), h.Value("-1"), h.Class("quantity-decrement"),
+			h.Aria("label", "Decrease"), g.If(value <= min, h.Disabled()), g.Text("−")),
+		h.Input(append([]g.Node{
+			h.Type("number"),
+			h.Name(name),
+			h.ID(id),
+			h.Value(strconv.Itoa(value)),
+			h.Min(strconv.Itoa(min)),
+			h.Max(strconv.Itoa(max)),
+		}, attrs...)...),
+		h.Button(h.Type("submit"), h.Name(stepName), h.Value("1"), h.Class("quantity-increment"),
+			h.Aria("label", "Increase"), g.If(value >= max, h.Disabled()), g.Text("+")),
+	)
+
+	if label == "" {
+		return control
+	}
+
+	return h.Div(h.Class("form-group"),
+		h.Label(h.For(id), g.Text(label)),
+		control,
+	)
+}
+
+// Quantity returns the submitted value of a QuantityInput, applying the
+// +/- button that was pressed and clamping the result to [min, max]
+func (c *Context) Quantity(name string, min, max int) int {
+	value, err := strconv.Atoi(c.Form(name))
+	if err != nil {
+		value = min
+	}
+
+	if step, err := strconv.Atoi(c.Form(name + quantityStepSuffix)); err == nil {
+		value += step
+	}
+
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// QuantityStepped reports whether the form was submitted by one of the
+// +/- buttons of the named QuantityInput rather than the form's own
+// submit button, in which case handlers usually re-render instead of
+// processing the form
+func (c *Context) QuantityStepped(name string) bool {
+	return c.Form(name+quantityStepSuffix) != ""
+}
@@ -0,0 +1,98 @@
+package nojs
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// QueryLimitConfig caps the query string of a request
+type QueryLimitConfig struct {
+	MaxLength      int // Raw query string length in bytes
+	MaxParams      int // Total number of values
+	MaxValues      int // Values per parameter name, e.g. ?tag=a&tag=b
+	MaxValueLength int
+	// Normalize drops empty and exactly repeated parameters before the
+	// handler sees the query
+	Normalize bool
+	// Reject renders the error page; it defaults to a 414 or 400 HTTPError
+	Reject func(ctx *Context, status int, reason string) error
+}
+
+// DefaultQueryLimitConfig returns caps generous for real pages
+func DefaultQueryLimitConfig() QueryLimitConfig {
+	return QueryLimitConfig{
+		MaxLength:      8192,
+		MaxParams:      100,
+		MaxValues:      20,
+		MaxValueLength: 2048,
+	}
+}
+
+// LimitQuery middleware rejects requests with abusive query strings:
+// oversized queries, thousands of parameters or values, and malformed
+// encoding. All state travels in URLs in a nojs app, so every handler
+// parses them.
+func LimitQuery(config ...QueryLimitConfig) Middleware {
+	cfg := DefaultQueryLimitConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Reject == nil {
+		cfg.Reject = func(ctx *Context, status int, reason string) error {
+			return NewHTTPError(status, reason)
+		}
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			raw := ctx.Request.URL.RawQuery
+			if raw == "" {
+				return next(ctx)
+			}
+			if cfg.MaxLength > 0 && len(raw) > cfg.MaxLength {
+				return cfg.Reject(ctx, http.StatusRequestURITooLong, "Query string too long")
+			}
+			if cfg.MaxParams > 0 && strings.Count(raw, "&")+strings.Count(raw, ";")+1 > cfg.MaxParams {
+				return cfg.Reject(ctx, http.StatusBadRequest, "Too many query parameters")
+			}
+
+			values, err := url.ParseQuery(raw)
+			if err != nil {
+				return cfg.Reject(ctx, http.StatusBadRequest, "Malformed query string")
+			}
+			for name, vals := range values {
+				if cfg.MaxValues > 0 && len(vals) > cfg.MaxValues {
+					return cfg.Reject(ctx, http.StatusBadRequest, fmt.Sprintf("Too many values for %q", name))
+				}
+				for _, v := range vals {
+					if cfg.MaxValueLength > 0 && len(v) > cfg.MaxValueLength {
+						return cfg.Reject(ctx, http.StatusBadRequest, fmt.Sprintf("Value of %q too long", name))
+					}
+				}
+			}
+
+			if cfg.Normalize {
+				ctx.Request.URL.RawQuery = normalizeQuery(values).Encode()
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// normalizeQuery drops empty values and repeated identical values
+func normalizeQuery(values url.Values) url.Values {
+	out := url.Values{}
+	for name, vals := range values {
+		seen := map[string]bool{}
+		for _, v := range vals {
+			if v == "" || seen[v] {
+				continue
+			}
+			seen[v] = true
+			out.Add(name, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,395 @@
+package nojs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// redacted replaces secret values in recordings
+const redacted = "[REDACTED]"
+
+// RecorderConfig configures the Recorder middleware
+type RecorderConfig struct {
+	// Dir is where recordings are written, one JSON file per request
+	Dir string
+	// MaxBodyBytes is the largest request or response body recorded;
+	// larger bodies are left out and marked truncated
+	MaxBodyBytes int64
+	// RedactHeaders lists headers whose values are replaced
+	RedactHeaders []string
+	// RedactFields lists substrings of query, form and JSON field names
+	// whose values are replaced, matched case-insensitively
+	RedactFields []string
+	// Skip excludes requests from recording, e.g. static files
+	Skip func(ctx *Context) bool
+}
+
+// DefaultRecorderConfig records bodies up to 64 KB and redacts
+// credentials, cookies, forwarded client addresses and password, token
+// and secret fields
+func DefaultRecorderConfig() RecorderConfig {
+	return RecorderConfig{
+		Dir:           "recordings",
+		MaxBodyBytes:  64 << 10,
+		RedactHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key", "X-Forwarded-For", "X-Real-IP", "Forwarded"},
+		RedactFields:  []string{"password", "token", "secret", "csrf", "card"},
+	}
+}
+
+// Recording is a recorded request and its response
+type Recording struct {
+	Time     time.Time        `json:"time"`
+	Route    string           `json:"route"`
+	Request  RecordedMessage  `json:"request"`
+	Response RecordedResponse `json:"response"`
+	Duration time.Duration    `json:"duration"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// RecordedMessage is a recorded request
+type RecordedMessage struct {
+	Method    string      `json:"method"`
+	URL       string      `json:"url"`
+	Header    http.Header `json:"header"`
+	Body      string      `json:"body,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// RecordedResponse is a recorded response
+type RecordedResponse struct {
+	Status    int         `json:"status"`
+	Header    http.Header `json:"header"`
+	Body      string      `json:"body,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+	Streamed  bool        `json:"streamed,omitempty"`
+	// Redacted is set when secrets were removed from the body
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// Recorder middleware writes every request and its response to
+// config.Dir for later replay with Replay. Recordings are anonymized:
+// client addresses are left out and secrets redacted. Turn it on while
+// hunting a bug, not permanently; it writes a file per request.
+func Recorder(config ...RecorderConfig) Middleware {
+	cfg := DefaultRecorderConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		log.Printf("nojs: recorder: %v", err)
+	}
+	var seq atomic.Int64
+
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			if cfg.Skip != nil && cfg.Skip(ctx) {
+				return next(ctx)
+			}
+			start := time.Now()
+			rec := Recording{Time: start, Route: ctx.Route(), Request: cfg.recordRequest(ctx.Request)}
+
+			w := &recordingWriter{ResponseWriter: ctx.ResponseWriter, max: cfg.MaxBodyBytes}
+			ctx.ResponseWriter = w
+			err := next(ctx)
+			ctx.ResponseWriter = w.ResponseWriter
+
+			rec.Duration = time.Since(start)
+			body := cfg.redactBody(w.Header().Get("Content-Type"), w.body.Bytes())
+			rec.Response = RecordedResponse{
+				Status:    w.status,
+				Header:    cfg.redactHeader(w.Header()),
+				Body:      body,
+				Truncated: w.truncated,
+				Streamed:  ctx.stream != nil,
+				Redacted:  strings.Contains(body, redacted) && !bytes.Contains(w.body.Bytes(), []byte(redacted)),
+			}
+			if err != nil {
+				rec.Error = err.Error()
+				rec.Response.Status = errorStatus(err)
+			} else if rec.Response.Status == 0 {
+				rec.Response.Status = http.StatusOK
+			}
+
+			name := fmt.Sprintf("%s-%06d.json", start.UTC().Format("20060102T150405.000000"), seq.Add(1))
+			if data, jerr := json.MarshalIndent(rec, "", "  "); jerr == nil {
+				if werr := os.WriteFile(filepath.Join(cfg.Dir, name), data, 0o600); werr != nil {
+					log.Printf("nojs: recorder: %v", werr)
+				}
+			}
+			return err
+		}
+	}
+}
+
+// recordRequest captures the request, leaving its body readable for the
+// handler
+func (cfg RecorderConfig) recordRequest(r *http.Request) RecordedMessage {
+	u := *r.URL
+	u.RawQuery = cfg.redactValues(u.Query()).Encode()
+	msg := RecordedMessage{Method: r.Method, URL: u.RequestURI(), Header: cfg.redactHeader(r.Header)}
+	if r.Body == nil || r.Body == http.NoBody {
+		return msg
+	}
+
+	buf, _ := io.ReadAll(io.LimitReader(r.Body, cfg.MaxBodyBytes+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+	if int64(len(buf)) > cfg.MaxBodyBytes {
+		msg.Truncated = true
+		return msg
+	}
+	msg.Body = cfg.redactBody(r.Header.Get("Content-Type"), buf)
+	return msg
+}
+
+// redactHeader copies header with secret values replaced
+func (cfg RecorderConfig) redactHeader(header http.Header) http.Header {
+	out := header.Clone()
+	for _, name := range cfg.RedactHeaders {
+		if _, ok := out[http.CanonicalHeaderKey(name)]; ok {
+			out.Set(name, redacted)
+		}
+	}
+	return out
+}
+
+// secretField reports whether a field name matches RedactFields
+func (cfg RecorderConfig) secretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, field := range cfg.RedactFields {
+		if strings.Contains(name, strings.ToLower(field)) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValues replaces the values of secret fields
+func (cfg RecorderConfig) redactValues(values url.Values) url.Values {
+	for name := range values {
+		if cfg.secretField(name) {
+			values[name] = []string{redacted}
+		}
+	}
+	return values
+}
+
+// secretInput matches the inputs of HTML pages, e.g. CSRF token fields
+var secretInput = regexp.MustCompile(`(?i)<input\b[^>]*>`)
+
+// inputAttr matches the name and value attributes of an input
+var inputAttr = regexp.MustCompile(`(?i)\b(name|value)="([^"]*)"`)
+
+// redactBody redacts form, JSON and HTML bodies; other bodies are kept as
+// is
+func (cfg RecorderConfig) redactBody(contentType string, body []byte) string {
+	switch {
+	case strings.HasPrefix(contentType, "text/html"):
+		return secretInput.ReplaceAllStringFunc(string(body), func(input string) string {
+			for _, m := range inputAttr.FindAllStringSubmatch(input, -1) {
+				if strings.EqualFold(m[1], "name") && cfg.secretField(html.UnescapeString(m[2])) {
+					return inputAttr.ReplaceAllStringFunc(input, func(attr string) string {
+						if strings.HasPrefix(strings.ToLower(attr), "value=") {
+							return `value="` + redacted + `"`
+						}
+						return attr
+					})
+				}
+			}
+			return input
+		})
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			return cfg.redactValues(values).Encode()
+		}
+	case strings.HasPrefix(contentType, "application/json"):
+		var v interface{}
+		if json.Unmarshal(body, &v) == nil {
+			if data, err := json.Marshal(cfg.redactJSON(v)); err == nil {
+				return string(data)
+			}
+		}
+	case strings.HasPrefix(contentType, "multipart/"):
+		return "" // Uploads are not recorded
+	}
+	return string(body)
+}
+
+// redactJSON replaces the values of secret keys at any depth
+func (cfg RecorderConfig) redactJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if cfg.secretField(key) {
+				v[key] = redacted
+			} else {
+				v[key] = cfg.redactJSON(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = cfg.redactJSON(value)
+		}
+	}
+	return v
+}
+
+// recordingWriter keeps the status and the start of the body
+type recordingWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	max       int64
+	truncated bool
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 && !informational(status) {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.truncated && w.max-int64(w.body.Len()) >= int64(len(b)) {
+		w.body.Write(b)
+	} else {
+		w.truncated = true
+		w.body.Reset()
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher for streams
+func (w *recordingWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// ReplayConfig configures Replay
+type ReplayConfig struct {
+	// Header is added to every replayed request, e.g. a dev session
+	// cookie in place of the redacted one
+	Header http.Header
+	// Client sends the requests; it defaults to one that does not
+	// follow redirects, so redirect responses can be compared
+	Client *http.Client
+}
+
+// ReplayResult compares a recorded response with the replayed one
+type ReplayResult struct {
+	File       string
+	Method     string
+	URL        string
+	WantStatus int
+	GotStatus  int
+	// BodyChanged is set when the bodies differ; bodies of streamed,
+	// truncated or redacted responses are not compared
+	BodyChanged bool
+	Err         error
+}
+
+// OK reports whether the replayed response matched the recording
+func (r ReplayResult) OK() bool {
+	return r.Err == nil && r.WantStatus == r.GotStatus && !r.BodyChanged
+}
+
+// Replay re-executes the requests recorded in dir, in recording order,
+// against target (e.g. "http://localhost:8080"), a dev server running the
+// code under suspicion. Requests with truncated or multipart bodies are
+// sent without a body. Bodies of streamed, truncated and redacted
+// responses are not compared, and pages embedding other per-request
+// values always report a changed body. Never point it at production:
+// recorded POSTs are sent again.
+func Replay(dir, target string, config ...ReplayConfig) ([]ReplayResult, error) {
+	var cfg ReplayConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{
+			Timeout:       30 * time.Second,
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var results []ReplayResult
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return results, err
+		}
+		var rec Recording
+		if err := json.Unmarshal(data, &rec); err != nil {
+			results = append(results, ReplayResult{File: file, Err: err})
+			continue
+		}
+		results = append(results, replayOne(client, cfg, target, file, rec))
+	}
+	return results, nil
+}
+
+// replayOne sends a recorded request and compares the response
+func replayOne(client *http.Client, cfg ReplayConfig, target, file string, rec Recording) ReplayResult {
+	result := ReplayResult{File: file, Method: rec.Request.Method, URL: rec.Request.URL, WantStatus: rec.Response.Status}
+	req, err := http.NewRequest(rec.Request.Method, strings.TrimRight(target, "/")+rec.Request.URL, strings.NewReader(rec.Request.Body))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	for name, values := range rec.Request.Header {
+		if len(values) == 1 && values[0] == redacted {
+			continue
+		}
+		req.Header[name] = values
+	}
+	for name, values := range cfg.Header {
+		req.Header[name] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+	result.GotStatus = resp.StatusCode
+	if !rec.Response.Streamed && !rec.Response.Truncated && !rec.Response.Redacted {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.BodyChanged = string(body) != rec.Response.Body
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package nojs
+
+import (
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// MoveDirection is a manual ordering operation
+type MoveDirection string
+
+// Supported move directions
+const (
+	MoveUp     MoveDirection = "up"
+	MoveDown   MoveDirection = "down"
+	MoveTop    MoveDirection = "top"
+	MoveBottom MoveDirection = "bottom"
+)
+
+// Move identifies the row to move and where to move it
+type Move struct {
+	ID        string
+	Direction MoveDirection
+}
+
+// MoveButtons creates a small POST form with top/up/down/bottom buttons
+// for the row with the given id at position index of total rows
+func MoveButtons(action, id string, index, total int) g.Node {
+	button := func(dir MoveDirection, label, text string, disabled bool) g.Node {
+		return h.Button(
+			h.Type("submit"),
+			h.Name("move"),
+			h.Value(string(dir)),
+			h.Class("move-"+string(dir)),
+			h.Aria("label", label),
+			g.If(disabled, h.Disabled()),
+			g.Text(text),
+		)
+	}
+
+	first := index == 0
+	last := index >= total-1
+
+	return Form(FormConfig{Action: action, Class: "move-buttons"},
+		h.Input(h.Type("hidden"), h.Name("id"), h.Value(id)),
+		button(MoveTop, "Move to top", "⤒", first),
+		button(MoveUp, "Move up", "↑", first),
+		button(MoveDown, "Move down", "↓", last),
+		button(MoveBottom, "Move to bottom", "⤓", last),
+	)
+}
+
+// Move returns the move submitted by MoveButtons
+func (c *Context) Move() Move {
+	return Move{
+		ID:        c.Form("id"),
+		Direction: MoveDirection(c.Form("move")),
+	}
+}
+
+// ReorderSlice returns a copy of ids with move applied. Unknown IDs and
+// directions leave the order unchanged.
+func ReorderSlice(ids []string, move Move) []string {
+	result := append([]string(nil), ids...)
+
+	from := -1
+	for i, id := range result {
+		if id == move.ID {
+			from = i
+			break
+		}
+	}
+	if from < 0 {
+		return result
+	}
+
+	to := from
+	switch move.Direction {
+	case MoveUp:
+		to = from - 1
+	case MoveDown:
+		to = from + 1
+	case MoveTop:
+		to = 0
+	case MoveBottom:
+		to = len(result) - 1
+	}
+	if to < 0 || to >= len(result) || to == from {
+		return result
+	}
+
+	item := result[from]
+	result = append(result[:from], result[from+1:]...)
+	result = append(result[:to], append([]string{item}, result[to:]...)...)
+	return result
+}
@@ -0,0 +1,85 @@
+package nojs
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// statusRecorder records the status and size of a response. Every
+// request's ResponseWriter is wrapped in one, read through ctx.Status,
+// ctx.BytesWritten and ctx.HeadersSent.
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	bytes   int64
+	timings serverTimings
+	// Set by ctx.Stream to count the bytes of streams
+	streamBytes *atomic.Uint64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	// Informational responses such as 103 Early Hints precede the real one
+	if r.status == 0 && !informational(status) {
+		r.status = status
+		r.timings.send(r.Header())
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+		r.timings.send(r.Header())
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	if r.streamBytes != nil {
+		r.streamBytes.Add(uint64(n))
+	}
+	return n, err
+}
+
+// Status returns the response status, 200 if none was written yet
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+func (r *statusRecorder) Flush() {
+	if r.status == 0 {
+		r.timings.send(r.Header())
+	}
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Status returns the status code sent to the client, or 200 when the
+// handler has not answered yet
+func (c *Context) Status() int {
+	return c.response.Status()
+}
+
+// BytesWritten returns the number of body bytes sent to the client
+func (c *Context) BytesWritten() int64 {
+	return c.response.bytes
+}
+
+// Streaming reports whether the response is a stream started by ctx.Stream
+func (c *Context) Streaming() bool {
+	return c.response.streamBytes != nil
+}
+
+// HeadersSent reports whether the status and headers have been sent, after
+// which headers can no longer be changed and errors no longer reported
+// with an error page
+func (c *Context) HeadersSent() bool {
+	return c.response.status != 0
+}
@@ -0,0 +1,319 @@
+package nojs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// ErrRevisionNotFound is returned by a RevisionStore for unknown revisions
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Revision is a saved version of a document
+type Revision struct {
+	ID         string
+	DocumentID string
+	Content    string
+	Author     string
+	Message    string
+	Created    time.Time
+}
+
+// RevisionStore persists document revisions
+type RevisionStore interface {
+	// List returns the revisions of a document, newest first
+	List(documentID string) ([]Revision, error)
+	// Get returns a single revision or ErrRevisionNotFound
+	Get(documentID, revisionID string) (Revision, error)
+	// Save stores a new revision, assigning its ID and creation time
+	Save(rev Revision) (Revision, error)
+}
+
+// MemoryRevisionStore is an in-memory RevisionStore for development
+type MemoryRevisionStore struct {
+	mu        sync.RWMutex
+	revisions map[string][]Revision
+	next      int
+}
+
+// NewMemoryRevisionStore creates an empty in-memory revision store
+func NewMemoryRevisionStore() *MemoryRevisionStore {
+	return &MemoryRevisionStore{revisions: make(map[string][]Revision)}
+}
+
+// List returns the revisions of a document, newest first
+func (s *MemoryRevisionStore) List(documentID string) ([]Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	revs := append([]Revision(nil), s.revisions[documentID]...)
+	sort.SliceStable(revs, func(i, j int) bool { return revs[i].Created.After(revs[j].Created) })
+	return revs, nil
+}
+
+// Get returns a single revision
+func (s *MemoryRevisionStore) Get(documentID, revisionID string) (Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, rev := range s.revisions[documentID] {
+		if rev.ID == revisionID {
+			return rev, nil
+		}
+	}
+	return Revision{}, ErrRevisionNotFound
+}
+
+// Save stores a new revision
+func (s *MemoryRevisionStore) Save(rev Revision) (Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.next++
+	rev.ID = fmt.Sprintf("%d", s.next)
+	if rev.Created.IsZero() {
+		rev.Created = time.Now()
+	}
+	s.revisions[rev.DocumentID] = append(s.revisions[rev.DocumentID], rev)
+	return rev, nil
+}
+
+// RevisionHistory provides prebuilt pages to list, compare and restore
+// revisions of a document
+type RevisionHistory struct {
+	Store    RevisionStore
+	BasePath string // e.g. "/revisions"
+	CSS      []string
+	// Restore applies a revision to the live document. The restored
+	// content is also saved as a new revision.
+	Restore func(ctx *Context, rev Revision) error
+	// Redirect returns where to go after a restore, defaults to the list
+	Redirect func(documentID string) string
+}
+
+// Register adds the revision pages to the server:
+//
+//	BasePath?doc=ID                      list revisions
+//	BasePath/diff?doc=ID&rev=R&against=A compare two revisions
+//	BasePath/restore                     POST doc and rev to restore
+func (rh *RevisionHistory) Register(s *Server) {
+	s.Route(rh.BasePath, rh.listHandler)
+	s.Route(rh.BasePath+"/diff", rh.diffHandler)
+	s.Route(rh.BasePath+"/restore", rh.restoreHandler)
+}
+
+func (rh *RevisionHistory) listHandler(ctx *Context) error {
+	docID := ctx.Query("doc")
+	revs, err := rh.Store.List(docID)
+	if err != nil {
+		return WrapHTTPError(http.StatusInternalServerError, "Could not load revisions", err)
+	}
+
+	rows := []g.Node{}
+	for i, rev := range revs {
+		var compare g.Node
+		if i+1 < len(revs) {
+			compare = h.A(h.Href(rh.diffURL(docID, rev.ID, revs[i+1].ID)), g.Text("Changes"))
+		}
+
+		rows = append(rows, h.Tr(
+			h.Td(g.Text(FormatDateTime(rev.Created))),
+			h.Td(g.Text(rev.Author)),
+			h.Td(g.Text(rev.Message)),
+			h.Td(compare),
+			h.Td(g.If(i > 0, rh.restoreForm(docID, rev.ID))),
+		))
+	}
+
+	return ctx.HTML(http.StatusOK, Page{
+		Title: "Revision history",
+		CSS:   rh.CSS,
+		Body: h.Main(h.Class("revisions"),
+			h.H1(g.Text("Revision history")),
+			g.If(len(revs) == 0, h.P(h.Class("empty"), g.Text("No revisions yet."))),
+			g.If(len(revs) > 0, h.Table(h.Class("table"),
+				h.THead(h.Tr(h.Th(g.Text("Date")), h.Th(g.Text("Author")), h.Th(g.Text("Message")), h.Th(), h.Th())),
+				h.TBody(rows...),
+			)),
+		),
+	}.Render())
+}
+
+func (rh *RevisionHistory) diffHandler(ctx *Context) error {
+	docID := ctx.Query("doc")
+	rev, err := rh.Store.Get(docID, ctx.Query("rev"))
+	if err != nil {
+		return WrapHTTPError(http.StatusNotFound, "Revision not found", err)
+	}
+	against, err := rh.Store.Get(docID, ctx.Query("against"))
+	if err != nil {
+		return WrapHTTPError(http.StatusNotFound, "Revision not found", err)
+	}
+
+	return ctx.HTML(http.StatusOK, Page{
+		Title: "Compare revisions",
+		CSS:   rh.CSS,
+		Body: h.Main(h.Class("revisions"),
+			h.H1(g.Text("Compare revisions")),
+			h.P(g.Textf("%s → %s", FormatDateTime(against.Created), FormatDateTime(rev.Created))),
+			DiffView(against.Content, rev.Content),
+			rh.restoreForm(docID, against.ID),
+			h.P(h.A(h.Href(rh.BasePath+"?doc="+url.QueryEscape(docID)), g.Text("Back to history"))),
+		),
+	}.Render())
+}
+
+func (rh *RevisionHistory) restoreHandler(ctx *Context) error {
+	if ctx.Method() != http.MethodPost {
+		return NewHTTPError(http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+
+	docID := ctx.Form("doc")
+	rev, err := rh.Store.Get(docID, ctx.Form("rev"))
+	if err != nil {
+		return WrapHTTPError(http.StatusNotFound, "Revision not found", err)
+	}
+
+	if rh.Restore != nil {
+		if err := rh.Restore(ctx, rev); err != nil {
+			return err
+		}
+	}
+
+	restored := rev
+	restored.Message = "Restored revision from " + FormatDateTime(rev.Created)
+	restored.Created = time.Time{}
+	if _, err := rh.Store.Save(restored); err != nil {
+		return WrapHTTPError(http.StatusInternalServerError, "Could not save revision", err)
+	}
+
+	redirect := rh.BasePath + "?doc=" + url.QueryEscape(docID)
+	if rh.Redirect != nil {
+		redirect = rh.Redirect(docID)
+	}
+	return ctx.Redirect(http.StatusSeeOther, redirect)
+}
+
+func (rh *RevisionHistory) restoreForm(docID, revID string) g.Node {
+	return Form(FormConfig{Action: rh.BasePath + "/restore", Class: "inline-form"},
+		h.Input(h.Type("hidden"), h.Name("doc"), h.Value(docID)),
+		h.Input(h.Type("hidden"), h.Name("rev"), h.Value(revID)),
+		SubmitButton("Restore", h.Class("button-small")),
+	)
+}
+
+func (rh *RevisionHistory) diffURL(docID, revID, againstID string) string {
+	return rh.BasePath + "/diff?" + url.Values{"doc": {docID}, "rev": {revID}, "against": {againstID}}.Encode()
+}
+
+// DiffLine is one line of a line-based diff
+type DiffLine struct {
+	Op   byte // ' ' unchanged, '+' added, '-' removed
+	Text string
+}
+
+// maxDiffCells caps the size of the table Diff builds, about 32MB, since
+// revisions are user content of any length
+const maxDiffCells = 4 << 20
+
+// Diff computes a line-based diff between two texts. Unchanged lines at
+// the start and end are matched directly; when the changed middle is too
+// large to compare line by line it is shown as removed and added whole.
+func Diff(before, after string) []DiffLine {
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+
+	var head, tail []DiffLine
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		head = append(head, DiffLine{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		tail = append(tail, DiffLine{' ', a[len(a)-1]})
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	for i, j := 0, len(tail)-1; i < j; i, j = i+1, j-1 {
+		tail[i], tail[j] = tail[j], tail[i]
+	}
+
+	lines := head
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, line := range a {
+			lines = append(lines, DiffLine{'-', line})
+		}
+		for _, line := range b {
+			lines = append(lines, DiffLine{'+', line})
+		}
+		return append(lines, tail...)
+	}
+	return append(append(lines, diffLCS(a, b)...), tail...)
+}
+
+// diffLCS diffs two lists of lines through their longest common
+// subsequence
+func diffLCS(a, b []string) []DiffLine {
+	// Longest common subsequence table
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{'+', b[j]})
+	}
+	return lines
+}
+
+// DiffView renders a line-based diff between two texts
+func DiffView(before, after string) g.Node {
+	rows := []g.Node{h.Class("diff")}
+	for _, line := range Diff(before, after) {
+		class := "diff-same"
+		switch line.Op {
+		case '+':
+			class = "diff-added"
+		case '-':
+			class = "diff-removed"
+		}
+		rows = append(rows, h.Div(h.Class(class), g.Text(string(line.Op)+" "+line.Text)))
+	}
+	return h.Pre(rows...)
+}
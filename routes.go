@@ -0,0 +1,122 @@
+package nojs
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// RoutesDebugPath serves the route table when ServerConfig.DevMode is set
+const RoutesDebugPath = "/_nojs/routes"
+
+// RouteInfo describes a registered route
+type RouteInfo struct {
+	// Host is the host pattern for routes of apps registered with Host
+	Host    string
+	Pattern string
+	// Methods is empty for routes accepting any method
+	Methods    []string
+	Handler    string
+	Middleware []string
+	// SEO holds the directives declared with Server.SEO
+	SEO SEO
+}
+
+// routeRecord remembers a registration for Routes
+type routeRecord struct {
+	pattern string
+	method  string
+	handler Handler
+	name    string
+	app     *Server
+}
+
+// Routes lists the registered routes in registration order, including
+// those of mounted and host-routed apps, with the middleware that
+// currently applies to each
+func (s *Server) Routes() []RouteInfo {
+	var middleware []string
+	for _, m := range s.middlewares {
+		middleware = append(middleware, funcName(m))
+	}
+
+	var routes []RouteInfo
+	for _, r := range s.routes {
+		if r.app != nil {
+			prefix := strings.TrimSuffix(r.pattern, "/")
+			for _, info := range r.app.Routes() {
+				info.Pattern = prefix + info.Pattern
+				info.Middleware = append(append([]string(nil), middleware...), info.Middleware...)
+				routes = append(routes, info)
+			}
+			continue
+		}
+
+		info := RouteInfo{Pattern: r.pattern, Handler: r.name, Middleware: middleware, SEO: s.seo[r.pattern]}
+		if r.handler != nil {
+			info.Handler = funcName(r.handler)
+		}
+		if r.method != "" {
+			info.Methods = []string{r.method}
+			if r.method == http.MethodGet {
+				info.Methods = append(info.Methods, http.MethodHead)
+			}
+		}
+		if r.handler == nil {
+			info.Middleware = nil
+		}
+		routes = append(routes, info)
+	}
+
+	for _, host := range s.hosts {
+		for _, info := range host.app.Routes() {
+			info.Host = host.pattern
+			routes = append(routes, info)
+		}
+	}
+	return routes
+}
+
+// closureSuffix matches the suffix Go gives anonymous functions
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+(\.\d+)*$`)
+
+// funcName returns a short name for a function such as "main.homeHandler"
+// or "nojs.Logger"
+func funcName(fn interface{}) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "?"
+	}
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm")
+	return closureSuffix.ReplaceAllString(name, "")
+}
+
+// routesDebugPage renders the route table
+func (s *Server) routesDebugPage(ctx *Context) error {
+	rows := [][]string{}
+	for _, r := range s.Routes() {
+		methods := strings.Join(r.Methods, ", ")
+		if methods == "" {
+			methods = "any"
+		}
+		rows = append(rows, []string{r.Host, r.Pattern, methods, r.Handler, strings.Join(r.Middleware, ", ")})
+	}
+
+	return ctx.HTML(http.StatusOK, Page{
+		Title: "Routes",
+		Body: h.Main(
+			h.H1(g.Text("Routes")),
+			h.P(g.Textf("%d routes registered. This page is only served in DevMode.", len(rows))),
+			Table([]string{"Host", "Pattern", "Methods", "Handler", "Middleware"}, rows),
+		),
+	}.Render())
+}
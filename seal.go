@@ -0,0 +1,48 @@
+package nojs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// errUnsealed is returned for values that fail authentication
+var errUnsealed = errors.New("invalid or tampered value")
+
+// sealer encrypts and authenticates small values such as cookie payloads
+// with AES-256-GCM under a key derived from a secret
+type sealer struct {
+	aead cipher.AEAD
+}
+
+func newSealer(secret string) *sealer {
+	key := sha256.Sum256([]byte(secret))
+	block, _ := aes.NewCipher(key[:])
+	aead, _ := cipher.NewGCM(block)
+	return &sealer{aead: aead}
+}
+
+// seal encrypts plaintext, binding it to purpose so a value sealed for
+// one cookie cannot be replayed as another
+func (s *sealer) seal(purpose string, plaintext []byte) string {
+	nonce := make([]byte, s.aead.NonceSize())
+	rand.Read(nonce)
+	return base64.RawURLEncoding.EncodeToString(s.aead.Seal(nonce, nonce, plaintext, []byte(purpose)))
+}
+
+// open reverses seal
+func (s *sealer) open(purpose, value string) ([]byte, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return nil, errUnsealed
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(purpose))
+	if err != nil {
+		return nil, errUnsealed
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,165 @@
+package nojs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// secretsHeader starts every encrypted secrets file
+const secretsHeader = "nojs-secrets-v1\n"
+
+// SecretsKeyEnv is the environment variable LoadSecretsFromEnv reads the
+// master key from
+const SecretsKeyEnv = "NOJS_MASTER_KEY"
+
+// ErrSecretsKey is returned for malformed master keys
+var ErrSecretsKey = errors.New("secrets key must be 32 bytes encoded as base64")
+
+// Secrets holds decrypted configuration secrets such as API keys
+type Secrets map[string]string
+
+// Get returns a secret or "" when it is not set
+func (s Secrets) Get(name string) string {
+	return s[name]
+}
+
+// MustGet returns a secret and panics when it is missing, for use at startup
+func (s Secrets) MustGet(name string) string {
+	value, ok := s[name]
+	if !ok {
+		panic("nojs: missing secret " + name)
+	}
+	return value
+}
+
+// GenerateSecretsKey returns a new random master key, encoded as base64
+func GenerateSecretsKey() string {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func secretsAEAD(key string) (cipher.AEAD, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil || len(raw) != 32 {
+		return nil, ErrSecretsKey
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptSecrets encrypts plaintext with AES-256-GCM under key
+func EncryptSecrets(key string, plaintext []byte) ([]byte, error) {
+	aead, err := secretsAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(secretsHeader))
+	return []byte(secretsHeader + base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// DecryptSecrets reverses EncryptSecrets
+func DecryptSecrets(key string, data []byte) ([]byte, error) {
+	aead, err := secretsAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(data, []byte(secretsHeader)) {
+		return nil, errors.New("not a nojs secrets file")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data[len(secretsHeader):])))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, errors.New("corrupt secrets file")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(secretsHeader))
+	if err != nil {
+		return nil, errors.New("cannot decrypt secrets: wrong key or tampered file")
+	}
+	return plaintext, nil
+}
+
+// ParseSecrets parses KEY=value lines, ignoring blanks and # comments
+func ParseSecrets(data []byte) (Secrets, error) {
+	secrets := Secrets{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("secrets line %d: expected KEY=value", n)
+		}
+		secrets[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return secrets, scanner.Err()
+}
+
+// Format returns the secrets as sorted KEY=value lines
+func (s Secrets) Format() []byte {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s=%s\n", name, s[name])
+	}
+	return buf.Bytes()
+}
+
+// LoadSecrets decrypts and parses the secrets file at path
+func LoadSecrets(path, key string) (Secrets, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := DecryptSecrets(key, data)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSecrets(plaintext)
+}
+
+// LoadSecretsFromEnv loads the secrets file at path with the master key
+// from the NOJS_MASTER_KEY environment variable
+func LoadSecretsFromEnv(path string) (Secrets, error) {
+	key := os.Getenv(SecretsKeyEnv)
+	if key == "" {
+		return nil, fmt.Errorf("%s is not set", SecretsKeyEnv)
+	}
+	return LoadSecrets(path, key)
+}
+
+// SaveSecrets encrypts secrets and writes them to path, readable only by
+// the owner
+func SaveSecrets(path, key string, secrets Secrets) error {
+	data, err := EncryptSecrets(key, secrets.Format())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
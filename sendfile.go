@@ -0,0 +1,60 @@
+package nojs
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+)
+
+// SendfileMode selects how ctx.SendFile delivers file contents
+type SendfileMode string
+
+const (
+	// SendfileNone serves files from the Go process
+	SendfileNone SendfileMode = ""
+	// SendfileXAccel emits X-Accel-Redirect for nginx internal locations
+	SendfileXAccel SendfileMode = "x-accel"
+	// SendfileXSendfile emits X-Sendfile for Apache mod_xsendfile and lighttpd
+	SendfileXSendfile SendfileMode = "x-sendfile"
+)
+
+// SendfileConfig configures file offloading to a front proxy
+type SendfileConfig struct {
+	Mode SendfileMode
+	// Root is the directory SendFile paths are resolved against
+	Root string
+	// InternalPrefix is the nginx internal location mapped to Root,
+	// e.g. "/protected/" for `location /protected/ { internal; alias /srv/files/; }`
+	InternalPrefix string
+}
+
+// SendFile sends the file at name, relative to the configured Sendfile
+// root. Run authorization checks before calling it: when offloading is
+// enabled only headers are written and the proxy streams the file itself.
+func (c *Context) SendFile(name string) error {
+	cfg := c.server.config.Sendfile
+	clean := path.Clean("/" + name)
+
+	if ctype := mime.TypeByExtension(path.Ext(clean)); ctype != "" {
+		c.ResponseWriter.Header().Set("Content-Type", ctype)
+	}
+
+	switch cfg.Mode {
+	case SendfileXAccel:
+		c.ResponseWriter.Header().Set("X-Accel-Redirect", path.Join("/", cfg.InternalPrefix, clean))
+		c.ResponseWriter.WriteHeader(http.StatusOK)
+	case SendfileXSendfile:
+		abs, err := filepath.Abs(filepath.Join(cfg.Root, filepath.FromSlash(clean)))
+		if err != nil {
+			return WrapHTTPError(http.StatusInternalServerError, "Internal Server Error", err)
+		}
+		c.ResponseWriter.Header().Set("X-Sendfile", abs)
+		c.ResponseWriter.WriteHeader(http.StatusOK)
+	default:
+		http.ServeFile(c.ResponseWriter, c.Request, filepath.Join(cfg.Root, filepath.FromSlash(clean)))
+	}
+
+	c.written = true
+	return nil
+}
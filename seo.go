@@ -0,0 +1,220 @@
+package nojs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// keySEO stores the SEO flags set with ctx.SEO
+const keySEO = "nojs.seo"
+
+// SEO holds search engine directives for a page
+type SEO struct {
+	NoIndex  bool
+	NoFollow bool
+	// Canonical is the preferred URL of the page, absolute or a path
+	Canonical string
+}
+
+// robots returns the value of the robots meta tag and X-Robots-Tag header
+func (s SEO) robots() string {
+	var directives []string
+	if s.NoIndex {
+		directives = append(directives, "noindex")
+	}
+	if s.NoFollow {
+		directives = append(directives, "nofollow")
+	}
+	return strings.Join(directives, ", ")
+}
+
+// merge applies the set fields of o on top of s
+func (s SEO) merge(o SEO) SEO {
+	s.NoIndex = s.NoIndex || o.NoIndex
+	s.NoFollow = s.NoFollow || o.NoFollow
+	if o.Canonical != "" {
+		s.Canonical = o.Canonical
+	}
+	return s
+}
+
+// SEO declares search engine directives for the route registered with
+// pattern. They are sent as an X-Robots-Tag header, added to the head of
+// HTML pages, and respected by Robots and Sitemap.
+//
+//	server.Route("/admin/", adminHandler)
+//	server.SEO("/admin/", nojs.SEO{NoIndex: true, NoFollow: true})
+func (s *Server) SEO(pattern string, seo SEO) {
+	if s.seo == nil {
+		s.seo = make(map[string]SEO)
+	}
+	s.seo[pattern] = seo
+}
+
+// SEO sets search engine directives for this response on top of those
+// declared for the route, e.g. noindex for search results or a
+// canonical URL for a filtered list
+func (c *Context) SEO(seo SEO) {
+	current, _ := ValueOf[SEO](c, keySEO)
+	c.Set(keySEO, current.merge(seo))
+}
+
+// seoFlags returns the directives that apply to the response
+func (c *Context) seoFlags() SEO {
+	flags := c.server.seo[c.route]
+	if set, ok := ValueOf[SEO](c, keySEO); ok {
+		flags = flags.merge(set)
+	}
+	return flags
+}
+
+// seoTags returns the robots meta tag and canonical link for a page's head
+func (c *Context) seoTags(seo SEO) []g.Node {
+	var tags []g.Node
+	if robots := seo.robots(); robots != "" {
+		tags = append(tags, h.Meta(h.Name("robots"), h.Content(robots)))
+	}
+	if seo.Canonical != "" {
+		canonical := seo.Canonical
+		if strings.HasPrefix(canonical, "/") {
+			canonical = c.Scheme() + "://" + c.Request.Host + canonical
+		}
+		tags = append(tags, h.Link(h.Rel("canonical"), h.Href(canonical)))
+	}
+	return tags
+}
+
+// applySEO sets the X-Robots-Tag header and returns the flags that need
+// adding to the page head
+func (c *Context) applySEO() (SEO, bool) {
+	seo := c.seoFlags()
+	if robots := seo.robots(); robots != "" {
+		c.ResponseWriter.Header().Set("X-Robots-Tag", robots)
+	}
+	return seo, seo != SEO{}
+}
+
+// RobotsConfig configures the robots.txt served by Robots
+type RobotsConfig struct {
+	// Disallow lists extra paths crawlers should skip
+	Disallow []string
+	// Sitemap is the path of the sitemap to advertise, if any
+	Sitemap string
+}
+
+// Robots serves /robots.txt disallowing the routes declared NoIndex.
+// Keep in mind that crawlers never see the noindex tag of a page they
+// may not crawl; it still prevents crawling of private areas.
+func (s *Server) Robots(config ...RobotsConfig) {
+	var cfg RobotsConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	s.GET("/robots.txt", func(ctx *Context) error {
+		var b strings.Builder
+		b.WriteString("User-agent: *\n")
+		disallow := append([]string(nil), cfg.Disallow...)
+		for _, route := range s.Routes() {
+			if route.SEO.NoIndex && route.Host == "" {
+				disallow = append(disallow, robotsPath(route.Pattern))
+			}
+		}
+		if len(disallow) == 0 {
+			b.WriteString("Disallow:\n")
+		}
+		for _, path := range disallow {
+			fmt.Fprintf(&b, "Disallow: %s\n", path)
+		}
+		if cfg.Sitemap != "" {
+			fmt.Fprintf(&b, "\nSitemap: %s\n", ctx.AbsoluteURL(cfg.Sitemap))
+		}
+		return ctx.Text(http.StatusOK, b.String())
+	})
+}
+
+// robotsPath turns a route pattern into a robots.txt path, with
+// wildcards for path parameters
+func robotsPath(pattern string) string {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		pattern = path
+	}
+	var b strings.Builder
+	for len(pattern) > 0 {
+		start := strings.IndexByte(pattern, '{')
+		end := strings.IndexByte(pattern, '}')
+		if start < 0 || end < start {
+			b.WriteString(pattern)
+			break
+		}
+		b.WriteString(pattern[:start] + "*")
+		pattern = pattern[end+1:]
+	}
+	return b.String()
+}
+
+// SitemapURL is an entry of the sitemap
+type SitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Sitemap serves an XML sitemap at path listing the server's GET routes
+// without path parameters, minus those declared NoIndex and with their
+// canonical URL when one is declared. extra adds dynamic pages, e.g. one
+// per article.
+func (s *Server) Sitemap(path string, extra ...func(ctx *Context) []SitemapURL) {
+	s.GET(path, func(ctx *Context) error {
+		var urls []SitemapURL
+		seen := map[string]bool{}
+		add := func(u SitemapURL) {
+			if strings.HasPrefix(u.Loc, "/") {
+				u.Loc = ctx.AbsoluteURL(u.Loc)
+			}
+			if !seen[u.Loc] {
+				seen[u.Loc] = true
+				urls = append(urls, u)
+			}
+		}
+
+		for _, route := range s.Routes() {
+			if !sitemapRoute(route, path) {
+				continue
+			}
+			loc := route.Pattern
+			if route.SEO.Canonical != "" {
+				loc = route.SEO.Canonical
+			}
+			add(SitemapURL{Loc: loc})
+		}
+		for _, fn := range extra {
+			for _, u := range fn(ctx) {
+				add(u)
+			}
+		}
+
+		return ctx.XML(http.StatusOK, struct {
+			XMLName xml.Name     `xml:"urlset"`
+			XMLNS   string       `xml:"xmlns,attr"`
+			URLs    []SitemapURL `xml:"url"`
+		}{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9", URLs: urls})
+	})
+}
+
+// sitemapRoute reports whether a route is a page worth listing
+func sitemapRoute(route RouteInfo, sitemapPath string) bool {
+	if route.SEO.NoIndex || route.Host != "" || route.Pattern == sitemapPath || route.Pattern == "/robots.txt" {
+		return false
+	}
+	if strings.ContainsAny(route.Pattern, "{ ") || strings.HasPrefix(route.Pattern, "/_nojs/") || route.Handler == "" {
+		return false
+	}
+	if strings.HasPrefix(route.Handler, "static files") || strings.HasPrefix(route.Handler, "media files") {
+		return false
+	}
+	return len(route.Methods) == 0 || Contains(route.Methods, http.MethodGet)
+}
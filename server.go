@@ -3,9 +3,17 @@ package nojs
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	g "maragu.dev/gomponents"
 )
 
 // Server represents a NoJS web server
@@ -13,6 +21,33 @@ type Server struct {
 	mux         *http.ServeMux
 	middlewares []Middleware
 	config      ServerConfig
+	index       []IndexEntry
+	methods     map[string]map[string]Handler
+	hosts       []hostRoute
+	hooks       lifecycleHooks
+	routes      []routeRecord
+	apiDocs     []APIOperation
+	formReads   formReads
+	devReload   *devReload
+	stash       *stashStore
+	stashOnce   sync.Once
+	cookies     *cookieKeys
+	seo         map[string]SEO
+	cookieOnce  sync.Once
+	proxies     []*net.IPNet
+	proxiesOnce sync.Once
+	hints       sync.Map // Route to the stylesheets of its last page
+
+	// Set when the server is mounted inside another one
+	parent      *Server
+	mountPrefix string
+
+	// Closed on shutdown so streams can finish; streams tracks them
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+	streams      sync.WaitGroup
+	openStreams  atomic.Int64
+	streamBytes  atomic.Uint64
 }
 
 // ServerConfig holds server configuration
@@ -23,6 +58,90 @@ type ServerConfig struct {
 	MaxHeaderBytes    int
 	StreamingEnabled  bool
 	AutoRefreshPeriod time.Duration
+
+	// StreamDebug embeds the request ID and a sequence number as HTML
+	// comments in streaming responses and logs them, so chunks seen by
+	// the browser can be correlated with server logs
+	StreamDebug         bool
+	StreamDebugInterval time.Duration
+
+	// BaseURL is the public URL the site is reached through. When set
+	// together with BufferingCheck, the server fetches a streaming test
+	// route through it on startup and warns if a proxy buffers chunks
+	BaseURL        string
+	BufferingCheck bool
+
+	// Sendfile offloads ctx.SendFile responses to nginx or Apache
+	Sendfile SendfileConfig
+
+	// AutocertCacheDir stores Let's Encrypt certificates for
+	// StartAutoTLS, defaults to "certs"
+	AutocertCacheDir string
+	// AutocertEmail is given to Let's Encrypt for expiry notices
+	AutocertEmail string
+
+	// ShutdownGracePeriod is how long shutdown waits for streams to
+	// write their closing message and end
+	ShutdownGracePeriod time.Duration
+	// StreamShutdownMessage is written to open streams on shutdown. The
+	// default reloads the page (or iframe) after two seconds, so viewers
+	// reconnect to the next instance.
+	StreamShutdownMessage string
+	// StreamErrorTrailer renders what is written to a stream whose
+	// handler fails or panics after it started, before the document is
+	// ended. By default it is a banner asking to reload the page.
+	StreamErrorTrailer func(ctx *Context, err error) g.Node
+
+	// HTMXMode sends partial HTML from Fragment routes to HTMX requests
+	HTMXMode bool
+
+	// TrailingSlash redirects URLs to a canonical form with or without a
+	// trailing slash, so every page has a single URL
+	TrailingSlash TrailingSlash
+
+	// CaseInsensitive redirects paths with capitals to their lowercase
+	// form when only that form matches a route
+	CaseInsensitive bool
+
+	// DevMode enables development helpers: the route table at
+	// RoutesDebugPath and automatic page reloads when files under
+	// DevWatchDirs change or the server restarts. Never enable it in
+	// production.
+	DevMode      bool
+	DevWatchDirs []string
+
+	// CookieSecrets sign and encrypt cookies set with SetSignedCookie
+	// and SetEncryptedCookie. The first secret is used for new cookies;
+	// the others are still accepted, so secrets can be rotated by
+	// prepending a new one. A random secret is used when empty.
+	CookieSecrets []string
+
+	// TrustedProxies lists the addresses or CIDR ranges of the load
+	// balancers whose forwarding headers ctx.ClientIP and ProxyHeaders
+	// believe, e.g. "10.0.0.0/8". Leave it empty when clients connect
+	// directly.
+	TrustedProxies []string
+	// ClientIPHeader names the one header the proxies set to the client
+	// address, such as "X-Real-IP" or "CF-Connecting-IP". When empty,
+	// X-Forwarded-For is walked from the right, skipping trusted hops;
+	// "Forwarded" does the same with the RFC 7239 header.
+	ClientIPHeader string
+
+	// Layout wraps the content sent with ctx.Render
+	Layout *Layout
+
+	// EarlyHints sends a 103 Early Hints response preloading the
+	// stylesheets of the page last rendered by a route before running
+	// its handler. Some old proxies mishandle informational responses.
+	EarlyHints bool
+
+	// TurboMode answers Turbo-Frame requests with only the matching
+	// <turbo-frame> element of the rendered page
+	TurboMode bool
+
+	// Purger evicts CDN responses tagged with the surrogate keys queued
+	// by ctx.Purge
+	Purger Purger
 }
 
 // DefaultServerConfig returns sensible defaults
@@ -34,6 +153,11 @@ func DefaultServerConfig() ServerConfig {
 		MaxHeaderBytes:    1 << 20, // 1 MB
 		StreamingEnabled:  true,
 		AutoRefreshPeriod: 5 * time.Second,
+
+		StreamDebugInterval: 5 * time.Second,
+
+		ShutdownGracePeriod:   10 * time.Second,
+		StreamShutdownMessage: `<meta http-equiv="refresh" content="2">`,
 	}
 }
 
@@ -44,19 +168,64 @@ func NewServer(config ...ServerConfig) *Server {
 		cfg = config[0]
 	}
 
-	return &Server{
-		mux:    http.NewServeMux(),
-		config: cfg,
+	s := &Server{
+		mux:      http.NewServeMux(),
+		config:   cfg,
+		shutdown: make(chan struct{}),
+	}
+
+	if cfg.BufferingCheck {
+		s.mux.HandleFunc(BufferingTestPath, bufferingTestHandler)
+		s.routes = append(s.routes, routeRecord{pattern: BufferingTestPath, name: "buffering check"})
 	}
+	if cfg.DevMode {
+		s.Route(RoutesDebugPath, s.routesDebugPage)
+		s.devReload = newDevReload()
+		s.Route(DevReloadPath, s.devReloadHandler)
+	}
+
+	return s
 }
 
 // Route registers a route handler
 func (s *Server) Route(pattern string, handler Handler) {
+	s.routes = append(s.routes, routeRecord{pattern: pattern, handler: handler})
+	s.handle(pattern, handler)
+}
+
+// handle registers handler on the mux, wrapped with hooks and middleware
+func (s *Server) handle(pattern string, handler Handler) {
 	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w}
 		ctx := &Context{
 			Request:        r,
-			ResponseWriter: w,
+			ResponseWriter: rec,
 			server:         s,
+			route:          pattern,
+			response:       rec,
+		}
+
+		defer func() {
+			// Deferred so that aborted streams are counted out too
+			if ctx.streamCounted {
+				s.streamEnded()
+			}
+		}()
+		defer func() {
+			if p := recover(); p != nil {
+				if p != http.ErrAbortHandler && ctx.stream != nil && ctx.HeadersSent() {
+					ctx.stream.fail(fmt.Errorf("panic: %v", p))
+				}
+				panic(p)
+			}
+		}()
+
+		for _, hook := range s.hooks.request {
+			hook(ctx)
+		}
+
+		if s.config.EarlyHints {
+			ctx.sendEarlyHints()
 		}
 
 		// Apply middlewares
@@ -66,31 +235,172 @@ func (s *Server) Route(pattern string, handler Handler) {
 		}
 
 		// Execute handler
-		if err := finalHandler(ctx); err != nil {
+		err := finalHandler(ctx)
+		if err != nil {
 			s.handleError(ctx, err)
+		} else {
+			ctx.purgeQueued()
+		}
+
+		for _, hook := range s.hooks.response {
+			hook(ctx, err)
 		}
 	})
 }
 
+// GET registers a handler for GET (and HEAD) requests to pattern
+func (s *Server) GET(pattern string, handler Handler) {
+	s.handleMethod(http.MethodGet, pattern, handler)
+}
+
+// POST registers a handler for POST requests to pattern
+func (s *Server) POST(pattern string, handler Handler) {
+	s.handleMethod(http.MethodPost, pattern, handler)
+}
+
+// PUT registers a handler for PUT requests to pattern, including POST
+// forms overriding the method with _method=PUT
+func (s *Server) PUT(pattern string, handler Handler) {
+	s.handleMethod(http.MethodPut, pattern, handler)
+}
+
+// PATCH registers a handler for PATCH requests to pattern, including POST
+// forms overriding the method with _method=PATCH
+func (s *Server) PATCH(pattern string, handler Handler) {
+	s.handleMethod(http.MethodPatch, pattern, handler)
+}
+
+// DELETE registers a handler for DELETE requests to pattern, including
+// POST forms overriding the method with _method=DELETE
+func (s *Server) DELETE(pattern string, handler Handler) {
+	s.handleMethod(http.MethodDelete, pattern, handler)
+}
+
+// handleMethod adds a method handler for pattern, registering a
+// dispatching route the first time the pattern is seen. Requests with
+// other methods get 405 Method Not Allowed with an Allow header.
+func (s *Server) handleMethod(method, pattern string, handler Handler) {
+	if s.methods == nil {
+		s.methods = make(map[string]map[string]Handler)
+	}
+
+	handlers, exists := s.methods[pattern]
+	if !exists {
+		handlers = make(map[string]Handler)
+		s.methods[pattern] = handlers
+		s.handle(pattern, func(ctx *Context) error {
+			method := ctx.Method()
+			if h, ok := handlers[method]; ok {
+				return h(ctx)
+			}
+			if h, ok := handlers[http.MethodGet]; ok && method == http.MethodHead {
+				return h(ctx)
+			}
+
+			ctx.ResponseWriter.Header().Set("Allow", allowedMethods(handlers))
+			return NewHTTPError(http.StatusMethodNotAllowed, "Method Not Allowed")
+		})
+	}
+	handlers[method] = handler
+	s.routes = append(s.routes, routeRecord{pattern: pattern, method: method, handler: handler})
+}
+
+// allowedMethods lists the methods handled for a pattern, for the Allow header
+func allowedMethods(handlers map[string]Handler) string {
+	var methods []string
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		_, ok := handlers[method]
+		if method == http.MethodHead {
+			_, ok = handlers[http.MethodGet]
+		}
+		if ok {
+			methods = append(methods, method)
+		}
+	}
+	return strings.Join(methods, ", ")
+}
+
 // Use adds middleware to the server
 func (s *Server) Use(middleware Middleware) {
 	s.middlewares = append(s.middlewares, middleware)
 }
 
+// Mount serves app under prefix. Request paths reach app with the prefix
+// stripped; the server's middleware runs first, followed by app's own
+// middleware, which does not leak into the server's other routes.
+func (s *Server) Mount(prefix string, app *Server) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	app.parent = s
+	app.mountPrefix = prefix
+	s.routes = append(s.routes, routeRecord{pattern: prefix + "/", app: app})
+
+	s.handle(prefix+"/", func(ctx *Context) error {
+		http.StripPrefix(prefix, app).ServeHTTP(ctx.ResponseWriter, ctx.Request)
+		ctx.written = true
+		return nil
+	})
+}
+
+// URL returns the public path of a path inside this server, adding the
+// prefixes of every server it is mounted in
+func (s *Server) URL(path string) string {
+	for app := s; app != nil; app = app.parent {
+		path = app.mountPrefix + path
+	}
+	return path
+}
+
 // Static serves static files from a directory
-func (s *Server) Static(pattern string, dir string) {
-	s.mux.Handle(pattern, http.StripPrefix(pattern, http.FileServer(http.Dir(dir))))
+func (s *Server) Static(pattern string, dir string, opts ...StaticOptions) {
+	var o StaticOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Precompress {
+		if err := precompressDir(dir); err != nil {
+			log.Printf("nojs: precompressing %s: %v", dir, err)
+		}
+	}
+
+	s.routes = append(s.routes, routeRecord{pattern: pattern, name: "static files from " + dir})
+	sh := &staticHandler{
+		dir:   dir,
+		files: http.FileServer(http.Dir(dir)),
+	}
+	if len(o.ImageFormats) > 0 {
+		sh.images = newImageVariants(dir, o)
+	}
+	s.mux.Handle(pattern, http.StripPrefix(pattern, sh))
+}
+
+// ServeHTTP implements http.Handler, running the matching route with
+// its middleware. It lets a Server be embedded in another net/http
+// application or router instead of owning the process through Start.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if len(s.hosts) > 0 {
+		if app, sub, ok := s.matchHost(r.Host); ok {
+			if sub != "" {
+				r = r.WithContext(context.WithValue(r.Context(), subdomainKey{}, sub))
+			}
+			app.ServeHTTP(w, r)
+			return
+		}
+	}
+	if s.config.TrailingSlash != TrailingSlashAsIs || s.config.CaseInsensitive {
+		if target := s.canonicalPath(r); target != "" {
+			s.redirectCanonical(w, r, target)
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
 }
 
 // Start starts the HTTP server
 func (s *Server) Start(addr string) error {
-	srv := &http.Server{
-		Addr:           addr,
-		Handler:        s.mux,
-		ReadTimeout:    s.config.ReadTimeout,
-		WriteTimeout:   s.config.WriteTimeout,
-		IdleTimeout:    s.config.IdleTimeout,
-		MaxHeaderBytes: s.config.MaxHeaderBytes,
+	srv := s.newHTTPServer(addr)
+
+	if err := s.starting(); err != nil {
+		return err
 	}
 
 	fmt.Printf("NoJS server starting on %s\n", addr)
@@ -99,29 +409,67 @@ func (s *Server) Start(addr string) error {
 
 // StartWithContext starts the server with context for graceful shutdown
 func (s *Server) StartWithContext(ctx context.Context, addr string) error {
-	srv := &http.Server{
-		Addr:           addr,
-		Handler:        s.mux,
-		ReadTimeout:    s.config.ReadTimeout,
-		WriteTimeout:   s.config.WriteTimeout,
-		IdleTimeout:    s.config.IdleTimeout,
-		MaxHeaderBytes: s.config.MaxHeaderBytes,
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.ServeWithContext(ctx, l)
+}
+
+// root returns the outermost server this one is mounted in
+func (s *Server) root() *Server {
+	for s.parent != nil {
+		s = s.parent
 	}
+	return s
+}
+
+// DrainStreams signals every open stream to write its closing message and
+// end, then waits for them up to ShutdownGracePeriod. StartWithContext
+// calls it before shutting down; call it yourself before http.Server.Shutdown
+// when serving the Server through your own http.Server.
+func (s *Server) DrainStreams() {
+	root := s.root()
+	root.shutdownOnce.Do(func() { close(root.shutdown) })
 
+	done := make(chan struct{})
 	go func() {
-		<-ctx.Done()
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		srv.Shutdown(shutdownCtx)
+		root.streams.Wait()
+		close(done)
 	}()
 
-	fmt.Printf("NoJS server starting on %s\n", addr)
-	return srv.ListenAndServe()
+	select {
+	case <-done:
+	case <-time.After(s.config.ShutdownGracePeriod):
+		log.Printf("nojs: shutdown grace period elapsed with streams still open")
+	}
+}
+
+// streamEnded counts out a stream started by ctx.Stream
+func (s *Server) streamEnded() {
+	root := s.root()
+	root.openStreams.Add(-1)
+	root.streams.Done()
+}
+
+// StreamStats returns the number of streams open now and the bytes
+// written to streams so far, including those of mounted apps
+func (s *Server) StreamStats() (open int64, bytes uint64) {
+	root := s.root()
+	return root.openStreams.Load(), root.streamBytes.Load()
 }
 
 // handleError handles errors in a consistent way
 func (s *Server) handleError(ctx *Context, err error) {
-	if httpErr, ok := err.(*HTTPError); ok {
+	if ctx.HeadersSent() {
+		// Too late for an error page; close a stream's document instead
+		if ctx.stream != nil {
+			ctx.stream.fail(err)
+		}
+		return
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
 		http.Error(ctx.ResponseWriter, httpErr.Message, httpErr.Code)
 	} else {
 		http.Error(ctx.ResponseWriter, "Internal Server Error", http.StatusInternalServerError)
@@ -0,0 +1,387 @@
+package nojs
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionConfig configures SessionManager
+type SessionConfig struct {
+	CookieName string
+	// IdleTimeout expires sessions not used for this long
+	IdleTimeout time.Duration
+	// AbsoluteTimeout expires sessions this long after creation, however
+	// active they are
+	AbsoluteTimeout time.Duration
+	// Secure restricts the cookie to HTTPS
+	Secure bool
+	// Store keeps session data, defaults to a MemorySessionStore
+	Store SessionStore
+}
+
+// DefaultSessionConfig returns sensible defaults
+func DefaultSessionConfig() SessionConfig {
+	return SessionConfig{
+		CookieName:      "session",
+		IdleTimeout:     24 * time.Hour,
+		AbsoluteTimeout: 7 * 24 * time.Hour,
+	}
+}
+
+// SessionData is the server-side state shared by every request of a session
+type SessionData struct {
+	mu        sync.RWMutex
+	Values    map[string]interface{}
+	UserID    string
+	UserAgent string
+	IP        string
+	Created   time.Time
+	LastSeen  time.Time
+
+	dirty   bool // Changed since loaded, so stores must save it
+	version int  // Payload version, see SessionCodec
+}
+
+// NewSessionData creates empty session data
+func NewSessionData(now time.Time) *SessionData {
+	return &SessionData{Values: make(map[string]interface{}), Created: now, LastSeen: now}
+}
+
+// SessionStore keeps session data by session ID
+type SessionStore interface {
+	// Get returns the session data, or nil if there is none
+	Get(id string) *SessionData
+	Put(id string, data *SessionData)
+	Delete(id string)
+	// UserSessions returns the sessions of a user keyed by session ID
+	UserSessions(userID string) map[string]*SessionData
+	// DeleteExpired removes sessions for which expired returns true
+	DeleteExpired(expired func(*SessionData) bool)
+}
+
+// MemorySessionStore is the default in-memory SessionStore
+type MemorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*SessionData
+}
+
+// NewMemorySessionStore creates an empty in-memory session store
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]*SessionData)}
+}
+
+// Get returns the session data, or nil if there is none
+func (m *MemorySessionStore) Get(id string) *SessionData {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sessions[id]
+}
+
+// Put stores session data
+func (m *MemorySessionStore) Put(id string, data *SessionData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[id] = data
+}
+
+// Delete removes a session
+func (m *MemorySessionStore) Delete(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+}
+
+// UserSessions returns the sessions of a user keyed by session ID
+func (m *MemorySessionStore) UserSessions(userID string) map[string]*SessionData {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[string]*SessionData)
+	for id, data := range m.sessions {
+		data.mu.RLock()
+		if userID != "" && data.UserID == userID {
+			result[id] = data
+		}
+		data.mu.RUnlock()
+	}
+	return result
+}
+
+// DeleteExpired removes sessions for which expired returns true
+func (m *MemorySessionStore) DeleteExpired(expired func(*SessionData) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, data := range m.sessions {
+		if expired(data) {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+// sessionManager signs cookies and applies expiry on top of a SessionStore
+type sessionManager struct {
+	store  SessionStore
+	secret []byte
+	config SessionConfig
+}
+
+// Session is the session of the current request
+type Session struct {
+	id        string
+	data      *SessionData
+	manager   *sessionManager
+	w         http.ResponseWriter
+	server    *Server
+	destroyed bool
+}
+
+// ID returns the session ID
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Get retrieves a value from the session
+func (s *Session) Get(key string) interface{} {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	return s.data.Values[key]
+}
+
+// Set stores a value in the session
+func (s *Session) Set(key string, value interface{}) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	s.data.Values[key] = value
+	s.data.dirty = true
+}
+
+// Delete removes a value from the session
+func (s *Session) Delete(key string) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	delete(s.data.Values, key)
+	s.data.dirty = true
+}
+
+// UserID returns the ID of the logged in user, or ""
+func (s *Session) UserID() string {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	return s.data.UserID
+}
+
+// SetUser logs a user in (or out with ""), regenerating the session ID
+// so an ID planted before login cannot be used to hijack the session.
+// Call it on every privilege change.
+func (s *Session) SetUser(userID string) {
+	s.Regenerate()
+	s.data.mu.Lock()
+	s.data.UserID = userID
+	s.data.dirty = true
+	s.data.mu.Unlock()
+}
+
+// Regenerate moves the session to a new ID, invalidating the old one,
+// and sends the new cookie. Values are kept, except the CSRF token, which
+// someone who knew the old session could otherwise keep using.
+func (s *Session) Regenerate() {
+	s.data.mu.Lock()
+	if _, ok := s.data.Values[CSRFField]; ok {
+		delete(s.data.Values, CSRFField)
+		s.data.dirty = true
+	}
+	s.data.mu.Unlock()
+
+	newID := newSessionID()
+	s.manager.store.Delete(s.id)
+	s.manager.store.Put(newID, s.data)
+	s.server.stashStore().move(s.id, newID)
+	s.id = newID
+	s.manager.setCookie(s.w, newID)
+}
+
+// Destroy deletes the session and clears its cookie
+func (s *Session) Destroy() {
+	s.manager.store.Delete(s.id)
+	s.destroyed = true
+
+	http.SetCookie(s.w, &http.Cookie{
+		Name:     s.manager.config.CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   s.manager.config.Secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// newSessionID returns a random 256-bit session ID
+func newSessionID() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// sign returns the cookie value for a session ID
+func (m *sessionManager) sign(id string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(id))
+	return id + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify returns the session ID of a signed cookie value
+func (m *sessionManager) verify(value string) (string, bool) {
+	id, _, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(value), []byte(m.sign(id))) {
+		return "", false
+	}
+	return id, true
+}
+
+func (m *sessionManager) setCookie(w http.ResponseWriter, id string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.config.CookieName,
+		Value:    m.sign(id),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   m.config.Secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// expired reports whether a session is past its idle or absolute timeout.
+// The caller must hold data.mu.
+func (m *sessionManager) expired(data *SessionData, now time.Time) bool {
+	return (m.config.IdleTimeout > 0 && now.Sub(data.LastSeen) > m.config.IdleTimeout) ||
+		(m.config.AbsoluteTimeout > 0 && now.Sub(data.Created) > m.config.AbsoluteTimeout)
+}
+
+// load returns the live session with the given ID, deleting it if expired
+func (m *sessionManager) load(id string, now time.Time) *SessionData {
+	data := m.store.Get(id)
+	if data == nil {
+		return nil
+	}
+
+	data.mu.Lock()
+	expired := m.expired(data, now)
+	if !expired {
+		// Stores that encode sessions need not save every request's time
+		if now.Sub(data.LastSeen) > time.Minute {
+			data.dirty = true
+		}
+		data.LastSeen = now
+	}
+	data.mu.Unlock()
+
+	// Deleted without holding data.mu, as stores lock themselves first
+	if expired {
+		m.store.Delete(id)
+		return nil
+	}
+	return data
+}
+
+// SessionManager provides cookie-based sessions. Cookies are signed with
+// secret; sessions expire after the configured idle and absolute timeouts.
+func SessionManager(secret string, config ...SessionConfig) Middleware {
+	cfg := DefaultSessionConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "session"
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemorySessionStore()
+	}
+
+	manager := &sessionManager{
+		store:  cfg.Store,
+		secret: []byte(secret),
+		config: cfg,
+	}
+	var lastCleanup time.Time
+	var cleanupMu sync.Mutex
+
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			now := time.Now()
+
+			// Get the session from a valid cookie
+			var id string
+			var data *SessionData
+			if cookie, err := ctx.Request.Cookie(cfg.CookieName); err == nil {
+				if verified, ok := manager.verify(cookie.Value); ok {
+					id = verified
+					data = manager.load(id, now)
+				}
+			}
+
+			// Create a new session, never reusing a client supplied ID
+			if data == nil {
+				id = newSessionID()
+				data = NewSessionData(now)
+				manager.store.Put(id, data)
+				manager.setCookie(ctx.ResponseWriter, id)
+			}
+
+			data.mu.Lock()
+			if ua, ip := ctx.Request.UserAgent(), ctx.ClientIP(); data.UserAgent != ua || data.IP != ip {
+				data.UserAgent, data.IP = ua, ip
+				data.dirty = true
+			}
+			data.mu.Unlock()
+
+			cleanupMu.Lock()
+			if now.Sub(lastCleanup) > time.Minute {
+				lastCleanup = now
+				go manager.store.DeleteExpired(func(d *SessionData) bool {
+					d.mu.RLock()
+					defer d.mu.RUnlock()
+					return manager.expired(d, now)
+				})
+			}
+			cleanupMu.Unlock()
+
+			session := &Session{id: id, data: data, manager: manager, w: ctx.ResponseWriter, server: ctx.server}
+
+			ctx.Set(KeySession, session)
+
+			err := next(ctx)
+
+			// Save changes for stores that keep a copy, e.g. EncodedSessionStore
+			data.mu.Lock()
+			dirty := data.dirty
+			data.dirty = false
+			data.mu.Unlock()
+			if dirty && !session.destroyed {
+				manager.store.Put(session.id, data)
+			}
+			return err
+		}
+	}
+}
+
+// remoteHost strips the port from a RemoteAddr
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// GetSession retrieves the session from context
+func GetSession(ctx *Context) *Session {
+	session, _ := ValueOf[*Session](ctx, KeySession)
+	return session
+}
@@ -0,0 +1,512 @@
+package nojs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionMigration changes the shape of session values from Version-1 to
+// Version, e.g. renaming a key or splitting one value in two. Sessions
+// written before any migration are version 1, so the first migration
+// has Version 2. Up and Down work on values decoded as generic JSON:
+// numbers are float64, objects map[string]interface{}.
+type SessionMigration struct {
+	Version int
+	// Up converts values written by Version-1
+	Up func(values map[string]interface{}) error
+	// Down converts values back to Version-1, for instances of the
+	// previous release still running during a rolling deploy
+	Down func(values map[string]interface{}) error
+}
+
+// SessionCodec encodes session data as versioned, encrypted payloads for
+// stores that outlive the process, such as EncodedSessionStore.
+//
+// Payloads written by an older release are upgraded with the Up
+// migrations when read. To roll out a migration without logging anyone
+// out, first deploy it with WriteVersion set to the previous version, so
+// the new instances read both shapes but write the old one that the
+// instances not yet replaced understand. Once every instance runs the new
+// release, deploy again without WriteVersion.
+type SessionCodec struct {
+	// Secrets encrypt payloads. The first secret encrypts new payloads;
+	// all are tried when reading. To rotate secrets across a rolling
+	// deploy, append the new secret, then move it first, then run
+	// ReencryptSessions and drop the old one.
+	Secrets    []string
+	Migrations []SessionMigration
+	// WriteVersion is the version payloads are written in, by default
+	// the latest
+	WriteVersion int
+	// Types decodes the values of these keys into the type of the given
+	// value instead of generic JSON, e.g. {"cart": Cart{}}. Values the
+	// framework keeps, such as pending flashes, are always decoded.
+	Types map[string]interface{}
+	// Newer is called for payloads written by a later release, with
+	// migrations this one does not know. Values are kept as they are by
+	// default; returning an error drops the session instead.
+	Newer func(version int, values map[string]interface{}) error
+
+	once    sync.Once
+	sealers []*sealer
+	latest  int
+	err     error
+}
+
+// ErrSessionPayload is returned for payloads no secret decrypts
+var ErrSessionPayload = errors.New("nojs: invalid or tampered session payload")
+
+// sessionPayload is the encrypted JSON of a session
+type sessionPayload struct {
+	Version   int                    `json:"v"`
+	UserID    string                 `json:"user,omitempty"`
+	UserAgent string                 `json:"ua,omitempty"`
+	IP        string                 `json:"ip,omitempty"`
+	Created   time.Time              `json:"created"`
+	LastSeen  time.Time              `json:"seen"`
+	Values    map[string]interface{} `json:"values"`
+}
+
+// init derives the keys and checks that the migrations are numbered 2,
+// 3, 4 and so on
+func (c *SessionCodec) init() error {
+	c.once.Do(func() {
+		if len(c.Secrets) == 0 {
+			c.err = errors.New("nojs: SessionCodec needs at least one secret")
+			return
+		}
+		for _, secret := range c.Secrets {
+			c.sealers = append(c.sealers, newSealer("nojs-session:"+secret))
+		}
+		for i, m := range c.Migrations {
+			if m.Version != i+2 || m.Up == nil {
+				c.err = fmt.Errorf("nojs: session migration %d must have version %d and an Up function", i, i+2)
+				return
+			}
+		}
+		c.latest = len(c.Migrations) + 1
+		if c.WriteVersion < 0 || c.WriteVersion > c.latest {
+			c.err = fmt.Errorf("nojs: session write version %d is not between 1 and %d", c.WriteVersion, c.latest)
+			return
+		}
+		for v := c.WriteVersion + 1; v <= c.latest && c.WriteVersion > 0; v++ {
+			if c.migration(v).Down == nil {
+				c.err = fmt.Errorf("nojs: session migration %d needs a Down function to write version %d", v, c.WriteVersion)
+				return
+			}
+		}
+	})
+	return c.err
+}
+
+// migration returns the migration to version v
+func (c *SessionCodec) migration(v int) SessionMigration {
+	return c.Migrations[v-2]
+}
+
+// writeVersion returns the version new payloads are written in
+func (c *SessionCodec) writeVersion() int {
+	if c.WriteVersion > 0 {
+		return c.WriteVersion
+	}
+	return c.latest
+}
+
+// Encode encrypts data as a payload in the write version. Data decoded
+// from a later release's payload keeps its version.
+func (c *SessionCodec) Encode(data *SessionData) ([]byte, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+
+	data.mu.RLock()
+	p := sessionPayload{
+		Version:   c.writeVersion(),
+		UserID:    data.UserID,
+		UserAgent: data.UserAgent,
+		IP:        data.IP,
+		Created:   data.Created,
+		LastSeen:  data.LastSeen,
+		Values:    data.Values,
+	}
+	if data.version > c.latest {
+		p.Version = data.version
+	}
+	plain, err := json.Marshal(p)
+	data.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Version < c.latest {
+		// Run the Down migrations on a generic copy of the values
+		p = sessionPayload{}
+		if err := json.Unmarshal(plain, &p); err != nil {
+			return nil, err
+		}
+		if p.Values == nil {
+			p.Values = make(map[string]interface{})
+		}
+		for v := c.latest; v > p.Version; v-- {
+			if err := c.migration(v).Down(p.Values); err != nil {
+				return nil, fmt.Errorf("nojs: session migration %d down: %w", v, err)
+			}
+		}
+		if plain, err = json.Marshal(p); err != nil {
+			return nil, err
+		}
+	}
+	return []byte(c.sealers[0].seal("session", plain)), nil
+}
+
+// Decode decrypts a payload with any of the secrets and upgrades it to
+// the latest version
+func (c *SessionCodec) Decode(payload []byte) (*SessionData, error) {
+	p, err := c.open(payload)
+	if err != nil {
+		return nil, err
+	}
+	if p.Values == nil {
+		p.Values = make(map[string]interface{})
+	}
+	if p.Version < 1 {
+		p.Version = 1
+	}
+
+	switch {
+	case p.Version > c.latest:
+		if c.Newer != nil {
+			if err := c.Newer(p.Version, p.Values); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		for v := p.Version + 1; v <= c.latest; v++ {
+			if err := c.migration(v).Up(p.Values); err != nil {
+				return nil, fmt.Errorf("nojs: session migration %d up: %w", v, err)
+			}
+		}
+	}
+
+	for _, types := range []map[string]interface{}{sessionTypes, c.Types} {
+		for key, typ := range types {
+			if err := decodeSessionValue(p.Values, key, typ); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &SessionData{
+		Values:    p.Values,
+		UserID:    p.UserID,
+		UserAgent: p.UserAgent,
+		IP:        p.IP,
+		Created:   p.Created,
+		LastSeen:  p.LastSeen,
+		version:   p.Version,
+	}, nil
+}
+
+// sessionTypes are the types of the session values kept by the framework
+var sessionTypes = map[string]interface{}{
+	flashKey: []FlashMessage{},
+}
+
+// decodeSessionValue converts the generic JSON value of key, if present,
+// into the type of typ
+func decodeSessionValue(values map[string]interface{}, key string, typ interface{}) error {
+	value, ok := values[key]
+	if !ok {
+		return nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	typed := reflect.New(reflect.TypeOf(typ))
+	if err := json.Unmarshal(raw, typed.Interface()); err != nil {
+		return fmt.Errorf("nojs: session value %q: %w", key, err)
+	}
+	values[key] = typed.Elem().Interface()
+	return nil
+}
+
+// PayloadVersion returns the version a payload was written in
+func (c *SessionCodec) PayloadVersion(payload []byte) (int, error) {
+	p, err := c.open(payload)
+	if err != nil {
+		return 0, err
+	}
+	return p.Version, nil
+}
+
+// Reencrypt encrypts a payload again under the first secret, leaving its
+// version and values untouched
+func (c *SessionCodec) Reencrypt(payload []byte) ([]byte, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	plain, err := c.decrypt(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(c.sealers[0].seal("session", plain)), nil
+}
+
+// open decrypts and parses a payload without migrating it
+func (c *SessionCodec) open(payload []byte) (*sessionPayload, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	plain, err := c.decrypt(payload)
+	if err != nil {
+		return nil, err
+	}
+	var p sessionPayload
+	if err := json.Unmarshal(plain, &p); err != nil {
+		return nil, fmt.Errorf("nojs: session payload: %w", err)
+	}
+	return &p, nil
+}
+
+func (c *SessionCodec) decrypt(payload []byte) ([]byte, error) {
+	for _, s := range c.sealers {
+		if plain, err := s.open("session", string(payload)); err == nil {
+			return plain, nil
+		}
+	}
+	return nil, ErrSessionPayload
+}
+
+// SessionBackend stores encoded session payloads by session ID
+type SessionBackend interface {
+	// Load returns the payload of a session, or nil if there is none
+	Load(id string) ([]byte, error)
+	Save(id string, payload []byte) error
+	Delete(id string) error
+	// Each calls fn for every session until it returns false. fn may
+	// save and delete sessions.
+	Each(fn func(id string, payload []byte) bool) error
+}
+
+// EncodedSessionStore is a SessionStore keeping sessions encoded by Codec
+// in Backend, so they survive restarts and deploys:
+//
+//	store := &nojs.EncodedSessionStore{
+//		Codec:   &nojs.SessionCodec{Secrets: secrets, Migrations: sessionMigrations},
+//		Backend: nojs.NewFileSessionBackend("sessions"),
+//	}
+//	server.Use(nojs.SessionManager(secret, nojs.SessionConfig{Store: store}))
+//
+// Every request decodes its own copy of the session, which is saved when
+// changed, so concurrent requests of one session do not see each other's
+// changes and the last to finish wins.
+type EncodedSessionStore struct {
+	Codec   *SessionCodec
+	Backend SessionBackend
+	// OnError is called with the errors of the backend and of sessions
+	// that cannot be decoded, which are treated as missing
+	OnError func(id string, err error)
+}
+
+func (s *EncodedSessionStore) report(id string, err error) {
+	if err != nil && s.OnError != nil {
+		s.OnError(id, err)
+	}
+}
+
+// Get returns the decoded session, or nil if there is none
+func (s *EncodedSessionStore) Get(id string) *SessionData {
+	payload, err := s.Backend.Load(id)
+	if err != nil || payload == nil {
+		s.report(id, err)
+		return nil
+	}
+	data, err := s.Codec.Decode(payload)
+	if err != nil {
+		s.report(id, err)
+		return nil
+	}
+	return data
+}
+
+// Put encodes and saves session data
+func (s *EncodedSessionStore) Put(id string, data *SessionData) {
+	payload, err := s.Codec.Encode(data)
+	if err == nil {
+		err = s.Backend.Save(id, payload)
+	}
+	s.report(id, err)
+}
+
+// Delete removes a session
+func (s *EncodedSessionStore) Delete(id string) {
+	s.report(id, s.Backend.Delete(id))
+}
+
+// UserSessions decodes every session to find those of a user
+func (s *EncodedSessionStore) UserSessions(userID string) map[string]*SessionData {
+	result := make(map[string]*SessionData)
+	if userID == "" {
+		return result
+	}
+	s.report("", s.Backend.Each(func(id string, payload []byte) bool {
+		if data, err := s.Codec.Decode(payload); err == nil && data.UserID == userID {
+			result[id] = data
+		}
+		return true
+	}))
+	return result
+}
+
+// DeleteExpired removes sessions for which expired returns true.
+// Sessions that cannot be decoded are left alone, as they may belong to
+// a release using a newer secret.
+func (s *EncodedSessionStore) DeleteExpired(expired func(*SessionData) bool) {
+	s.report("", s.Backend.Each(func(id string, payload []byte) bool {
+		if data, err := s.Codec.Decode(payload); err == nil && expired(data) {
+			s.Delete(id)
+		}
+		return true
+	}))
+}
+
+// FileSessionBackend keeps each session payload in a file of a directory
+type FileSessionBackend struct {
+	dir string
+}
+
+// NewFileSessionBackend stores sessions in dir, creating it if needed
+func NewFileSessionBackend(dir string) *FileSessionBackend {
+	return &FileSessionBackend{dir: dir}
+}
+
+func (b *FileSessionBackend) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return "", fmt.Errorf("nojs: invalid session ID %q", id)
+	}
+	return filepath.Join(b.dir, id), nil
+}
+
+// Load returns the payload of a session, or nil if there is none
+func (b *FileSessionBackend) Load(id string) ([]byte, error) {
+	path, err := b.path(id)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return payload, err
+}
+
+// Save writes a payload atomically
+func (b *FileSessionBackend) Save(id string, payload []byte) error {
+	path, err := b.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(b.dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(b.dir, ".session-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(payload); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Delete removes a session's file
+func (b *FileSessionBackend) Delete(id string) error {
+	path, err := b.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Each calls fn for every stored session until it returns false
+func (b *FileSessionBackend) Each(fn func(id string, payload []byte) bool) error {
+	entries, err := os.ReadDir(b.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		payload, err := os.ReadFile(filepath.Join(b.dir, entry.Name()))
+		if errors.Is(err, os.ErrNotExist) {
+			continue // Deleted meanwhile
+		}
+		if err != nil {
+			return err
+		}
+		if !fn(entry.Name(), payload) {
+			break
+		}
+	}
+	return nil
+}
+
+// ReencryptSessions encrypts every session in backend again under the
+// codec's first secret, so the other secrets can be retired. Payloads no
+// secret decrypts are left alone and counted as failed.
+func ReencryptSessions(backend SessionBackend, codec *SessionCodec) (done, failed int, err error) {
+	return rewriteSessions(backend, codec.Reencrypt)
+}
+
+// MigrateSessions decodes every session in backend and encodes it again
+// in the codec's write version under its first secret, e.g. to stop
+// depending on Up migrations that can then be deleted
+func MigrateSessions(backend SessionBackend, codec *SessionCodec) (done, failed int, err error) {
+	return rewriteSessions(backend, func(payload []byte) ([]byte, error) {
+		data, err := codec.Decode(payload)
+		if err != nil {
+			return nil, err
+		}
+		return codec.Encode(data)
+	})
+}
+
+func rewriteSessions(backend SessionBackend, rewrite func([]byte) ([]byte, error)) (done, failed int, err error) {
+	var saveErr error
+	err = backend.Each(func(id string, payload []byte) bool {
+		rewritten, err := rewrite(payload)
+		if err != nil {
+			failed++
+			return true
+		}
+		if saveErr = backend.Save(id, rewritten); saveErr != nil {
+			return false
+		}
+		done++
+		return true
+	})
+	if err == nil {
+		err = saveErr
+	}
+	return done, failed, err
+}
@@ -0,0 +1,181 @@
+package nojs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// SessionInfo describes one of a user's sessions without exposing its ID
+type SessionInfo struct {
+	// Handle identifies the session in forms; it cannot be used as a
+	// session ID
+	Handle   string
+	Current  bool
+	Device   string
+	IP       string
+	Created  time.Time
+	LastSeen time.Time
+}
+
+// sessionHandle derives a public handle from a session ID
+func sessionHandle(id string) string {
+	sum := sha256.Sum256([]byte("handle:" + id))
+	return hex.EncodeToString(sum[:12])
+}
+
+// UserSessions lists the sessions of the logged in user, most recently
+// active first
+func (s *Session) UserSessions() []SessionInfo {
+	userID := s.UserID()
+	if userID == "" {
+		return nil
+	}
+
+	var infos []SessionInfo
+	for id, data := range s.manager.store.UserSessions(userID) {
+		data.mu.RLock()
+		infos = append(infos, SessionInfo{
+			Handle:   sessionHandle(id),
+			Current:  id == s.id,
+			Device:   DescribeUserAgent(data.UserAgent),
+			IP:       data.IP,
+			Created:  data.Created,
+			LastSeen: data.LastSeen,
+		})
+		data.mu.RUnlock()
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].LastSeen.After(infos[j].LastSeen) })
+	return infos
+}
+
+// Revoke logs out another session of the same user by handle and
+// reports whether one was found
+func (s *Session) Revoke(handle string) bool {
+	userID := s.UserID()
+	if userID == "" {
+		return false
+	}
+
+	for id := range s.manager.store.UserSessions(userID) {
+		if id != s.id && sessionHandle(id) == handle {
+			s.manager.store.Delete(id)
+			return true
+		}
+	}
+	return false
+}
+
+// LogoutOthers logs out every other session of the user and returns how
+// many were ended
+func (s *Session) LogoutOthers() int {
+	userID := s.UserID()
+	if userID == "" {
+		return 0
+	}
+
+	count := 0
+	for id := range s.manager.store.UserSessions(userID) {
+		if id != s.id {
+			s.manager.store.Delete(id)
+			count++
+		}
+	}
+	return count
+}
+
+// DescribeUserAgent returns a short "Browser on OS" description
+func DescribeUserAgent(ua string) string {
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"}, {"Safari/", "Safari"}, {"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	os := ""
+	for _, o := range []struct{ token, name string }{
+		{"iPhone", "iOS"}, {"iPad", "iPadOS"}, {"Android", "Android"}, {"Windows", "Windows"},
+		{"Mac OS X", "macOS"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			os = o.name
+			break
+		}
+	}
+
+	if os == "" {
+		return browser
+	}
+	return browser + " on " + os
+}
+
+// AccountSessions returns a handler for an account security page listing
+// the user's sessions with forms to end one or all other sessions. It
+// requires SessionManager and a logged in user.
+func AccountSessions(css ...string) Handler {
+	return func(ctx *Context) error {
+		session := GetSession(ctx)
+		if session == nil || session.UserID() == "" {
+			return NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		}
+
+		if ctx.Method() == http.MethodPost {
+			if ctx.Form("all") != "" {
+				n := session.LogoutOthers()
+				ctx.SetFlash("success", Pluralize(n, "other session", "other sessions")+" logged out")
+			} else if session.Revoke(ctx.Form("session")) {
+				ctx.SetFlash("success", "Session logged out")
+			}
+			return ctx.Redirect(http.StatusSeeOther, ctx.Request.URL.Path)
+		}
+
+		action := ctx.Request.URL.Path
+		rows := []g.Node{}
+		for _, info := range session.UserSessions() {
+			var control g.Node = h.Strong(g.Text("This device"))
+			if !info.Current {
+				control = Form(FormConfig{Action: action, Class: "inline-form"},
+					h.Input(h.Type("hidden"), h.Name("session"), h.Value(info.Handle)),
+					SubmitButton("Log out", h.Class("button-small")),
+				)
+			}
+			rows = append(rows, h.Tr(
+				h.Td(g.Text(info.Device)),
+				h.Td(g.Text(info.IP)),
+				h.Td(g.Text(TimeSince(info.LastSeen))),
+				h.Td(g.Text(FormatDateTime(info.Created))),
+				h.Td(control),
+			))
+		}
+
+		flash := ctx.GetFlash("success")
+		return ctx.HTML(http.StatusOK, Page{
+			Title: "Active sessions",
+			CSS:   css,
+			Body: h.Main(h.Class("account-sessions"),
+				h.H1(g.Text("Active sessions")),
+				g.If(flash != "", Alert(flash, "success")),
+				h.Table(h.Class("table"),
+					h.THead(h.Tr(h.Th(g.Text("Device")), h.Th(g.Text("IP address")), h.Th(g.Text("Last active")), h.Th(g.Text("Signed in")), h.Th())),
+					h.TBody(rows...),
+				),
+				Form(FormConfig{Action: action},
+					h.Input(h.Type("hidden"), h.Name("all"), h.Value("1")),
+					SubmitButton("Log out all other sessions", h.Class("button-danger")),
+				),
+			),
+		}.Render())
+	}
+}
@@ -0,0 +1,155 @@
+package nojs
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// CopyField renders a read-only input holding value, focused on page
+// load so the user can copy it with one shortcut. It is the no-JS
+// stand-in for a "copy link" button. Its ids are derived from label, so
+// fields with different labels can share a page.
+func CopyField(label, value string, attrs ...g.Node) g.Node {
+	name := "copy"
+	if slug := Slug(label); slug != "" {
+		name += "-" + slug
+	}
+	hint := name + "-hint"
+	return h.Div(h.Class("copy-field"),
+		Input(label, name, "text", value, append([]g.Node{
+			h.ReadOnly(), h.AutoFocus(), h.Aria("describedby", hint),
+		}, attrs...)...),
+		h.P(h.ID(hint), h.Class("copy-hint"), g.Text("Press Ctrl+C (⌘C on Mac) to copy.")),
+	)
+}
+
+// ShareTarget is a share destination. URL holds the placeholders {url},
+// {title} and {text}, replaced by their percent-encoded values.
+type ShareTarget struct {
+	Label string
+	URL   string
+}
+
+// DefaultShareTargets are the destinations ShareLinks offers by default.
+// mailto and sms open the device's own apps, like the native share sheet.
+var DefaultShareTargets = []ShareTarget{
+	{Label: "Email", URL: "mailto:?subject={title}&body={text}%0A%0A{url}"},
+	{Label: "Text message", URL: "sms:?&body={text}%20{url}"},
+	{Label: "WhatsApp", URL: "https://wa.me/?text={text}%20{url}"},
+	{Label: "Telegram", URL: "https://t.me/share/url?url={url}&text={text}"},
+	{Label: "X", URL: "https://x.com/intent/post?url={url}&text={text}"},
+	{Label: "LinkedIn", URL: "https://www.linkedin.com/sharing/share-offsite/?url={url}"},
+}
+
+// ShareConfig describes what to share
+type ShareConfig struct {
+	URL   string
+	Title string
+	Text  string // Defaults to Title
+	// Targets defaults to DefaultShareTargets
+	Targets []ShareTarget
+	// QR adds a QR code of URL to SharePanel, for phones
+	QR bool
+}
+
+// ShareURL fills in a target's URL template
+func (t ShareTarget) ShareURL(config ShareConfig) string {
+	text := config.Text
+	if text == "" {
+		text = config.Title
+	}
+	return strings.NewReplacer(
+		"{url}", shareEscape(config.URL),
+		"{title}", shareEscape(config.Title),
+		"{text}", shareEscape(text),
+	).Replace(t.URL)
+}
+
+// shareEscape percent-encodes a value, spaces included, since mail and
+// messaging apps do not decode "+"
+func shareEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// ShareLinks renders a list of links sharing config.URL to each target
+func ShareLinks(config ShareConfig) g.Node {
+	targets := config.Targets
+	if targets == nil {
+		targets = DefaultShareTargets
+	}
+	var items []g.Node
+	for _, target := range targets {
+		link := []g.Node{h.Href(target.ShareURL(config)), g.Text(target.Label)}
+		if strings.HasPrefix(target.URL, "http") {
+			link = append(link, h.Target("_blank"), h.Rel("noopener noreferrer"))
+		}
+		items = append(items, h.Li(h.A(link...)))
+	}
+	return h.Ul(append([]g.Node{h.Class("share-links")}, items...)...)
+}
+
+// SharePanel combines a CopyField, ShareLinks and optionally a QR code
+// into one block
+func SharePanel(config ShareConfig) g.Node {
+	var qr g.Node
+	if config.QR {
+		qr = h.Div(h.Class("share-qr"), QRCode(config.URL, QRConfig{Level: QRMedium, Size: 200, Label: config.URL}))
+	}
+	return h.Div(h.Class("share-panel"),
+		CopyField("Link", config.URL),
+		ShareLinks(config),
+		qr,
+	)
+}
+
+// SharePage registers a page sharing a path of this site, linked to as
+// pattern?path=/articles/42. describe returns the title and text to share
+// for the path, or an error such as a 404 for unknown paths; they are not
+// taken from the query, so the page cannot be made to share arbitrary
+// text under this site's name. Only local paths are accepted, so the page
+// cannot be used to dress up foreign links either.
+//
+//	server.SharePage("/share", func(ctx *nojs.Context, path string) (string, string, error) {
+//		article, ok := articles[path]
+//		if !ok {
+//			return "", "", nojs.NewHTTPError(http.StatusNotFound, "Not Found")
+//		}
+//		return article.Title, article.Summary, nil
+//	}, layout)
+func (s *Server) SharePage(pattern string, describe func(ctx *Context, path string) (title, text string, err error), wrap func(g.Node) g.Node) {
+	s.GET(pattern, func(ctx *Context) error {
+		path := ctx.Query("path")
+		if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+			return NewHTTPError(http.StatusBadRequest, "Invalid path")
+		}
+		title, text, err := describe(ctx, path)
+		if err != nil {
+			return err
+		}
+		config := ShareConfig{
+			URL:   ctx.Scheme() + "://" + ctx.Request.Host + path,
+			Title: title,
+			Text:  text,
+			QR:    true,
+		}
+		if title == "" {
+			title = "Share"
+		}
+		return ctx.HTML(http.StatusOK, wrap(h.Section(h.Class("share"),
+			h.H1(g.Text(title)),
+			SharePanel(config),
+			h.P(h.A(h.Href(path), g.Text("Back"))),
+		)))
+	})
+}
+
+// ShareLink links to a SharePage for path
+func ShareLink(sharePage, path string) g.Node {
+	q := url.Values{"path": {path}}
+	return h.A(h.Href(sharePage+"?"+q.Encode()),
+		h.Class("share-link"), g.Text("Share"))
+}
@@ -0,0 +1,87 @@
+package nojs
+
+import (
+	"net/http"
+	"strings"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// IndexEntry is a destination listed on the site index page
+type IndexEntry struct {
+	Title       string
+	Path        string
+	Description string
+	Keywords    []string
+	// AccessKey is an optional single-character keyboard shortcut
+	AccessKey string
+}
+
+// AddIndex adds entries to the site index served by IndexPage
+func (s *Server) AddIndex(entries ...IndexEntry) {
+	s.index = append(s.index, entries...)
+}
+
+// Index returns the registered site index entries
+func (s *Server) Index() []IndexEntry {
+	return s.index
+}
+
+// IndexPage serves a "Go to…" page listing the site index, filtered with
+// a GET search form, as a no-JS alternative to a command palette
+func (s *Server) IndexPage(pattern string, css ...string) {
+	s.Route(pattern, func(ctx *Context) error {
+		query := strings.TrimSpace(ctx.Query("q"))
+		entries := FilterIndex(s.index, query)
+
+		items := []g.Node{h.Class("site-index")}
+		for _, entry := range entries {
+			items = append(items, h.Li(
+				h.A(h.Href(entry.Path),
+					g.If(entry.AccessKey != "", g.Attr("accesskey", entry.AccessKey)),
+					g.Text(entry.Title),
+				),
+				g.If(entry.AccessKey != "", h.Kbd(h.Class("accesskey"), g.Text(entry.AccessKey))),
+				g.If(entry.Description != "", h.P(g.Text(entry.Description))),
+			))
+		}
+
+		return ctx.HTML(http.StatusOK, Page{
+			Title: "Go to…",
+			CSS:   css,
+			Body: h.Main(h.Class("site-index-page"),
+				h.H1(g.Text("Go to…")),
+				Form(FormConfig{Action: pattern, Method: "GET", Class: "site-index-search"},
+					h.Input(h.Type("search"), h.Name("q"), h.Value(query), h.Placeholder("Search pages"),
+						h.AutoFocus(), h.Aria("label", "Search pages")),
+					SubmitButton("Search"),
+				),
+				g.If(len(entries) == 0, h.P(h.Class("empty"), g.Text("No matching pages."))),
+				h.Ul(items...),
+				h.P(h.Class("accesskey-hint"),
+					g.Text("Shortcuts are activated with your browser's access key modifier, e.g. Alt+Shift+key or Ctrl+Option+key."),
+				),
+			),
+		}.Render())
+	})
+}
+
+// FilterIndex returns the entries whose title, path, description or
+// keywords contain every word of query, case-insensitively
+func FilterIndex(entries []IndexEntry, query string) []IndexEntry {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return entries
+	}
+
+	return Filter(entries, func(entry IndexEntry) bool {
+		haystack := strings.ToLower(strings.Join(append([]string{entry.Title, entry.Path, entry.Description}, entry.Keywords...), " "))
+		for _, word := range words {
+			if !strings.Contains(haystack, word) {
+				return false
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,223 @@
+package nojs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+
+	g "maragu.dev/gomponents"
+)
+
+// SnapshotConfig configures the Snapshot middleware
+type SnapshotConfig struct {
+	// Key identifies a client's view of a page. It defaults to the session
+	// ID (or remote host without sessions) plus the request URI.
+	Key func(ctx *Context) string
+
+	// IdleAfter is the number of unchanged renders after which
+	// SnapshotRefresh switches to IdleRefresh
+	IdleAfter int
+
+	// IdleRefresh is the refresh interval in seconds for idle pages
+	IdleRefresh int
+
+	// MaxEntries bounds the number of remembered snapshots
+	MaxEntries int
+}
+
+// DefaultSnapshotConfig returns sensible defaults
+func DefaultSnapshotConfig() SnapshotConfig {
+	return SnapshotConfig{
+		IdleAfter:   3,
+		IdleRefresh: 30,
+		MaxEntries:  10000,
+	}
+}
+
+// snapshot is the last page body hash sent to a client
+type snapshot struct {
+	hash      string
+	unchanged int
+}
+
+// snapshotState is the per-request view of the Snapshot middleware
+type snapshotState struct {
+	config SnapshotConfig
+	idle   bool
+	tag    string
+}
+
+// Snapshot middleware remembers a hash of the last page rendered for each
+// client and route. Unchanged pages are answered with 304 Not Modified when
+// the browser revalidates, so AutoRefresh reloads cost no transfer and
+// cause no flicker. Pages that stay unchanged for IdleAfter renders are
+// sent once more with the longer IdleRefresh interval (see
+// SnapshotRefresh), and the browser keeps that copy until content changes.
+func Snapshot(config ...SnapshotConfig) Middleware {
+	cfg := DefaultSnapshotConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Key == nil {
+		cfg.Key = snapshotKey
+	}
+
+	var mu sync.Mutex
+	snapshots := make(map[string]*snapshot)
+
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			if ctx.Request.Method != http.MethodGet {
+				return next(ctx)
+			}
+
+			key := cfg.Key(ctx)
+			mu.Lock()
+			last := snapshots[key]
+			state := &snapshotState{config: cfg, idle: last != nil && last.unchanged >= cfg.IdleAfter}
+			mu.Unlock()
+
+			w := ctx.ResponseWriter
+			rec := &snapshotRecorder{ResponseWriter: w, status: http.StatusOK}
+			ctx.ResponseWriter = rec
+			ctx.snapshot = state
+			err := next(ctx)
+			ctx.ResponseWriter = w
+			ctx.snapshot = nil
+
+			if rec.streaming {
+				return err
+			}
+			if err != nil || rec.status != http.StatusOK {
+				if rec.buf.Len() > 0 || rec.status != http.StatusOK {
+					w.WriteHeader(rec.status)
+					w.Write(rec.buf.Bytes())
+				}
+				return err
+			}
+
+			// The refresh tag is excluded so changing the interval does not
+			// count as a content change
+			sum := sha256.Sum256(bytes.Replace(rec.buf.Bytes(), []byte(state.tag), nil, 1))
+			hash := hex.EncodeToString(sum[:16])
+			etag := `"` + hash + `"`
+
+			mu.Lock()
+			if last == nil || last.hash != hash {
+				if len(snapshots) >= cfg.MaxEntries {
+					snapshots = make(map[string]*snapshot)
+				}
+				last = &snapshot{hash: hash}
+				snapshots[key] = last
+			} else {
+				last.unchanged++
+			}
+			// Send the idle interval once, then keep answering 304
+			slowDown := !state.idle && last.unchanged == cfg.IdleAfter && state.tag != ""
+			mu.Unlock()
+
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Cache-Control", "no-cache")
+			if !slowDown && ifNoneMatch(ctx.Request, etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+
+			body := rec.buf.Bytes()
+			if slowDown {
+				idle := renderString(AutoRefresh(cfg.IdleRefresh))
+				body = bytes.Replace(body, []byte(state.tag), []byte(idle), 1)
+			}
+			w.WriteHeader(rec.status)
+			_, err = w.Write(body)
+			return err
+		}
+	}
+}
+
+// SnapshotRefresh renders an AutoRefresh tag with the given interval, or
+// with SnapshotConfig.IdleRefresh once the page has been unchanged for a
+// while. Outside the Snapshot middleware it is AutoRefresh(seconds).
+func (c *Context) SnapshotRefresh(seconds int) g.Node {
+	if c.snapshot == nil {
+		return AutoRefresh(seconds)
+	}
+	if c.snapshot.idle {
+		seconds = c.snapshot.config.IdleRefresh
+	}
+	c.snapshot.tag = renderString(AutoRefresh(seconds))
+	return g.Raw(c.snapshot.tag)
+}
+
+// snapshotKey identifies the client by session, or remote host without
+// sessions, and the page by its request URI
+func snapshotKey(ctx *Context) string {
+	client := ctx.ClientIP()
+	if session := GetSession(ctx); session != nil {
+		client = session.ID()
+	}
+	return client + " " + ctx.Request.URL.RequestURI()
+}
+
+// ifNoneMatch reports whether the request's If-None-Match header lists etag
+func ifNoneMatch(r *http.Request, etag string) bool {
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// renderString renders a node to a string
+func renderString(node g.Node) string {
+	var b strings.Builder
+	node.Render(&b)
+	return b.String()
+}
+
+// snapshotRecorder buffers a response so it can be hashed. Streaming
+// responses are passed through untouched.
+type snapshotRecorder struct {
+	http.ResponseWriter
+	status    int
+	buf       bytes.Buffer
+	streaming bool
+}
+
+func (r *snapshotRecorder) WriteHeader(status int) {
+	if informational(status) {
+		r.ResponseWriter.WriteHeader(status)
+		return
+	}
+	r.status = status
+}
+
+func (r *snapshotRecorder) Write(b []byte) (int, error) {
+	if r.streaming {
+		return r.ResponseWriter.Write(b)
+	}
+	return r.buf.Write(b)
+}
+
+// Flush switches to pass-through: a flushed response is a stream
+func (r *snapshotRecorder) Flush() {
+	if !r.streaming {
+		r.streaming = true
+		r.ResponseWriter.WriteHeader(r.status)
+		r.ResponseWriter.Write(r.buf.Bytes())
+		r.buf.Reset()
+	}
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (r *snapshotRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,318 @@
+package nojs
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// StagingTokenField is the form field carrying a staged upload token
+const StagingTokenField = "staged"
+
+// ErrStagedNotFound is returned for unknown, expired or malformed tokens
+// and for uploads staged by another client
+var ErrStagedNotFound = errors.New("staged upload not found or expired")
+
+// Staging implements a two-phase upload: Stage stores a posted file under
+// a random token, the next page previews it with Preview and offers
+// StagedActions, and Commit moves it to permanent storage or Discard
+// deletes it. Uncommitted files expire after TTL. Tokens only work for
+// the client that staged the file, identified by its session or, without
+// the session middleware, its CSRF cookie.
+type Staging struct {
+	Dir     string
+	TTL     time.Duration
+	MaxSize int64
+}
+
+// StagedFile describes a staged upload
+type StagedFile struct {
+	Token       string
+	Name        string
+	ContentType string
+	Size        int64
+	Created     time.Time
+	// Owner is a hash of the secret identifying the client that staged
+	// the file, empty when it had neither a session nor a CSRF token
+	Owner string
+}
+
+// stagingOwnerKey is the session key of the secret identifying the
+// client to Staging
+const stagingOwnerKey = "nojs_staging_owner"
+
+// stagingOwner returns a hash of the secret identifying the client,
+// creating the secret in its session on first use
+func stagingOwner(ctx *Context) string {
+	secret := ctx.CSRFToken()
+	if session := GetSession(ctx); session != nil {
+		s, ok := session.Get(stagingOwnerKey).(string)
+		if !ok || s == "" {
+			s = newSessionID()
+			session.Set(stagingOwnerKey, s)
+		}
+		secret = s
+	}
+	if secret == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewStaging creates a staging area in dir keeping files for an hour
+func NewStaging(dir string) *Staging {
+	return &Staging{Dir: dir, TTL: time.Hour, MaxSize: 32 << 20}
+}
+
+// Stage stores the file posted in field and returns its staged entry
+func (s *Staging) Stage(ctx *Context, field string) (*StagedFile, error) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.ResponseWriter, ctx.Request.Body, s.MaxSize+1<<20)
+	file, header, err := ctx.Request.FormFile(field)
+	if err != nil {
+		return nil, WrapHTTPError(http.StatusBadRequest, "No file uploaded", err)
+	}
+	defer file.Close()
+	if header.Size > s.MaxSize {
+		return nil, NewHTTPError(http.StatusRequestEntityTooLarge, "File too large")
+	}
+
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	staged := &StagedFile{
+		Token:       hex.EncodeToString(b),
+		Name:        filepath.Base(header.Filename),
+		ContentType: header.Header.Get("Content-Type"),
+		Created:     time.Now(),
+		Owner:       stagingOwner(ctx),
+	}
+
+	out, err := os.OpenFile(s.path(staged.Token), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	staged.Size, err = io.Copy(out, file)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(s.path(staged.Token))
+		return nil, err
+	}
+
+	meta, _ := json.Marshal(staged)
+	if err := os.WriteFile(s.path(staged.Token)+".json", meta, 0o600); err != nil {
+		os.Remove(s.path(staged.Token))
+		return nil, err
+	}
+	return staged, nil
+}
+
+// Get returns an upload the client staged that has not expired
+func (s *Staging) Get(ctx *Context, token string) (*StagedFile, error) {
+	staged, err := s.load(token)
+	if err != nil {
+		return nil, err
+	}
+	owner := stagingOwner(ctx)
+	if subtle.ConstantTimeCompare([]byte(staged.Owner), []byte(owner)) != 1 {
+		return nil, ErrStagedNotFound
+	}
+	return staged, nil
+}
+
+// load returns a staged upload that has not expired, whoever staged it
+func (s *Staging) load(token string) (*StagedFile, error) {
+	if len(token) != 32 || strings.Trim(token, "0123456789abcdef") != "" {
+		return nil, ErrStagedNotFound
+	}
+	meta, err := os.ReadFile(s.path(token) + ".json")
+	if err != nil {
+		return nil, ErrStagedNotFound
+	}
+	var staged StagedFile
+	if err := json.Unmarshal(meta, &staged); err != nil {
+		return nil, ErrStagedNotFound
+	}
+	if time.Since(staged.Created) > s.TTL {
+		s.remove(token)
+		return nil, ErrStagedNotFound
+	}
+	return &staged, nil
+}
+
+// Open opens the content of a file the client staged
+func (s *Staging) Open(ctx *Context, token string) (*os.File, *StagedFile, error) {
+	staged, err := s.Get(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(s.path(token))
+	return f, staged, err
+}
+
+// Commit moves a file the client staged to dest, creating its directory
+func (s *Staging) Commit(ctx *Context, token, dest string) (*StagedFile, error) {
+	staged, err := s.Get(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(s.path(token), dest); err != nil {
+		// Different file systems: copy instead
+		if err := copyFile(s.path(token), dest); err != nil {
+			return nil, err
+		}
+	}
+	s.remove(token)
+	return staged, nil
+}
+
+// Discard deletes an upload the client staged
+func (s *Staging) Discard(ctx *Context, token string) error {
+	if _, err := s.Get(ctx, token); err != nil {
+		return err
+	}
+	return s.remove(token)
+}
+
+// remove deletes a staged upload and its metadata
+func (s *Staging) remove(token string) error {
+	os.Remove(s.path(token) + ".json")
+	err := os.Remove(s.path(token))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Cleanup deletes expired uploads; call it periodically or from OnStart
+func (s *Staging) Cleanup() {
+	entries, _ := os.ReadDir(s.Dir)
+	for _, entry := range entries {
+		if token, ok := strings.CutSuffix(entry.Name(), ".json"); ok {
+			s.load(token)
+		}
+	}
+}
+
+// stagingInline lists the sniffed types Serve shows in the browser;
+// anything else, including HTML and SVG, is sent as a download
+var stagingInline = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "image/bmp", "text/plain; charset=utf-8"}
+
+// Serve sends a file the client staged, e.g. as the source of a preview
+// thumbnail. The type is sniffed from the content rather than taken from
+// the upload, and only raster images and plain text are shown inline.
+func (s *Staging) Serve(ctx *Context, token string) error {
+	f, staged, err := s.Open(ctx, token)
+	if err != nil {
+		return WrapHTTPError(http.StatusNotFound, "Not Found", err)
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	contentType, disposition := http.DetectContentType(head[:n]), "inline"
+	if !Contains(stagingInline, contentType) {
+		contentType, disposition = "application/octet-stream", "attachment"
+	}
+
+	header := ctx.ResponseWriter.Header()
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Disposition", contentDisposition(disposition, staged.Name))
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Security-Policy", "sandbox")
+	ctx.written = true
+	http.ServeContent(ctx.ResponseWriter, ctx.Request, staged.Name, staged.Created, f)
+	return nil
+}
+
+func (s *Staging) path(token string) string {
+	return filepath.Join(s.Dir, token)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Preview renders a preview of a staged upload: a thumbnail for
+// images (served from imageURL, e.g. a route calling Staging.Serve), a
+// sample of the first rows for CSV files and the name and size otherwise
+func (s *Staging) Preview(staged *StagedFile, imageURL string) g.Node {
+	info := h.P(h.Class("staged-info"), g.Textf("%s (%s)", staged.Name, FormatBytes(staged.Size)))
+
+	switch {
+	case strings.HasPrefix(staged.ContentType, "image/") && staged.ContentType != "image/svg+xml":
+		return h.Figure(h.Class("staged-preview"),
+			h.Img(h.Src(imageURL), h.Alt(staged.Name), h.Style("max-width: 320px; max-height: 240px")),
+			h.FigCaption(info),
+		)
+	case staged.ContentType == "text/csv" || strings.HasSuffix(strings.ToLower(staged.Name), ".csv"):
+		f, err := os.Open(s.path(staged.Token))
+		if err != nil {
+			return info
+		}
+		defer f.Close()
+		r := csv.NewReader(f)
+		r.FieldsPerRecord = -1
+		var rows [][]string
+		for len(rows) < 6 {
+			record, err := r.Read()
+			if err != nil {
+				break
+			}
+			rows = append(rows, record)
+		}
+		if len(rows) == 0 {
+			return info
+		}
+		return h.Div(h.Class("staged-preview"), info, Table(rows[0], rows[1:]))
+	}
+	return h.Div(h.Class("staged-preview"), info)
+}
+
+// StagedActions renders confirm and discard forms for a staged upload
+func StagedActions(staged *StagedFile, commitURL, discardURL string) g.Node {
+	token := h.Input(h.Type("hidden"), h.Name(StagingTokenField), h.Value(staged.Token))
+	return h.Div(h.Class("staged-actions"),
+		Form(FormConfig{Action: commitURL, Method: "POST"}, token, SubmitButton("Confirm upload")),
+		Form(FormConfig{Action: discardURL, Method: "POST"}, token, Button("Discard", h.Type("submit"), h.Class("btn btn-secondary"))),
+	)
+}
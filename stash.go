@@ -0,0 +1,142 @@
+package nojs
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StashCookie identifies the stash owner when no session is in use
+const StashCookie = "nojs_stash"
+
+// stashStore holds temporary values for all clients, by owner and key
+type stashStore struct {
+	mu      sync.Mutex
+	entries map[string]map[string]stashEntry
+	sweep   time.Time
+}
+
+type stashEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// move hands the entries of owner from to owner to, e.g. when a session
+// is regenerated
+func (st *stashStore) move(from, to string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if entries, ok := st.entries[from]; ok {
+		st.entries[to] = entries
+		delete(st.entries, from)
+	}
+}
+
+// Stash is a client's temporary server-side store for multi-request flows
+// such as wizard progress, pending uploads or preview drafts. Unlike
+// session data every entry expires on its own.
+type Stash struct {
+	store *stashStore
+	owner string
+}
+
+// stashStore returns the stash store of the root server
+func (s *Server) stashStore() *stashStore {
+	root := s.root()
+	root.stashOnce.Do(func() {
+		root.stash = &stashStore{entries: make(map[string]map[string]stashEntry)}
+	})
+	return root.stash
+}
+
+// Stash returns the stash of the client, keyed by its session or, without
+// the session middleware, by a StashCookie set on first use. Entries
+// follow the session when it is regenerated, e.g. on login.
+func (c *Context) Stash() *Stash {
+	owner := ""
+	if session := GetSession(c); session != nil {
+		owner = session.ID()
+	} else if cookie, err := c.Request.Cookie(StashCookie); err == nil && len(cookie.Value) == 64 {
+		owner = cookie.Value
+	} else {
+		owner = newSessionID()
+		http.SetCookie(c.ResponseWriter, &http.Cookie{
+			Name:     StashCookie,
+			Value:    owner,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   c.Request.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+		// Later calls in this request see the new owner
+		c.Request.AddCookie(&http.Cookie{Name: StashCookie, Value: owner})
+	}
+	return &Stash{store: c.server.stashStore(), owner: owner}
+}
+
+// Put stores value under key for ttl
+func (s *Stash) Put(key string, value interface{}, ttl time.Duration) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	now := time.Now()
+	if now.After(s.store.sweep) {
+		for owner, entries := range s.store.entries {
+			for k, e := range entries {
+				if now.After(e.expires) {
+					delete(entries, k)
+				}
+			}
+			if len(entries) == 0 {
+				delete(s.store.entries, owner)
+			}
+		}
+		s.store.sweep = now.Add(time.Minute)
+	}
+	entries := s.store.entries[s.owner]
+	if entries == nil {
+		entries = make(map[string]stashEntry)
+		s.store.entries[s.owner] = entries
+	}
+	entries[key] = stashEntry{value: value, expires: now.Add(ttl)}
+}
+
+// Get returns the value stored under key unless it expired
+func (s *Stash) Get(key string) (interface{}, bool) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	return s.get(key)
+}
+
+// get looks key up; the caller must hold the store's lock
+func (s *Stash) get(key string) (interface{}, bool) {
+	entries := s.store.entries[s.owner]
+	e, ok := entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Take returns the value stored under key and removes it, so of two
+// concurrent requests only one gets it
+func (s *Stash) Take(key string) (interface{}, bool) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	value, ok := s.get(key)
+	if ok {
+		delete(s.store.entries[s.owner], key)
+	}
+	return value, ok
+}
+
+// Delete removes key
+func (s *Stash) Delete(key string) {
+	s.store.mu.Lock()
+	delete(s.store.entries[s.owner], key)
+	s.store.mu.Unlock()
+}
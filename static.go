@@ -0,0 +1,159 @@
+package nojs
+
+import (
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// StaticOptions configures static file serving
+type StaticOptions struct {
+	// Precompress writes a .gz variant next to every compressible file
+	// at startup when it is missing or older than the original
+	Precompress bool
+	// ImageFormats serves JPEG and PNG images as these modern formats,
+	// e.g. DefaultImageFormats, to clients accepting them. Variants are
+	// converted on first request and kept in ImageCacheDir.
+	ImageFormats []string
+	// ImageCacheDir holds converted images, in a subdirectory per static
+	// directory, defaulting to a directory under os.TempDir
+	ImageCacheDir string
+	// ImageConverter converts images, defaulting to ExecImageConverter
+	ImageConverter ImageConverter
+}
+
+// precompressed lists the encodings looked for next to static files, in
+// order of preference
+var precompressed = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// compressibleExts lists the file types worth precompressing
+var compressibleExts = map[string]bool{
+	".css": true, ".html": true, ".js": true, ".json": true,
+	".svg": true, ".txt": true, ".xml": true, ".map": true,
+}
+
+// staticHandler serves files from dir, preferring pre-compressed
+// variants (file.css.br, file.css.gz) the client accepts
+type staticHandler struct {
+	dir    string
+	files  http.Handler
+	images *imageVariants
+}
+
+func (sh *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + r.URL.Path)
+	accept := r.Header.Get("Accept-Encoding")
+
+	if sh.images != nil && sh.images.serve(w, r, name) {
+		return
+	}
+
+	if !strings.HasSuffix(r.URL.Path, "/") && accept != "" {
+		for _, p := range precompressed {
+			if !acceptsEncoding(accept, p.encoding) {
+				continue
+			}
+			if sh.serveVariant(w, r, name, p.encoding, p.ext) {
+				return
+			}
+		}
+	}
+
+	sh.files.ServeHTTP(w, r)
+}
+
+// serveVariant serves name+ext with the given Content-Encoding if that
+// file exists, reporting whether it did
+func (sh *staticHandler) serveVariant(w http.ResponseWriter, r *http.Request, name, encoding, ext string) bool {
+	f, err := http.Dir(sh.dir).Open(name + ext)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	ctype := mime.TypeByExtension(path.Ext(name))
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("Content-Encoding", encoding)
+	w.Header().Add("Vary", "Accept-Encoding")
+	http.ServeContent(w, r, name, info.ModTime(), f)
+	return true
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows the
+// given encoding with a non-zero quality
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		token, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(token), encoding) {
+			continue
+		}
+		params = strings.ReplaceAll(params, " ", "")
+		return params != "q=0" && params != "q=0.0" && params != "q=0.00" && params != "q=0.000"
+	}
+	return false
+}
+
+// precompressDir writes gzip variants of compressible files under dir
+func precompressDir(dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !compressibleExts[filepath.Ext(p)] {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if gz, err := os.Stat(p + ".gz"); err == nil && !gz.ModTime().Before(info.ModTime()) {
+			return nil
+		}
+
+		return gzipFile(p, p+".gz")
+	})
+}
+
+// gzipFile compresses src into dst at the best compression level
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	zw, _ := gzip.NewWriterLevel(out, gzip.BestCompression)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,165 @@
+package nojs
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Keys used by built-in middleware for ctx.Set and ctx.Get
+const (
+	KeySession   = "nojs.session"    // *Session, from SessionManager
+	KeyUser      = "nojs.user"       // string user ID, from BasicAuth or your own auth middleware
+	KeyRequestID = "nojs.request_id" // string, see RequestID
+	KeyLocale    = "nojs.locale"     // string language tag, from Locale
+	keyCSRF      = "nojs.csrf"
+)
+
+// contextValues holds the request-scoped values. It lives in the request
+// context, so apps mounted with Server.Mount, which get a Context of
+// their own, see the values set by the outer server's middleware.
+type contextValues struct {
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+type valuesKey struct{}
+
+// Set stores a request-scoped value, typically from middleware for the
+// handler. Use namespaced keys, e.g. "myapp.tenant".
+func (c *Context) Set(key string, value interface{}) {
+	if c.values == nil {
+		if v, ok := c.Request.Context().Value(valuesKey{}).(*contextValues); ok {
+			c.values = v
+		} else {
+			c.values = &contextValues{values: make(map[string]interface{})}
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), valuesKey{}, c.values))
+		}
+	}
+	c.values.mu.Lock()
+	defer c.values.mu.Unlock()
+	c.values.values[key] = value
+}
+
+// Get returns a request-scoped value stored with Set
+func (c *Context) Get(key string) (interface{}, bool) {
+	if c.values == nil {
+		v, ok := c.Request.Context().Value(valuesKey{}).(*contextValues)
+		if !ok {
+			return nil, false
+		}
+		c.values = v
+	}
+	c.values.mu.RLock()
+	defer c.values.mu.RUnlock()
+	value, ok := c.values.values[key]
+	return value, ok
+}
+
+// ValueOf returns the value stored under key if it has type T
+//
+//	tenant, ok := nojs.ValueOf[*Tenant](ctx, "myapp.tenant")
+func ValueOf[T any](c *Context, key string) (T, bool) {
+	value, _ := c.Get(key)
+	typed, ok := value.(T)
+	return typed, ok
+}
+
+// GetString returns a string value stored with Set, or ""
+func (c *Context) GetString(key string) string {
+	s, _ := ValueOf[string](c, key)
+	return s
+}
+
+// GetInt returns an int value stored with Set, or 0
+func (c *Context) GetInt(key string) int {
+	n, _ := ValueOf[int](c, key)
+	return n
+}
+
+// GetBool returns a bool value stored with Set, or false
+func (c *Context) GetBool(key string) bool {
+	b, _ := ValueOf[bool](c, key)
+	return b
+}
+
+// UserID returns the authenticated user stored under KeyUser, falling
+// back to the user logged in to the session, or ""
+func (c *Context) UserID() string {
+	if id := c.GetString(KeyUser); id != "" {
+		return id
+	}
+	if session := GetSession(c); session != nil {
+		return session.UserID()
+	}
+	return ""
+}
+
+// Locale picks the response language from the Accept-Language header
+// among supported tags (the first is the default) and stores it under
+// KeyLocale, where ctx.Locale finds it. A lang query parameter naming a
+// supported tag overrides the header, for language switcher links.
+func Locale(supported ...string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			ctx.ResponseWriter.Header().Add("Vary", "Accept-Language")
+			ctx.Set(KeyLocale, negotiateLocale(ctx.Query("lang"), ctx.Request.Header.Get("Accept-Language"), supported))
+			return next(ctx)
+		}
+	}
+}
+
+// Locale returns the language chosen by the Locale middleware, or ""
+func (c *Context) Locale() string {
+	return c.GetString(KeyLocale)
+}
+
+// negotiateLocale matches Accept-Language entries by q-value, first
+// exactly and then by primary language ("en-GB" matches "en")
+func negotiateLocale(override, header string, supported []string) string {
+	if len(supported) == 0 {
+		return ""
+	}
+	for _, tag := range supported {
+		if override != "" && strings.EqualFold(tag, override) {
+			return tag
+		}
+	}
+
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var accepted []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if tag != "" && q > 0 {
+			accepted = append(accepted, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].q > accepted[j].q })
+
+	for _, a := range accepted {
+		for _, tag := range supported {
+			if strings.EqualFold(tag, a.tag) {
+				return tag
+			}
+		}
+		primary, _, _ := strings.Cut(a.tag, "-")
+		for _, tag := range supported {
+			p, _, _ := strings.Cut(tag, "-")
+			if strings.EqualFold(p, primary) {
+				return tag
+			}
+		}
+	}
+	return supported[0]
+}
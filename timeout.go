@@ -0,0 +1,238 @@
+package nojs
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// Timeout middleware gives handlers d to start answering. After that the
+// request context is cancelled and page is sent with 503 Service
+// Unavailable; a nil page sends a default "this took too long" page with
+// a retry link. Responses that have started writing by then, such as
+// streams, are left to finish.
+//
+// Like http.TimeoutHandler, the handler runs on its own goroutine, with
+// its own copy of ctx, and may still be running when the timeout page is
+// sent. Its writes are discarded and ctx.Stream fails from then on; it
+// should return once ctx.Request.Context() is done. Panics after the
+// timeout are logged.
+func Timeout(d time.Duration, page g.Node) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			reqCtx, cancel := context.WithCancel(ctx.Request.Context())
+			defer cancel()
+
+			w, r := ctx.ResponseWriter, ctx.Request
+			tw := &timeoutWriter{w: w, header: w.Header().Clone()}
+			inner := *ctx
+			inner.ResponseWriter, inner.Request = tw, r.WithContext(reqCtx)
+
+			done := make(chan error, 1)
+			panicked := make(chan interface{}, 1)
+			go func() {
+				var err error
+				defer func() {
+					p := recover()
+					// Counted out here, where the stream is written, as
+					// the request may have timed out and returned
+					if inner.streamCounted {
+						inner.streamCounted = false
+						inner.server.streamEnded()
+					}
+					switch {
+					case !tw.finish():
+						if p != nil {
+							log.Printf("nojs: panic after timeout in %s %s: %v\n%s", r.Method, r.URL.Path, p, debug.Stack())
+						}
+					case p != nil:
+						panicked <- p
+					default:
+						done <- err
+					}
+				}()
+				err = next(&inner)
+			}()
+
+			// The handler is done with its copy once it reports back
+			restore := func() {
+				inner.ResponseWriter, inner.Request = w, r
+				*ctx = inner
+			}
+			timer := time.NewTimer(d)
+			defer timer.Stop()
+			for {
+				select {
+				case err := <-done:
+					restore()
+					return err
+				case p := <-panicked:
+					restore()
+					panic(p)
+				case <-timer.C:
+					if !tw.timeout() {
+						continue // Already answering, e.g. a stream
+					}
+					cancel()
+					node := page
+					if node == nil {
+						node = timeoutPage(r)
+					}
+					w.Header().Set("Content-Type", "text/html; charset=utf-8")
+					w.Header().Set("Retry-After", "5")
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return node.Render(w)
+				}
+			}
+		}
+	}
+}
+
+// timeoutPage is the default page sent by Timeout
+func timeoutPage(r *http.Request) g.Node {
+	return Page{
+		Title: "This took too long",
+		Body: h.Main(h.Style("max-width: 32rem; margin: 4rem auto; padding: 0 1rem; font-family: system-ui, sans-serif; text-align: center"),
+			h.H1(g.Text("This took too long")),
+			h.P(g.Text("The server could not answer in time. It may be busy; please try again in a moment.")),
+			g.If(r.Method == http.MethodGet, h.P(h.A(h.Href(r.URL.RequestURI()), g.Text("Try again")))),
+		),
+	}.Render()
+}
+
+// timeoutWriter holds back headers until the handler writes, and
+// discards writes once the timeout page has been sent
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu        sync.Mutex
+	started   bool
+	streaming bool // ctx.Stream was called
+	finished  bool // The handler returned or panicked
+	timedOut  bool
+}
+
+// timeout marks the response as timed out unless it has started or the
+// handler is done, reporting whether it did
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.started || tw.streaming || tw.finished {
+		return false
+	}
+	tw.timedOut = true
+	return true
+}
+
+// finish marks the handler as done unless the response has timed out,
+// reporting whether it did
+func (tw *timeoutWriter) finish() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return false
+	}
+	tw.finished = true
+	return true
+}
+
+// stream marks the response as a stream, which never times out,
+// reporting false if it already has
+func (tw *timeoutWriter) stream() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return false
+	}
+	tw.streaming = true
+	return true
+}
+
+// startStream tells a Timeout middleware wrapping w that the handler is
+// streaming, reporting false if the request has already timed out
+func startStream(w http.ResponseWriter) bool {
+	for {
+		if tw, ok := w.(*timeoutWriter); ok {
+			return tw.stream()
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return true
+		}
+		w = u.Unwrap()
+	}
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// start sends the headers the first time the handler writes
+func (tw *timeoutWriter) start() bool {
+	if tw.timedOut {
+		return false
+	}
+	if !tw.started {
+		tw.started = true
+		tw.copyHeader()
+	}
+	return true
+}
+
+// copyHeader replaces the underlying writer's headers with the held back
+// ones
+func (tw *timeoutWriter) copyHeader() {
+	dst := tw.w.Header()
+	for key := range dst {
+		delete(dst, key)
+	}
+	for key, values := range tw.header {
+		dst[key] = values
+	}
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if informational(status) {
+		// Sent without starting the response, which may still time out
+		if !tw.timedOut {
+			tw.copyHeader()
+			tw.w.WriteHeader(status)
+		}
+		return
+	}
+	if tw.start() {
+		tw.w.WriteHeader(status)
+	}
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.start() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tw.w.Write(b)
+}
+
+// Flush implements http.Flusher for streams
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.start() {
+		http.NewResponseController(tw.w).Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
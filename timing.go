@@ -0,0 +1,61 @@
+package nojs
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serverTimings collects the Server-Timing entries of a response until
+// its headers are sent
+type serverTimings struct {
+	mu      sync.Mutex
+	entries []string
+	sent    bool
+}
+
+// Timing adds an entry to the Server-Timing header, shown by browser
+// developer tools next to the request's network timings. name must be a
+// token such as "db" or "render"; desc is an optional description. Calls
+// after the headers have been sent are ignored, so time work done before
+// writing the response. Entries show how the app works internally; leave
+// them out of public responses if that matters:
+//
+//	start := time.Now()
+//	posts, err := db.RecentPosts()
+//	ctx.Timing("db", time.Since(start), "Recent posts")
+func (c *Context) Timing(name string, dur time.Duration, desc string) {
+	entry := name + ";dur=" + strconv.FormatFloat(float64(dur.Microseconds())/1000, 'f', -1, 64)
+	if desc != "" {
+		entry += ";desc=" + strconv.Quote(desc)
+	}
+	t := &c.response.timings
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.sent {
+		t.entries = append(t.entries, entry)
+	}
+}
+
+// StartTiming starts timing name and returns the function recording it
+// with ctx.Timing:
+//
+//	defer ctx.StartTiming("db", "")()
+func (c *Context) StartTiming(name, desc string) func() {
+	start := time.Now()
+	return func() {
+		c.Timing(name, time.Since(start), desc)
+	}
+}
+
+// send adds the collected entries to header, once
+func (t *serverTimings) send(header http.Header) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.sent && len(t.entries) > 0 {
+		header.Add("Server-Timing", strings.Join(t.entries, ", "))
+	}
+	t.sent = true
+}
@@ -0,0 +1,97 @@
+package nojs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// DefaultTLSConfig returns a TLS configuration with modern defaults:
+// TLS 1.2 or newer, with fast and safe curves
+func DefaultTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:       tls.VersionTLS12,
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+	}
+}
+
+// newHTTPServer returns an http.Server for addr using the server config
+func (s *Server) newHTTPServer(addr string) *http.Server {
+	return &http.Server{
+		Addr:           addr,
+		Handler:        s,
+		ReadTimeout:    s.config.ReadTimeout,
+		WriteTimeout:   s.config.WriteTimeout,
+		IdleTimeout:    s.config.IdleTimeout,
+		MaxHeaderBytes: s.config.MaxHeaderBytes,
+	}
+}
+
+// StartTLS starts the HTTPS server with the given certificate and key files
+func (s *Server) StartTLS(addr, certFile, keyFile string) error {
+	srv := s.newHTTPServer(addr)
+	srv.TLSConfig = DefaultTLSConfig()
+
+	if err := s.starting(); err != nil {
+		return err
+	}
+
+	fmt.Printf("NoJS server starting on %s (TLS)\n", addr)
+	return srv.ListenAndServeTLS(certFile, keyFile)
+}
+
+// StartAutoTLS serves HTTPS on :443 with certificates obtained from
+// Let's Encrypt for domains, and redirects HTTP on :80 to HTTPS while
+// answering ACME challenges there
+func (s *Server) StartAutoTLS(domains ...string) error {
+	cacheDir := s.config.AutocertCacheDir
+	if cacheDir == "" {
+		cacheDir = "certs"
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      autocert.DirCache(cacheDir),
+		Email:      s.config.AutocertEmail,
+	}
+
+	srv := s.newHTTPServer(":443")
+	srv.TLSConfig = DefaultTLSConfig()
+	srv.TLSConfig.GetCertificate = manager.GetCertificate
+	srv.TLSConfig.NextProtos = append([]string{"h2", "http/1.1"}, srv.TLSConfig.NextProtos...)
+
+	redirect := &http.Server{
+		Addr:        ":80",
+		Handler:     manager.HTTPHandler(http.HandlerFunc(redirectHTTPS)),
+		ReadTimeout: s.config.ReadTimeout,
+		IdleTimeout: s.config.IdleTimeout,
+	}
+	if err := s.starting(); err != nil {
+		return err
+	}
+
+	errs := make(chan error, 2)
+	go func() { errs <- redirect.ListenAndServe() }()
+	go func() {
+		fmt.Printf("NoJS server starting on :443 (TLS) for %v\n", domains)
+		errs <- srv.ListenAndServeTLS("", "")
+	}()
+
+	err := <-errs
+	redirect.Close()
+	srv.Close()
+	return err
+}
+
+// redirectHTTPS permanently redirects a plain HTTP request to HTTPS
+func redirectHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+}
@@ -0,0 +1,188 @@
+package nojs
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// TOCConfig configures TOC
+type TOCConfig struct {
+	Title string
+	// MaxLevel is the deepest heading level listed (default 3, i.e. h2–h3)
+	MaxLevel int
+	// Numbered prefixes entries with section numbers such as "2.1",
+	// matching NumberedHeadings
+	Numbered bool
+}
+
+// tocHeading is a heading found in rendered content
+type tocHeading struct {
+	level  int
+	id     string
+	text   string
+	number string
+}
+
+var (
+	headingPattern = regexp.MustCompile(`(?s)<h([2-6])\b([^>]*)>(.*?)</h[2-6]>`)
+	headingID      = regexp.MustCompile(`\bid="([^"]*)"`)
+	headingAnchor  = regexp.MustCompile(`(?s)<a class="heading-anchor"[^>]*>.*?</a>`)
+	htmlTag        = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Slug turns text into an id usable as a URL fragment, e.g.
+// "Getting started" becomes "getting-started"
+func Slug(text string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// Heading creates an h2–h6 heading with an id derived from its text and a
+// "#" link to itself, so readers can copy a link to the section
+func Heading(level int, text string, attrs ...g.Node) g.Node {
+	if level < 2 || level > 6 {
+		level = 2
+	}
+	id := Slug(text)
+	return g.El("h"+strconv.Itoa(level), h.ID(id), g.Group(attrs),
+		g.Text(text),
+		h.A(h.Class("heading-anchor"), h.Href("#"+id), h.TitleAttr("Link to this section"),
+			h.Aria("label", "Link to "+text), g.Text("#")),
+	)
+}
+
+// scanHeadings finds the h2–h6 headings with an id in rendered HTML and
+// numbers them relative to the highest level present
+func scanHeadings(content string) []tocHeading {
+	var headings []tocHeading
+	top := 6
+	for _, m := range headingPattern.FindAllStringSubmatch(content, -1) {
+		id := headingID.FindStringSubmatch(m[2])
+		if id == nil {
+			continue
+		}
+		level, _ := strconv.Atoi(m[1])
+		text := headingAnchor.ReplaceAllString(m[3], "")
+		text = strings.TrimSpace(html.UnescapeString(htmlTag.ReplaceAllString(text, "")))
+		headings = append(headings, tocHeading{level: level, id: html.UnescapeString(id[1]), text: text})
+		if level < top {
+			top = level
+		}
+	}
+
+	counters := make([]int, 6)
+	for i := range headings {
+		depth := headings[i].level - top
+		counters[depth]++
+		for j := depth + 1; j < len(counters); j++ {
+			counters[j] = 0
+		}
+		parts := make([]string, depth+1)
+		for j := range parts {
+			parts[j] = strconv.Itoa(counters[j])
+		}
+		headings[i].number = strings.Join(parts, ".")
+	}
+	return headings
+}
+
+// TOC renders content and builds a nested table of contents linking to
+// its headings. Only headings with an id are listed; Heading adds one.
+func TOC(content g.Node, config ...TOCConfig) g.Node {
+	cfg := TOCConfig{Title: "Contents", MaxLevel: 3}
+	if len(config) > 0 {
+		cfg = config[0]
+		if cfg.MaxLevel == 0 {
+			cfg.MaxLevel = 3
+		}
+	}
+
+	var headings []tocHeading
+	for _, heading := range scanHeadings(renderString(content)) {
+		if heading.level <= cfg.MaxLevel {
+			headings = append(headings, heading)
+		}
+	}
+	if len(headings) == 0 {
+		return nil
+	}
+
+	list, _ := tocList(headings, headings[0].level, cfg.Numbered)
+	return h.Nav(h.Class("toc"), h.Aria("label", cfg.Title),
+		g.If(cfg.Title != "", h.H2(h.Class("toc-title"), g.Text(cfg.Title))),
+		list,
+	)
+}
+
+// tocList builds the list for headings at level and deeper, returning it
+// and the number of headings consumed
+func tocList(headings []tocHeading, level int, numbered bool) (g.Node, int) {
+	items := []g.Node{}
+	i := 0
+	for i < len(headings) && headings[i].level >= level {
+		heading := headings[i]
+		i++
+
+		var sub g.Node
+		if i < len(headings) && headings[i].level > heading.level {
+			var n int
+			sub, n = tocList(headings[i:], headings[i].level, numbered)
+			i += n
+		}
+
+		label := heading.text
+		if numbered {
+			label = heading.number + " " + label
+		}
+		items = append(items, h.Li(h.A(h.Href("#"+heading.id), g.Text(label)), sub))
+	}
+	return h.Ol(g.Group(items)), i
+}
+
+// NumberedHeadings renders content with section numbers such as "2.1"
+// inserted at the start of every heading that has an id
+func NumberedHeadings(content g.Node) g.Node {
+	rendered := renderString(content)
+	headings := scanHeadings(rendered)
+
+	i := 0
+	return g.Raw(headingPattern.ReplaceAllStringFunc(rendered, func(match string) string {
+		m := headingPattern.FindStringSubmatch(match)
+		if i >= len(headings) || headingID.FindString(m[2]) == "" {
+			return match
+		}
+		number := headings[i].number
+		i++
+		return fmt.Sprintf(`<h%s%s><span class="heading-number">%s</span> %s</h%s>`, m[1], m[2], number, m[3], m[1])
+	}))
+}
+
+// BackToTop renders a link to the top of the page. Browsers scroll "#top"
+// to the start of the document without a matching element; pass target to
+// link to another id instead, e.g. the TOC.
+func BackToTop(target ...string) g.Node {
+	id := "top"
+	if len(target) > 0 {
+		id = target[0]
+	}
+	return h.A(h.Class("back-to-top"), h.Href("#"+id), g.Text("Back to top ↑"))
+}
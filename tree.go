@@ -0,0 +1,152 @@
+package nojs
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// TreeNode is one node of a Tree
+type TreeNode struct {
+	ID       string
+	Label    string
+	Href     string // Optional link for the label
+	Children []TreeNode
+	// Lazy marks a branch whose children are only fetched through
+	// TreeConfig.Load once the node is expanded
+	Lazy bool
+}
+
+// TreeConfig configures a Tree
+type TreeConfig struct {
+	// Param is the query parameter listing expanded node IDs, defaults
+	// to "open". Ignored when Details is set.
+	Param string
+	// URL is the current request URL, used to build expand/collapse links
+	URL *url.URL
+	// Details renders branches as <details> elements whose open state is
+	// kept by the browser instead of in the URL
+	Details bool
+	// Load fetches the children of an expanded lazy node
+	Load func(id string) []TreeNode
+	// FragmentURL, in Details mode, returns a URL rendering the children
+	// of a lazy node; it is embedded as a lazy iframe so deep branches
+	// are only fetched once their <details> is opened
+	FragmentURL func(id string) string
+}
+
+// Tree creates a recursive tree view whose expanded nodes are kept in the
+// URL (or in <details> elements), so expand/collapse works without JS
+func Tree(nodes []TreeNode, config TreeConfig) g.Node {
+	if config.Param == "" {
+		config.Param = "open"
+	}
+
+	open := map[string]bool{}
+	if config.URL != nil {
+		for _, id := range TreeOpen(config.URL, config.Param) {
+			open[id] = true
+		}
+	}
+
+	return h.Ul(append([]g.Node{h.Class("tree"), g.Attr("role", "tree")}, treeItems(nodes, config, open)...)...)
+}
+
+// TreeOpen returns the expanded node IDs stored in a URL
+func TreeOpen(u *url.URL, param string) []string {
+	value := u.Query().Get(param)
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func treeItems(nodes []TreeNode, config TreeConfig, open map[string]bool) []g.Node {
+	var items []g.Node
+	for _, node := range nodes {
+		items = append(items, treeItem(node, config, open))
+	}
+	return items
+}
+
+func treeItem(node TreeNode, config TreeConfig, open map[string]bool) g.Node {
+	var label g.Node = g.Text(node.Label)
+	if node.Href != "" {
+		label = h.A(h.Href(node.Href), g.Text(node.Label))
+	}
+
+	isBranch := len(node.Children) > 0 || node.Lazy
+	if !isBranch {
+		return h.Li(h.ID("tree-"+node.ID), h.Class("tree-leaf"), g.Attr("role", "treeitem"), label)
+	}
+
+	expanded := open[node.ID]
+	children := node.Children
+	lazy := node.Lazy && len(children) == 0
+
+	var childList g.Node
+	if lazy && config.Details && config.FragmentURL != nil {
+		childList = h.IFrame(h.Src(config.FragmentURL(node.ID)), h.Loading("lazy"), h.Class("tree-fragment"), h.TitleAttr(node.Label))
+	} else {
+		if lazy && config.Load != nil && (expanded || config.Details) {
+			children = config.Load(node.ID)
+		}
+		childList = h.Ul(append([]g.Node{h.Class("tree-children"), g.Attr("role", "group")}, treeItems(children, config, open)...)...)
+	}
+
+	if config.Details {
+		return h.Li(h.ID("tree-"+node.ID), h.Class("tree-branch"), g.Attr("role", "treeitem"),
+			h.Details(g.If(expanded, g.Attr("open")),
+				h.Summary(label),
+				childList,
+			),
+		)
+	}
+
+	toggle := "▸"
+	if expanded {
+		toggle = "▾"
+	}
+
+	return h.Li(h.ID("tree-"+node.ID), h.Class("tree-branch"), g.Attr("role", "treeitem"),
+		h.Aria("expanded", map[bool]string{true: "true", false: "false"}[expanded]),
+		h.A(h.Href(treeToggleURL(config.URL, config.Param, node.ID, open)+"#tree-"+node.ID), h.Class("tree-toggle"), g.Text(toggle)),
+		label,
+		g.If(expanded, childList),
+	)
+}
+
+// treeToggleURL returns the current URL with id added to or removed from
+// the expanded set
+func treeToggleURL(u *url.URL, param, id string, open map[string]bool) string {
+	var ids []string
+	for openID := range open {
+		if openID != id {
+			ids = append(ids, openID)
+		}
+	}
+	if !open[id] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	path := ""
+	query := url.Values{}
+	if u != nil {
+		path = u.Path
+		query = u.Query()
+	}
+	if len(ids) == 0 {
+		query.Del(param)
+	} else {
+		query.Set(param, strings.Join(ids, ","))
+	}
+
+	if encoded := query.Encode(); encoded != "" {
+		return path + "?" + encoded
+	}
+	return path
+}
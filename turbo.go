@@ -0,0 +1,54 @@
+package nojs
+
+import (
+	"bytes"
+	"html"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// TurboFrame renders a <turbo-frame> element. Without Turbo it is an
+// unknown inline element and links inside it navigate normally.
+func TurboFrame(id string, children ...g.Node) g.Node {
+	return g.El("turbo-frame", h.ID(id), g.Group(children))
+}
+
+// TurboFrameID returns the frame requested by a Hotwire Turbo client, or
+// an empty string for normal navigation
+func (c *Context) TurboFrameID() string {
+	return c.Request.Header.Get("Turbo-Frame")
+}
+
+// extractTurboFrame returns the frame rendered by TurboFrame with the
+// given id, including nested frames, or nil
+func extractTurboFrame(page []byte, id string) []byte {
+	const open, close = "<turbo-frame", "</turbo-frame>"
+	start := bytes.Index(page, []byte(`<turbo-frame id="`+html.EscapeString(id)+`"`))
+	if start < 0 {
+		return nil
+	}
+
+	depth := 0
+	for i := start; i < len(page); {
+		rest := page[i:]
+		switch {
+		case bytes.HasPrefix(rest, []byte(open)):
+			depth++
+			i += len(open)
+		case bytes.HasPrefix(rest, []byte(close)):
+			depth--
+			i += len(close)
+			if depth == 0 {
+				return page[start:i]
+			}
+		default:
+			next := bytes.IndexByte(rest[1:], '<')
+			if next < 0 {
+				return nil
+			}
+			i += next + 1
+		}
+	}
+	return nil
+}
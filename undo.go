@@ -0,0 +1,124 @@
+package nojs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// ErrInvalidUndoToken is returned for tampered or expired undo tokens
+var ErrInvalidUndoToken = errors.New("invalid or expired undo token")
+
+// SoftDelete can be embedded in records that are hidden rather than
+// removed when deleted
+type SoftDelete struct {
+	DeletedAt time.Time
+}
+
+// Delete marks the record as deleted
+func (s *SoftDelete) Delete() { s.DeletedAt = time.Now() }
+
+// Restore clears the deletion mark
+func (s *SoftDelete) Restore() { s.DeletedAt = time.Time{} }
+
+// Deleted reports whether the record is marked as deleted
+func (s SoftDelete) Deleted() bool { return !s.DeletedAt.IsZero() }
+
+// Undeleted returns the items not marked as deleted
+func Undeleted[T interface{ Deleted() bool }](items []T) []T {
+	return Filter(items, func(item T) bool { return !item.Deleted() })
+}
+
+// Undo issues and verifies time-limited tokens that allow restoring a
+// soft-deleted record
+type Undo struct {
+	secret []byte
+	// TTL is how long an undo remains possible
+	TTL time.Duration
+	// Action is the URL the restore form posts to
+	Action string
+}
+
+// NewUndo creates an Undo signing tokens with secret
+func NewUndo(secret string, action string) *Undo {
+	return &Undo{
+		secret: []byte(secret),
+		TTL:    30 * time.Second,
+		Action: action,
+	}
+}
+
+// Token returns a signed token identifying the record of the given kind
+func (u *Undo) Token(kind, id string) string {
+	payload := strings.Join([]string{kind, id, strconv.FormatInt(time.Now().Add(u.TTL).Unix(), 10)}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + u.sign(payload)
+}
+
+// Verify checks a token and returns the kind and id it was issued for
+func (u *Undo) Verify(token string) (kind, id string, err error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", ErrInvalidUndoToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", ErrInvalidUndoToken
+	}
+
+	payload := string(raw)
+	if !hmac.Equal([]byte(sig), []byte(u.sign(payload))) {
+		return "", "", ErrInvalidUndoToken
+	}
+
+	parts := strings.SplitN(payload, "|", 3)
+	if len(parts) != 3 {
+		return "", "", ErrInvalidUndoToken
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", "", ErrInvalidUndoToken
+	}
+
+	return parts[0], parts[1], nil
+}
+
+func (u *Undo) sign(payload string) string {
+	mac := hmac.New(sha256.New, u.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SetUndo stores an undo flash with message and a restore token for the
+// deleted record; render it on the next page with UndoNotice
+func (c *Context) SetUndo(u *Undo, message, kind, id string) {
+	c.SetFlash("undo", u.Token(kind, id)+"\n"+message)
+}
+
+// UndoNotice renders the pending undo flash, if any, as an alert with a
+// restore form. The restore handler reads the token with ctx.UndoToken.
+func UndoNotice(ctx *Context, u *Undo) g.Node {
+	token, message, ok := strings.Cut(ctx.GetFlash("undo"), "\n")
+	if !ok {
+		return nil
+	}
+
+	return h.Div(h.Class("alert alert-undo"), h.Role("status"),
+		g.Text(message+" "),
+		Form(FormConfig{Action: u.Action, Class: "inline-form"},
+			h.Input(h.Type("hidden"), h.Name("undo_token"), h.Value(token)),
+			SubmitButton("Undo", h.Class("button-small")),
+		),
+	)
+}
+
+// UndoToken verifies the token submitted by an UndoNotice form
+func (c *Context) UndoToken(u *Undo) (kind, id string, err error) {
+	return u.Verify(c.Form("undo_token"))
+}
@@ -0,0 +1,249 @@
+package nojs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// multipartMemory is how much of a multipart body is kept in memory;
+// larger files are spooled to temporary files
+const multipartMemory = 32 << 20
+
+// FormFile returns the header of the file posted in field, parsing the
+// multipart form first. It fails with 400 when no file was chosen and
+// 413 when the body exceeded a limit set with http.MaxBytesReader.
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	files, err := c.FormFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+// FormFiles returns all files posted in field, e.g. from an input with
+// the multiple attribute
+func (c *Context) FormFiles(name string) ([]*multipart.FileHeader, error) {
+	if c.Request.MultipartForm == nil {
+		if err := c.Request.ParseMultipartForm(multipartMemory); err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				return nil, WrapHTTPError(http.StatusRequestEntityTooLarge, "File too large", err)
+			}
+			return nil, WrapHTTPError(http.StatusBadRequest, "Invalid upload", err)
+		}
+	}
+	var files []*multipart.FileHeader
+	for _, fh := range c.Request.MultipartForm.File[name] {
+		if fh.Filename != "" || fh.Size > 0 {
+			files = append(files, fh)
+		}
+	}
+	if len(files) == 0 {
+		return nil, NewHTTPError(http.StatusBadRequest, "No file uploaded")
+	}
+	return files, nil
+}
+
+// UploadConfig configures Uploads
+type UploadConfig struct {
+	// MaxSize is the largest accepted file in bytes
+	MaxSize int64
+	// MaxFiles limits how many files SaveAll accepts from one field
+	MaxFiles int
+	// AllowedTypes lists accepted content types as sniffed from the
+	// file's first bytes; "image/*" accepts any image. Empty allows all
+	// but HTML and XML documents, which would run scripts when served
+	// from the site; list "text/html" or "text/xml" to accept them.
+	AllowedTypes []string
+	// AllowedExtensions lists accepted file name extensions with their
+	// dot, e.g. ".png". Empty allows all.
+	AllowedExtensions []string
+	// Prefix is prepended to generated storage keys, e.g. "avatars/"
+	Prefix string
+}
+
+// DefaultUploadConfig accepts common images and PDFs up to 10 MB
+func DefaultUploadConfig() UploadConfig {
+	return UploadConfig{
+		MaxSize:           10 << 20,
+		MaxFiles:          10,
+		AllowedTypes:      []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf"},
+		AllowedExtensions: []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".pdf"},
+	}
+}
+
+// Uploads validates posted files and saves them to a Storage under
+// unique random names, so user-supplied file names never reach the
+// file system and content types come from the bytes, not the browser
+type Uploads struct {
+	Storage Storage
+	config  UploadConfig
+}
+
+// Upload describes a stored file
+type Upload struct {
+	Key         string
+	Name        string // Original file name, for display only
+	ContentType string
+	Size        int64
+	URL         string
+}
+
+// NewUploads creates an upload handler saving to storage
+func NewUploads(storage Storage, config ...UploadConfig) *Uploads {
+	cfg := DefaultUploadConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	return &Uploads{Storage: storage, config: cfg}
+}
+
+// Save validates and stores the file posted in field
+func (u *Uploads) Save(ctx *Context, field string) (*Upload, error) {
+	u.limitBody(ctx, 1)
+	fh, err := ctx.FormFile(field)
+	if err != nil {
+		return nil, err
+	}
+	return u.store(ctx, fh)
+}
+
+// SaveAll validates and stores every file posted in field. Nothing is
+// kept when any file is rejected.
+func (u *Uploads) SaveAll(ctx *Context, field string) ([]*Upload, error) {
+	u.limitBody(ctx, u.config.MaxFiles)
+	files, err := ctx.FormFiles(field)
+	if err != nil {
+		return nil, err
+	}
+	if u.config.MaxFiles > 0 && len(files) > u.config.MaxFiles {
+		return nil, NewHTTPError(http.StatusRequestEntityTooLarge, "Too many files")
+	}
+
+	var uploads []*Upload
+	for _, fh := range files {
+		upload, err := u.store(ctx, fh)
+		if err != nil {
+			for _, saved := range uploads {
+				u.Storage.Delete(ctx.Request.Context(), saved.Key)
+			}
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, nil
+}
+
+// limitBody caps the request body before the multipart form is parsed
+func (u *Uploads) limitBody(ctx *Context, files int) {
+	if ctx.Request.MultipartForm == nil && u.config.MaxSize > 0 {
+		if files < 1 {
+			files = 1
+		}
+		ctx.Request.Body = http.MaxBytesReader(ctx.ResponseWriter, ctx.Request.Body, u.config.MaxSize*int64(files)+1<<20)
+	}
+}
+
+// store checks one file and writes it to storage
+func (u *Uploads) store(ctx *Context, fh *multipart.FileHeader) (*Upload, error) {
+	if u.config.MaxSize > 0 && fh.Size > u.config.MaxSize {
+		return nil, NewHTTPError(http.StatusRequestEntityTooLarge, "File too large")
+	}
+
+	name := filepath.Base(strings.ReplaceAll(fh.Filename, `\`, "/"))
+	ext := strings.ToLower(path.Ext(name))
+	if len(u.config.AllowedExtensions) > 0 && !containsFold(u.config.AllowedExtensions, ext) {
+		return nil, NewHTTPError(http.StatusUnsupportedMediaType, "File type not allowed")
+	}
+
+	file, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if !u.allowedType(contentType) {
+		return nil, NewHTTPError(http.StatusUnsupportedMediaType, "File type not allowed")
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	upload := &Upload{
+		Key:         u.config.Prefix + hex.EncodeToString(b) + keyExtension(ext, contentType),
+		Name:        name,
+		ContentType: contentType,
+		Size:        fh.Size,
+	}
+	body := io.MultiReader(bytes.NewReader(head), file)
+	if err := u.Storage.Save(ctx.Request.Context(), upload.Key, body, fh.Size, contentType); err != nil {
+		return nil, err
+	}
+	upload.URL = u.Storage.URL(upload.Key)
+	return upload, nil
+}
+
+// documentTypes are sniffed types browsers render as documents able to
+// run scripts, accepted only when AllowedTypes lists them
+var documentTypes = []string{"text/html", "text/xml"}
+
+// keyExtension returns the extension of the storage key for a file named
+// with ext and sniffed as contentType. The client's extension is kept only
+// when it is served as the sniffed type, so a file cannot be stored under
+// an extension that makes Static or DiskStorage serve it as something
+// else. Otherwise the type's own extension is used, or none, in which
+// case file servers sniff the same type again.
+func keyExtension(ext, contentType string) string {
+	sniffed, _, _ := strings.Cut(contentType, ";")
+	if served, _, _ := strings.Cut(mime.TypeByExtension(ext), ";"); served == sniffed {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(sniffed); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// allowedType matches a sniffed content type against AllowedTypes
+func (u *Uploads) allowedType(contentType string) bool {
+	contentType, _, _ = strings.Cut(contentType, ";")
+	if Contains(documentTypes, contentType) {
+		return Contains(u.config.AllowedTypes, contentType)
+	}
+	if len(u.config.AllowedTypes) == 0 {
+		return true
+	}
+	for _, allowed := range u.config.AllowedTypes {
+		if allowed == contentType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,291 @@
+package nojs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+// Validation errors returned by the built-in validators
+var (
+	ErrInvalidEmail = errors.New("is not a valid email address")
+	ErrInvalidPhone = errors.New("is not a valid phone number")
+)
+
+// ValidatorFunc checks a single form value
+type ValidatorFunc func(value string) error
+
+// Validators holds named validators usable in `validate` struct tags,
+// e.g. `validate:"email"`. Applications may register their own.
+var Validators = map[string]ValidatorFunc{
+	"email": func(v string) error {
+		_, err := NormalizeEmail(v)
+		return err
+	},
+	"phone": func(v string) error {
+		_, err := NormalizePhone(v, "")
+		return err
+	},
+	"password": func(v string) error {
+		return PasswordPolicy{}.Validate(context.Background(), v)
+	},
+}
+
+// NormalizeEmail validates an email address and returns it trimmed with
+// a lower-cased domain. Display names ("Jane <jane@example.com>") are
+// rejected, as are addresses without a dot in the domain.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" || len(email) > 254 {
+		return "", ErrInvalidEmail
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return "", ErrInvalidEmail
+	}
+
+	at := strings.LastIndex(email, "@")
+	local, domain := email[:at], strings.ToLower(email[at+1:])
+	if len(local) > 64 || !strings.Contains(domain, ".") ||
+		strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
+		return "", ErrInvalidEmail
+	}
+
+	return local + "@" + domain, nil
+}
+
+// countryCallingCodes maps ISO country codes to calling codes and whether
+// national numbers start with a trunk prefix 0 to drop
+var countryCallingCodes = map[string]struct {
+	code  string
+	trunk bool
+}{
+	"US": {"1", false}, "CA": {"1", false}, "GB": {"44", true}, "IE": {"353", true},
+	"DE": {"49", true}, "FR": {"33", true}, "ES": {"34", false}, "IT": {"39", false},
+	"NL": {"31", true}, "BE": {"32", true}, "PT": {"351", false}, "CH": {"41", true},
+	"AT": {"43", true}, "SE": {"46", true}, "NO": {"47", false}, "DK": {"45", false},
+	"FI": {"358", true}, "PL": {"48", false}, "AU": {"61", true}, "NZ": {"64", true},
+	"JP": {"81", true}, "IN": {"91", true}, "BR": {"55", true}, "MX": {"52", false},
+	"AR": {"54", true}, "CN": {"86", true}, "HK": {"852", false}, "SG": {"65", false},
+}
+
+// NormalizePhone returns a phone number in E.164 format (+14155552671).
+// Numbers without an international prefix are interpreted as national
+// numbers of country, an ISO 3166-1 alpha-2 code.
+func NormalizePhone(phone, country string) (string, error) {
+	phone = strings.TrimSpace(phone)
+	if i := strings.IndexAny(strings.ToLower(phone), "ex;#"); i > 0 {
+		phone = phone[:i] // Drop extensions
+	}
+
+	international := strings.HasPrefix(phone, "+")
+	var digits strings.Builder
+	for _, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' || r == ' ' || r == '-' || r == '.' || r == '(' || r == ')' || r == '/':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+
+	number := digits.String()
+	if !international && strings.HasPrefix(number, "00") {
+		international = true
+		number = number[2:]
+	}
+
+	if !international {
+		cc, ok := countryCallingCodes[strings.ToUpper(country)]
+		if !ok {
+			return "", ErrInvalidPhone
+		}
+		if cc.trunk {
+			number = strings.TrimPrefix(number, "0")
+		}
+		if cc.code == "1" {
+			number = strings.TrimPrefix(number, "1")
+		}
+		number = cc.code + number
+	}
+
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	return "+" + number, nil
+}
+
+// FieldError renders a validation message below a form field
+func FieldError(message string) g.Node {
+	if message == "" {
+		return nil
+	}
+	return h.P(h.Class("field-error"), h.Role("alert"), g.Text(message))
+}
+
+// Add records a message for field unless it already has one
+func (e FieldErrors) Add(field, message string) {
+	if _, exists := e[field]; !exists {
+		e[field] = message
+	}
+}
+
+// Err returns e as an error, or nil when there are no errors
+func (e FieldErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate checks the `validate` tags of the struct v points to and
+// returns the failures keyed by form field name (as used by Bind).
+// Rules are comma-separated: required, min=N and max=N (length for
+// strings, count for slices, value for numbers), oneof=a|b|c, and any
+// name registered in Validators such as email or phone.
+//
+//	type Signup struct {
+//		Email string `form:"email" validate:"required,email"`
+//		Name  string `form:"name" validate:"required,max=50"`
+//	}
+func Validate(v interface{}) FieldErrors {
+	errs := FieldErrors{}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct {
+		validateStruct(rv, "", errs)
+	}
+	return errs
+}
+
+func validateStruct(v reflect.Value, prefix string, errs FieldErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get("form")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		name = prefix + name
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && !isScalar(fv.Type()) {
+			validateStruct(fv, name+".", errs)
+			continue
+		}
+		if message := validateField(fv, field.Tag.Get("validate")); message != "" {
+			errs.Add(name, message)
+		}
+	}
+}
+
+// validateField applies the rules of a validate tag, returning the first
+// failure message
+func validateField(v reflect.Value, tag string) string {
+	if tag == "" {
+		return ""
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if strings.Contains(","+tag+",", ",required,") {
+				return "is required"
+			}
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	empty := v.IsZero()
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "":
+		case "required":
+			if empty || (v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "") {
+				return "is required"
+			}
+		case "min", "max":
+			// An empty string is left to required; a zero number is
+			// still a value to check
+			if v.Kind() == reflect.String && v.Len() == 0 {
+				continue
+			}
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			n, unit := measure(v)
+			if name == "min" && n < limit {
+				return fmt.Sprintf("must be at least %s%s", arg, unit)
+			}
+			if name == "max" && n > limit {
+				return fmt.Sprintf("must be at most %s%s", arg, unit)
+			}
+		case "oneof":
+			if !empty && !Contains(strings.Split(arg, "|"), fmt.Sprint(v.Interface())) {
+				return "is not an allowed value"
+			}
+		default:
+			validator, ok := Validators[name]
+			if !ok || empty || v.Kind() != reflect.String {
+				continue
+			}
+			if err := validator(v.String()); err != nil {
+				return err.Error()
+			}
+		}
+	}
+	return ""
+}
+
+// measure returns the size min and max compare against, with its unit
+func measure(v reflect.Value) (float64, string) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	}
+	return 0, ""
+}
+
+// BindValid binds the request into dst like Bind and then validates it,
+// returning all conversion and validation failures as FieldErrors
+func (c *Context) BindValid(dst interface{}) error {
+	errs := FieldErrors{}
+	if err := c.Bind(dst); err != nil {
+		bindErrs, ok := err.(FieldErrors)
+		if !ok {
+			return err
+		}
+		errs = bindErrs
+	}
+	for field, message := range Validate(dst) {
+		errs.Add(field, message)
+	}
+	return errs.Err()
+}